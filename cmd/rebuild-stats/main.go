@@ -0,0 +1,40 @@
+// Command rebuild-stats recomputes the materialized user stats from forecasts and score records.
+// Run it after restoring a backup or fixing scores by hand; pass -user to rebuild a single user.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/domolitom/reThink/internal/database"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	userID := flag.Uint("user", 0, "rebuild only this user's stats")
+	flag.Parse()
+
+	// Load environment variables from .env file if it exists
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to set up database: %v", err)
+	}
+
+	if *userID != 0 {
+		if err := services.RebuildUserStatsFor(database.DB, *userID); err != nil {
+			log.Fatalf("Failed to rebuild stats for user %d: %v", *userID, err)
+		}
+		log.Printf("Rebuilt stats for user %d", *userID)
+		return
+	}
+
+	n, err := services.RebuildUserStats(database.DB)
+	if err != nil {
+		log.Fatalf("Failed to rebuild user stats after %d users: %v", n, err)
+	}
+	log.Printf("Rebuilt stats for %d users", n)
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
+	"github.com/domolitom/reThink/internal/api/handlers"
+	"github.com/domolitom/reThink/internal/api/middleware"
 	"github.com/domolitom/reThink/internal/api/routes"
 	"github.com/domolitom/reThink/internal/database"
+	"github.com/domolitom/reThink/internal/mailer"
+	"github.com/domolitom/reThink/internal/pubsub"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/internal/store"
+	"github.com/domolitom/reThink/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -24,13 +33,80 @@ func main() {
 	}
 
 	// Connect to the database
-	database.Connect()
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to set up database: %v", err)
+	}
+
+	// Score markets resolved before per-forecast scores were recorded
+	backfilled, err := services.BackfillScoreRecords(database.DB)
+	if err != nil {
+		log.Printf("Failed to backfill score records: %v", err)
+	} else if backfilled > 0 {
+		log.Printf("Backfilled %d score records", backfilled)
+	}
+
+	// Build user stats on first deploy, or when backfilled scores have made them stale
+	rebuild := services.EnsureUserStats
+	if backfilled > 0 {
+		rebuild = services.RebuildUserStats
+	}
+	if n, err := rebuild(database.DB); err != nil {
+		log.Printf("Failed to build user stats: %v", err)
+	} else if n > 0 {
+		log.Printf("Built stats for %d users", n)
+	}
+
+	// Share real-time events with the other server instances
+	switch driver := utils.GetEnvString("PUBSUB_DRIVER", "memory"); driver {
+	case "memory":
+	case "postgres":
+		dbDriver, dsn := database.ParseDSN(database.DSN())
+		if dbDriver != database.Postgres {
+			log.Fatalf("PUBSUB_DRIVER=postgres needs a Postgres database, not %s", dbDriver)
+		}
+		bus := pubsub.NewPostgres(database.DB, dsn)
+		defer bus.Close()
+		services.UseBus(bus)
+	default:
+		log.Fatalf("Invalid PUBSUB_DRIVER %q: must be memory or postgres", driver)
+	}
+
+	// Record periodic market probability snapshots in the background
+	snapshotInterval, err := time.ParseDuration(utils.GetEnvString("SNAPSHOT_INTERVAL", "1h"))
+	if err != nil {
+		log.Fatalf("Invalid SNAPSHOT_INTERVAL: %v", err)
+	}
+	go services.RunSnapshotJob(context.Background(), database.DB, snapshotInterval)
+
+	// Email notification digests to users who are due one
+	digestInterval, err := time.ParseDuration(utils.GetEnvString("DIGEST_CHECK_INTERVAL", "1h"))
+	if err != nil {
+		log.Fatalf("Invalid DIGEST_CHECK_INTERVAL: %v", err)
+	}
+	go services.RunDigestJob(context.Background(), database.DB, mailer.New(), digestInterval)
+
+	// Close markets past their close date and send ending-soon and resolution reminders
+	lifecycleInterval, err := time.ParseDuration(utils.GetEnvString("MARKET_LIFECYCLE_INTERVAL", "1m"))
+	if err != nil {
+		log.Fatalf("Invalid MARKET_LIFECYCLE_INTERVAL: %v", err)
+	}
+	go services.RunMarketLifecycleJob(context.Background(), database.DB, lifecycleInterval)
+
+	// Send queued webhook deliveries and retries
+	webhookInterval, err := time.ParseDuration(utils.GetEnvString("WEBHOOK_POLL_INTERVAL", "5s"))
+	if err != nil {
+		log.Fatalf("Invalid WEBHOOK_POLL_INTERVAL: %v", err)
+	}
+	// Only allow deliveries to loopback and private addresses for local development
+	services.WebhookAllowPrivateNetworks = utils.GetEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+	go services.RunWebhookWorker(context.Background(), database.DB, webhookInterval)
 
-	// Create a new Gin router with default middleware
-	r := gin.Default()
+	// Create a new Gin router; the request logger keeps tokens in query strings out of the logs
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), gin.Recovery())
 
 	// Setup routes
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, handlers.NewHandler(store.NewGormStore(database.DB)))
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
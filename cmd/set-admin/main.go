@@ -0,0 +1,47 @@
+// Command set-admin grants admin rights to a registered user, or revokes them with -revoke.
+// Admin rights can't be granted through the API, so use it to set up the first admins.
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/domolitom/reThink/internal/database"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	email := flag.String("email", "", "email address of the user")
+	revoke := flag.Bool("revoke", false, "revoke admin rights instead of granting them")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("Usage: set-admin -email user@example.com [-revoke]")
+	}
+
+	// Load environment variables from .env file if it exists
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to set up database: %v", err)
+	}
+
+	user, err := services.SetAdmin(database.DB, *email, !*revoke)
+	if errors.Is(err, utils.ErrNotFound) {
+		log.Fatalf("No user is registered with email %s", *email)
+	}
+	if err != nil {
+		log.Fatalf("Failed to update user %s: %v", *email, err)
+	}
+
+	if *revoke {
+		log.Printf("Revoked admin rights from %s (user %d)", user.Email, user.ID)
+	} else {
+		log.Printf("Granted admin rights to %s (user %d)", user.Email, user.ID)
+	}
+}
@@ -1,51 +1,54 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/domolitom/reThink/internal/database"
 	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/store"
+	"github.com/domolitom/reThink/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
-	"golang.org/x/crypto/bcrypt"
 )
 
-type RegisterInput struct {
-	Username string `json:"username" binding:"required,min=3,max=30"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-}
-
-type LoginInput struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
-}
-
 // Register handles user registration
-func Register(c *gin.Context) {
-	var input RegisterInput
+func (h *Handler) Register(c *gin.Context) {
+	var input models.RegisterRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if user with this email already exists
-	var existingUser models.User
-	if result := database.DB.Where("email = ?", input.Email).First(&existingUser); result.RowsAffected > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+	if !utils.ValidateEmail(input.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
+		return
+	}
+	if len(input.Password) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
 		return
 	}
 
-	// Check if username is taken
-	if result := database.DB.Where("username = ?", input.Username).First(&existingUser); result.RowsAffected > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+	// The username defaults to the name
+	username := input.Username
+	if username == "" {
+		username = input.Name
+	}
+	if len(username) < 3 || len(username) > 30 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username must be between 3 and 30 characters long"})
+		return
+	}
+
+	// Check if user with this email already exists
+	if _, err := h.Users.GetUserByEmail(input.Email); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+		return
+	} else if !errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(input.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not hash password"})
 		return
@@ -53,19 +56,31 @@ func Register(c *gin.Context) {
 
 	// Create new user
 	user := models.User{
-		Name:      input.Username,
+		Name:      input.Name,
+		Username:  username,
 		Email:     input.Email,
-		Password:  string(hashedPassword),
+		Password:  hashedPassword,
 		CreatedAt: time.Now(),
+
+		DigestFrequency: models.DigestOff,
 	}
 
-	if result := database.DB.Create(&user); result.Error != nil {
+	// The store checks the email and username again, in case another registration got there first
+	err = h.Users.CreateUser(&user)
+	switch {
+	case errors.Is(err, utils.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+		return
+	case errors.Is(err, store.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
 	// Generate JWT token
-	token, err := generateToken(user.ID)
+	token, err := utils.GenerateToken(int(user.ID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
 		return
@@ -75,7 +90,8 @@ func Register(c *gin.Context) {
 		"message": "User registered successfully",
 		"user": gin.H{
 			"id":       user.ID,
-			"username": user.Name,
+			"name":     user.Name,
+			"username": user.Username,
 			"email":    user.Email,
 		},
 		"token": token,
@@ -83,28 +99,32 @@ func Register(c *gin.Context) {
 }
 
 // Login handles user authentication
-func Login(c *gin.Context) {
-	var input LoginInput
+func (h *Handler) Login(c *gin.Context) {
+	var input models.LoginRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Find user by email
-	var user models.User
-	if result := database.DB.Where("email = ?", input.Email).First(&user); result.Error != nil {
+	user, err := h.Users.GetUserByEmail(input.Email)
+	if errors.Is(err, utils.ErrNotFound) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
 
 	// Compare passwords
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+	if !utils.CheckPasswordHash(input.Password, user.Password) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
 	// Generate JWT token
-	token, err := generateToken(user.ID)
+	token, err := utils.GenerateToken(int(user.ID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
 		return
@@ -114,36 +134,22 @@ func Login(c *gin.Context) {
 		"message": "Login successful",
 		"user": gin.H{
 			"id":       user.ID,
-			"username": user.Name,
+			"name":     user.Name,
+			"username": user.Username,
 			"email":    user.Email,
 		},
 		"token": token,
 	})
 }
 
-// generateToken creates a new JWT token for a user
-func generateToken(userID uint) (string, error) {
-	// Get the JWT secret key from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-default-secret-key" // Default for development only
+// currentUserID returns the ID of the authenticated user set by AuthMiddleware
+func currentUserID(c *gin.Context) uint {
+	userID, _ := c.Get("userID")
+	switch id := userID.(type) {
+	case int:
+		return uint(id)
+	case uint:
+		return id
 	}
-
-	// Set token claims
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // Token expires in 7 days
-		"iat":     time.Now().Unix(),
-	}
-
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token with the secret key
-	tokenString, err := token.SignedString([]byte(jwtSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	return 0
 }
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTodaysChallenge returns the daily challenge for today in the current user's time zone,
+// along with whether they have forecast on it and their daily streak
+func (h *Handler) GetTodaysChallenge(c *gin.Context) {
+	userID := currentUserID(c)
+
+	user, err := h.Users.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	today := models.LocalDay(time.Now(), user.Location())
+
+	challenge, err := h.Challenges.DailyChallengeFor(today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve daily challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No challenge today"})
+		return
+	}
+
+	forecast, err := h.Forecasts.GetUserForecast(challenge.MarketID, userID)
+	if err != nil && !errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve forecast"})
+		return
+	}
+
+	stats, err := h.Stats.GetUserStats(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"day":        today,
+		"challenge":  challenge,
+		"forecasted": forecast != nil && forecast.IsActive(),
+		"day_streak": stats.ActiveDayStreak(today),
+	})
+}
+
+// CreateDailyChallenge schedules a curated market as the challenge for a day; only admins can curate
+func (h *Handler) CreateDailyChallenge(c *gin.Context) {
+	userID := currentUserID(c)
+
+	user, err := h.Users.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can schedule daily challenges"})
+		return
+	}
+
+	var input models.DailyChallengeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, err := h.Challenges.ScheduleDailyChallenge(&input, userID)
+	switch {
+	case errors.Is(err, services.ErrChallengeMarket):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrChallengeScheduled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule daily challenge"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Daily challenge scheduled successfully",
+		"challenge": challenge,
+	})
+}
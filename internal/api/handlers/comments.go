@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateCommentInput struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// GetMarketComments returns the top-level comments of a market with their replies
+func (h *Handler) GetMarketComments(c *gin.Context) {
+	market, ok := h.loadMarket(c)
+	if !ok {
+		return
+	}
+
+	// Get pagination parameters
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+
+	// Pagination applies to threads; replies are returned with their thread
+	comments, total, err := h.Comments.ListMarketComments(market.ID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments": comments,
+		"meta": gin.H{
+			"total": total,
+			"page":  page,
+			"limit": limit,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// CreateComment adds a comment or reply to a market
+func (h *Handler) CreateComment(c *gin.Context) {
+	market, ok := h.loadMarket(c)
+	if !ok {
+		return
+	}
+
+	author, err := h.Users.GetUser(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var input models.CommentRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment := models.Comment{
+		MarketID:  market.ID,
+		UserID:    author.ID,
+		Body:      input.Body,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// Replies join the thread of the comment they answer
+	if input.ParentID != nil {
+		parent, err := h.Comments.GetComment(*input.ParentID)
+		if err != nil || parent.MarketID != market.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment not found on this market"})
+			return
+		}
+		if parent.DeletedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot reply to a deleted comment"})
+			return
+		}
+
+		comment.ParentID = &parent.ID
+		comment.ThreadID = parent.ThreadID
+		if comment.ThreadID == nil {
+			comment.ThreadID = &parent.ID
+		}
+	}
+
+	if err := comment.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Comments.CreateComment(&comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+	comment.User = *author
+	h.Markets.BumpMarketTrending(market.ID, services.TrendingWeightComment)
+
+	if err := h.Comments.NotifyMentions(*author, *market, comment, nil); err != nil {
+		log.Printf("Failed to notify mentions for comment %d: %v", comment.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Comment created successfully",
+		"comment": comment,
+	})
+}
+
+// UpdateComment edits a comment within the edit window
+func (h *Handler) UpdateComment(c *gin.Context) {
+	userID := currentUserID(c)
+
+	comment, ok := h.loadComment(c)
+	if !ok {
+		return
+	}
+
+	// Check if the comment belongs to the user
+	if comment.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own comments"})
+		return
+	}
+
+	if !comment.CanEdit(time.Now(), services.CommentEditWindow) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment can no longer be edited"})
+		return
+	}
+
+	var input UpdateCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previousMentions := services.ExtractMentions(comment.Body)
+
+	now := time.Now()
+	comment.Body = input.Body
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+
+	if err := comment.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Comments.UpdateComment(comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	// Only users newly mentioned by the edit are notified
+	if market, err := h.Markets.GetMarket(comment.MarketID); err == nil {
+		if err := h.Comments.NotifyMentions(comment.User, *market, *comment, previousMentions); err != nil {
+			log.Printf("Failed to notify mentions for comment %d: %v", comment.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment updated successfully",
+		"comment": comment,
+	})
+}
+
+// DeleteComment removes a comment's content while keeping its replies in place
+func (h *Handler) DeleteComment(c *gin.Context) {
+	userID := currentUserID(c)
+
+	comment, ok := h.loadComment(c)
+	if !ok {
+		return
+	}
+
+	// Check if the comment belongs to the user
+	if comment.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own comments"})
+		return
+	}
+
+	if comment.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	now := time.Now()
+	comment.Body = ""
+	comment.DeletedAt = &now
+	comment.UpdatedAt = now
+
+	if err := h.Comments.UpdateComment(comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// loadComment loads the comment in the URL, writing a 404 if it does not exist
+func (h *Handler) loadComment(c *gin.Context) (*models.Comment, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+
+	comment, err := h.Comments.GetComment(uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comment"})
+		return nil, false
+	}
+	return comment, true
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// FollowUser makes the current user follow another user
+func (h *Handler) FollowUser(c *gin.Context) {
+	user, ok := h.loadUser(c, "id")
+	if !ok {
+		return
+	}
+
+	if user.ID == currentUserID(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+		return
+	}
+
+	if err := h.Follows.FollowUser(currentUserID(c), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User followed successfully"})
+}
+
+// UnfollowUser makes the current user stop following another user
+func (h *Handler) UnfollowUser(c *gin.Context) {
+	user, ok := h.loadUser(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Follows.UnfollowUser(currentUserID(c), user.ID); err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "You are not following this user"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unfollowed successfully"})
+}
+
+// GetFollowers returns the users following a user with pagination
+func (h *Handler) GetFollowers(c *gin.Context) {
+	h.listFollows(c, "followers", h.Follows.ListFollowers)
+}
+
+// GetFollowing returns the users a user follows with pagination
+func (h *Handler) GetFollowing(c *gin.Context) {
+	h.listFollows(c, "following", h.Follows.ListFollowing)
+}
+
+func (h *Handler) listFollows(c *gin.Context, key string, list func(userID uint, page, limit int) ([]models.User, int64, error)) {
+	user, ok := h.loadUser(c, "id")
+	if !ok {
+		return
+	}
+
+	// Get pagination parameters
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+
+	users, total, err := list(user.ID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve " + key})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		key: users,
+		"meta": gin.H{
+			"total": total,
+			"page":  page,
+			"limit": limit,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetFeed returns recent activity from the users the current user follows
+func (h *Handler) GetFeed(c *gin.Context) {
+	_, limit := utils.ParsePaginationParams("", c.Query("limit"))
+
+	activities, next, err := h.Activities.GetFeed(currentUserID(c), c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activities": activities,
+		"meta": gin.H{
+			"limit":       limit,
+			"next_cursor": next,
+		},
+	})
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateMarketPredictionInput is the body of a new forecast. Prediction is a pointer, like
+// ResolveMarketInput.Outcome, so that forecasting "no" is not mistaken for leaving it out.
+type CreateMarketPredictionInput struct {
+	Prediction *bool   `json:"prediction" binding:"required"`
+	Confidence float64 `json:"confidence" binding:"required,min=0,max=100"`
+	Rationale  string  `json:"rationale" binding:"max=5000"`
+}
+
+type UpdateMarketPredictionInput struct {
+	Prediction bool    `json:"prediction"`
+	Confidence float64 `json:"confidence" binding:"min=0,max=100"`
+	Rationale  *string `json:"rationale" binding:"omitempty,max=5000"`
+}
+
+// GetMarketPredictions returns all predictions for a specific market
+func (h *Handler) GetMarketPredictions(c *gin.Context) {
+	market, ok := h.loadMarket(c)
+	if !ok {
+		return
+	}
+
+	// Get pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Hide the crowd until the user has made their own forecast
+	visible, err := h.Forecasts.ForecastsVisible(market, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve predictions"})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusOK, gin.H{
+			"predictions": []models.Forecast{},
+			"hidden":      true,
+			"meta": gin.H{
+				"total": 0,
+				"page":  page,
+				"limit": limit,
+				"pages": 0,
+			},
+		})
+		return
+	}
+
+	predictions, total, err := h.Forecasts.ListMarketForecasts(market.ID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve predictions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"predictions": predictions,
+		"hidden":      false,
+		"meta": gin.H{
+			"total": total,
+			"page":  page,
+			"limit": limit,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// CreateMarketPrediction adds a new prediction for a market
+func (h *Handler) CreateMarketPrediction(c *gin.Context) {
+	userID := currentUserID(c)
+
+	// Check if market exists
+	market, ok := h.loadMarket(c)
+	if !ok {
+		return
+	}
+
+	// Check if market is open for predictions
+	if market.Status != models.MarketOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Market is not open for predictions"})
+		return
+	}
+
+	// Check if user already made a prediction for this market
+	existingPrediction, err := h.Forecasts.GetUserForecast(market.ID, userID)
+	if err != nil && !errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prediction"})
+		return
+	}
+
+	// Parse input
+	var input CreateMarketPredictionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// If user already made a prediction, update it (restoring it if it was withdrawn)
+	if existingPrediction != nil {
+		existingPrediction.Prediction = *input.Prediction
+		existingPrediction.Confidence = input.Confidence
+		existingPrediction.Rationale = input.Rationale
+		existingPrediction.WithdrawnAt = nil
+		existingPrediction.UpdatedAt = time.Now()
+
+		if err := h.Forecasts.SaveForecast(existingPrediction); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prediction"})
+			return
+		}
+		h.recordSnapshot(market.ID)
+		h.Markets.BumpMarketTrending(market.ID, services.TrendingWeightForecast)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Prediction updated successfully",
+			"prediction": existingPrediction,
+		})
+		return
+	}
+
+	// Create new prediction
+	prediction := models.Forecast{
+		UserID:     userID,
+		MarketID:   market.ID,
+		Prediction: *input.Prediction,
+		Confidence: input.Confidence,
+		Rationale:  input.Rationale,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	if err := h.Forecasts.SaveForecast(&prediction); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prediction"})
+		return
+	}
+	h.recordSnapshot(market.ID)
+	h.Markets.BumpMarketTrending(market.ID, services.TrendingWeightForecast)
+	h.Activities.RecordActivity(models.Activity{
+		ActorID:    userID,
+		Type:       models.ActivityForecast,
+		MarketID:   market.ID,
+		ForecastID: &prediction.ID,
+	})
+	h.Achievements.CheckAchievements(models.AchievementForecastCreated, userID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Prediction created successfully",
+		"prediction": prediction,
+	})
+}
+
+// UpdateMarketPrediction updates an existing market prediction
+func (h *Handler) UpdateMarketPrediction(c *gin.Context) {
+	userID := currentUserID(c)
+
+	prediction, ok := h.loadForecast(c)
+	if !ok {
+		return
+	}
+
+	// Check if the prediction belongs to the user
+	if prediction.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own predictions"})
+		return
+	}
+
+	if !prediction.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prediction has been withdrawn; predict on the market again instead"})
+		return
+	}
+
+	// Get the associated market
+	market, err := h.Markets.GetMarket(prediction.MarketID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Market not found"})
+		return
+	}
+
+	// Check if market is still open
+	if market.Status != models.MarketOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Market is not open for predictions"})
+		return
+	}
+
+	var input UpdateMarketPredictionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update fields
+	prediction.Prediction = input.Prediction
+	prediction.Confidence = input.Confidence
+	if input.Rationale != nil {
+		prediction.Rationale = *input.Rationale
+	}
+	prediction.UpdatedAt = time.Now()
+
+	if err := h.Forecasts.SaveForecast(prediction); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prediction"})
+		return
+	}
+	h.recordSnapshot(market.ID)
+	h.Markets.BumpMarketTrending(market.ID, services.TrendingWeightForecast)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Prediction updated successfully",
+		"prediction": prediction,
+	})
+}
+
+// WithdrawMarketPrediction withdraws a prediction while its market is still open.
+// The forecast and its history are kept, but it no longer counts towards the aggregate or scoring.
+func (h *Handler) WithdrawMarketPrediction(c *gin.Context) {
+	userID := currentUserID(c)
+
+	prediction, ok := h.loadForecast(c)
+	if !ok {
+		return
+	}
+
+	// Check if the prediction belongs to the user
+	if prediction.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only withdraw your own predictions"})
+		return
+	}
+
+	if !prediction.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prediction is already withdrawn"})
+		return
+	}
+
+	// Get the associated market
+	market, err := h.Markets.GetMarket(prediction.MarketID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Market not found"})
+		return
+	}
+
+	// Predictions can only be withdrawn before the market closes
+	if market.IsClosed(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Market is closed; predictions can no longer be withdrawn"})
+		return
+	}
+
+	now := time.Now()
+	prediction.WithdrawnAt = &now
+	prediction.UpdatedAt = now
+
+	if err := h.Forecasts.SaveForecast(prediction); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw prediction"})
+		return
+	}
+	h.recordSnapshot(market.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Prediction withdrawn successfully",
+		"prediction": prediction,
+	})
+}
+
+// recordSnapshot stores the market's aggregated forecast after a prediction changes.
+// Snapshots are derived data, so a failure is logged rather than failing the request.
+func (h *Handler) recordSnapshot(marketID uint) {
+	if _, err := h.Markets.RecordMarketSnapshot(marketID); err != nil {
+		log.Printf("Failed to record snapshot for market %d: %v", marketID, err)
+	}
+}
+
+// loadForecast loads the forecast in the URL, writing a 404 if it does not exist
+func (h *Handler) loadForecast(c *gin.Context) (*models.Forecast, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prediction not found"})
+		return nil, false
+	}
+
+	forecast, err := h.Forecasts.GetForecast(uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prediction not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prediction"})
+		return nil, false
+	}
+	return forecast, true
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"github.com/domolitom/reThink/internal/api/middleware"
+	"github.com/domolitom/reThink/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the API, reading and writing everything through its stores
+type Handler struct {
+	Users         store.UserStore
+	Follows       store.FollowStore
+	Activities    store.ActivityStore
+	Markets       store.MarketStore
+	Forecasts     store.ForecastStore
+	Predictions   store.PredictionStore
+	Votes         store.VoteStore
+	Comments      store.CommentStore
+	Notifications store.NotificationStore
+	Stats         store.StatsStore
+	Achievements  store.AchievementStore
+	Teams         store.TeamStore
+	Tournaments   store.TournamentStore
+	Challenges    store.ChallengeStore
+	Webhooks      store.WebhookStore
+}
+
+// NewHandler creates a Handler served entirely by s
+func NewHandler(s store.Store) *Handler {
+	return &Handler{
+		Users:         s,
+		Follows:       s,
+		Activities:    s,
+		Markets:       s,
+		Forecasts:     s,
+		Predictions:   s,
+		Votes:         s,
+		Comments:      s,
+		Notifications: s,
+		Stats:         s,
+		Achievements:  s,
+		Teams:         s,
+		Tournaments:   s,
+		Challenges:    s,
+		Webhooks:      s,
+	}
+}
+
+// AuthMiddleware authenticates requests by the JWT in their Authorization header
+func (h *Handler) AuthMiddleware() gin.HandlerFunc {
+	return middleware.AuthMiddleware()
+}
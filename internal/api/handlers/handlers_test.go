@@ -4,21 +4,33 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/domolitom/reThink/internal/database"
 	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/internal/store"
 	"github.com/domolitom/reThink/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
-// Mock DB for testing
+// Mock DB for testing. Methods it does not mock are served by an in-memory store.
 type MockDB struct {
 	mock.Mock
+	*store.MemoryStore
+}
+
+func newMockDB() *MockDB {
+	return &MockDB{MemoryStore: store.NewMemoryStore()}
 }
 
 func (m *MockDB) CreateUser(user *models.User) error {
@@ -71,7 +83,7 @@ func SetupTestRouter(handler *Handler) *gin.Engine {
 }
 
 func TestRegisterHandler(t *testing.T) {
-	mockDB := new(MockDB)
+	mockDB := newMockDB()
 	handler := NewHandler(mockDB)
 	router := SetupTestRouter(handler)
 
@@ -179,7 +191,7 @@ func TestRegisterHandler(t *testing.T) {
 }
 
 func TestLoginHandler(t *testing.T) {
-	mockDB := new(MockDB)
+	mockDB := newMockDB()
 	handler := NewHandler(mockDB)
 	router := SetupTestRouter(handler)
 
@@ -275,7 +287,7 @@ func TestLoginHandler(t *testing.T) {
 }
 
 func TestCreatePredictionHandler(t *testing.T) {
-	mockDB := new(MockDB)
+	mockDB := newMockDB()
 	handler := NewHandler(mockDB)
 	router := SetupTestRouter(handler)
 
@@ -367,7 +379,7 @@ func TestCreatePredictionHandler(t *testing.T) {
 }
 
 func TestGetPredictionsHandler(t *testing.T) {
-	mockDB := new(MockDB)
+	mockDB := newMockDB()
 	handler := NewHandler(mockDB)
 	router := SetupTestRouter(handler)
 
@@ -466,7 +478,7 @@ func TestGetPredictionsHandler(t *testing.T) {
 }
 
 func TestVotePredictionHandler(t *testing.T) {
-	mockDB := new(MockDB)
+	mockDB := newMockDB()
 	handler := NewHandler(mockDB)
 	router := SetupTestRouter(handler)
 
@@ -539,3 +551,463 @@ func TestVotePredictionHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestVotePredictionRejectsInvalidVote(t *testing.T) {
+	handler, _ := newSQLiteHandler(t)
+	router := SetupTestRouter(handler)
+	token, _ := utils.GenerateToken(1)
+
+	code, response := serveJSON(router, "POST", "/predictions/0/vote", token, models.VoteRequest{Value: true})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, response["error"], "prediction_id")
+
+	code, _ = serveJSON(router, "POST", "/predictions/1/vote", token, map[string]interface{}{"value": "maybe"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestHandlersWithMemoryStore(t *testing.T) {
+	memory := store.NewMemoryStore()
+	handler := NewHandler(memory)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/register", handler.Register)
+	r.POST("/auth/login", handler.Login)
+	auth := r.Group("/")
+	auth.Use(handler.AuthMiddleware())
+	{
+		auth.POST("/markets", handler.CreateMarket)
+		auth.GET("/markets", handler.GetMarkets)
+		auth.GET("/markets/:id", handler.GetMarket)
+		auth.POST("/predictions", handler.CreatePrediction)
+		auth.GET("/predictions", handler.GetPredictions)
+		auth.POST("/predictions/:id/vote", handler.VotePrediction)
+		auth.GET("/notifications/unread-count", handler.GetUnreadNotificationCount)
+		auth.POST("/notifications/:id/read", handler.MarkNotificationRead)
+	}
+
+	do := func(method, path, token string, payload interface{}) (int, map[string]interface{}) {
+		return serveJSON(r, method, path, token, payload)
+	}
+
+	// Register, then log in with the same credentials
+	register := models.RegisterRequest{Name: "Memory User", Username: "memory", Email: "memory@example.com", Password: "password123"}
+	code, _ := do("POST", "/auth/register", "", register)
+	assert.Equal(t, http.StatusCreated, code)
+
+	code, response := do("POST", "/auth/register", "", models.RegisterRequest{Name: "Someone Else", Username: "memory", Email: "else@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "Username is already taken", response["error"])
+
+	code, response = do("POST", "/auth/login", "", models.LoginRequest{Email: register.Email, Password: register.Password})
+	assert.Equal(t, http.StatusOK, code)
+	token, _ := response["token"].(string)
+	assert.NotEmpty(t, token)
+
+	// Markets
+	code, response = do("POST", "/markets", token, CreateMarketInput{
+		Title:       "Will it rain tomorrow?",
+		Description: "Resolves true if it rains",
+		Category:    "Weather",
+		CloseDate:   time.Now().AddDate(0, 0, 1),
+		ResolveDate: time.Now().AddDate(0, 0, 2),
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	market, _ := response["market"].(map[string]interface{})
+
+	code, response = do("GET", fmt.Sprintf("/markets/%v", market["id"]), token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	market, _ = response["market"].(map[string]interface{})
+	creator, _ := market["creator"].(map[string]interface{})
+	assert.Equal(t, "memory", creator["username"])
+
+	code, _ = do("GET", "/markets/999", token, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, response = do("GET", "/markets?category=Weather", token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, response["markets"], 1)
+
+	// Predictions and votes
+	code, response = do("POST", "/predictions", token, models.PredictionRequest{
+		Title:       "Rates fall this year",
+		Description: "Central banks cut rates",
+		Category:    "Finance",
+		EndDate:     time.Now().AddDate(0, 6, 0),
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	prediction, _ := response["prediction"].(map[string]interface{})
+
+	code, _ = do("POST", fmt.Sprintf("/predictions/%v/vote", prediction["id"]), token, models.VoteRequest{Value: true})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("POST", "/predictions/999/vote", token, models.VoteRequest{Value: true})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, response = do("GET", "/predictions?category=Finance", token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	predictions, _ := response["predictions"].([]interface{})
+	if assert.Len(t, predictions, 1) {
+		listed, _ := predictions[0].(map[string]interface{})
+		assert.Equal(t, float64(1), listed["agree_count"])
+		assert.Equal(t, true, listed["user_vote"])
+	}
+
+	// Notifications
+	user, _ := memory.GetUserByEmail(register.Email)
+	notification := models.Notification{UserID: int(user.ID), Type: models.NotificationVote, Message: "Someone agreed"}
+	memory.CreateNotification(&notification)
+
+	code, response = do("GET", "/notifications/unread-count", token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), response["unread"])
+
+	code, _ = do("POST", fmt.Sprintf("/notifications/%d/read", notification.ID), token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, response = do("GET", "/notifications/unread-count", token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(0), response["unread"])
+}
+
+// serveJSON sends a JSON request through the router, authenticated by token if it is set,
+// and returns the response code and decoded body
+func serveJSON(r *gin.Engine, method, path, token string, payload interface{}) (int, map[string]interface{}) {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+// newSQLiteHandler returns a Handler backed by a migrated SQLite database that lives for the test
+func newSQLiteHandler(t *testing.T) (*Handler, *gorm.DB) {
+	t.Helper()
+	db, err := database.Open("sqlite:" + filepath.Join(t.TempDir(), "rethink.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	return NewHandler(store.NewGormStore(db)), db
+}
+
+func TestHandlersWithSQLite(t *testing.T) {
+	handler, db := newSQLiteHandler(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/register", handler.Register)
+	r.POST("/auth/login", handler.Login)
+	auth := r.Group("/")
+	auth.Use(handler.AuthMiddleware())
+	{
+		auth.POST("/markets", handler.CreateMarket)
+		auth.GET("/markets", handler.GetMarkets)
+		auth.POST("/markets/:id/predict", handler.CreateMarketPrediction)
+		auth.POST("/markets/:id/resolve", handler.ResolveMarket)
+		auth.POST("/predictions", handler.CreatePrediction)
+		auth.GET("/predictions", handler.GetPredictions)
+		auth.POST("/predictions/:id/vote", handler.VotePrediction)
+		auth.POST("/teams", handler.CreateTeam)
+		auth.GET("/users/:id/stats", handler.GetUserStats)
+		auth.GET("/leaderboard", handler.GetLeaderboard)
+		auth.GET("/leaderboard/teams", handler.GetTeamLeaderboard)
+	}
+	do := func(method, path, token string, payload interface{}) (int, map[string]interface{}) {
+		return serveJSON(r, method, path, token, payload)
+	}
+
+	login := func(name string) (string, uint) {
+		register := models.RegisterRequest{Name: name, Username: name, Email: name + "@example.com", Password: "password123"}
+		code, response := do("POST", "/auth/register", "", register)
+		assert.Equal(t, http.StatusCreated, code)
+		user, _ := response["user"].(map[string]interface{})
+		id, _ := user["id"].(float64)
+
+		code, response = do("POST", "/auth/login", "", models.LoginRequest{Email: register.Email, Password: register.Password})
+		assert.Equal(t, http.StatusOK, code)
+		token, _ := response["token"].(string)
+		return token, uint(id)
+	}
+	alice, aliceID := login("alice")
+	bob, bobID := login("bob")
+
+	code, response := do("POST", "/auth/register", "", models.RegisterRequest{Name: "Alice Again", Username: "alice2", Email: "alice@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "User already exists", response["error"])
+
+	// Both forecast yes on Alice's market and it resolves true; Bob was more confident
+	code, response = do("POST", "/markets", alice, CreateMarketInput{
+		Title:       "Will it rain tomorrow?",
+		Description: "Resolves true if it rains",
+		Category:    "Weather",
+		CloseDate:   time.Now().AddDate(0, 0, 1),
+		ResolveDate: time.Now().AddDate(0, 0, 2),
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	market, _ := response["market"].(map[string]interface{})
+	marketPath := fmt.Sprintf("/markets/%v", market["id"])
+
+	yes := true
+	code, _ = do("POST", marketPath+"/predict", bob, CreateMarketPredictionInput{Prediction: &yes, Confidence: 90})
+	assert.Equal(t, http.StatusCreated, code)
+	code, _ = do("POST", marketPath+"/predict", alice, CreateMarketPredictionInput{Prediction: &yes, Confidence: 60})
+	assert.Equal(t, http.StatusCreated, code)
+
+	// Teams count scores resolved after their members joined
+	code, _ = do("POST", "/teams", bob, models.TeamRequest{Name: "Rain Makers"})
+	assert.Equal(t, http.StatusCreated, code)
+
+	code, _ = do("POST", marketPath+"/resolve", alice, ResolveMarketInput{Outcome: &yes})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, response = do("GET", "/markets?status=resolved", bob, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, response["markets"], 1)
+
+	code, response = do("GET", fmt.Sprintf("/users/%d/stats", bobID), bob, nil)
+	assert.Equal(t, http.StatusOK, code)
+	stats, _ := response["stats"].(map[string]interface{})
+	assert.Equal(t, float64(1), stats["resolved_predictions"])
+	assert.Equal(t, float64(1), stats["correct_predictions"])
+	assert.Len(t, stats["recent_predictions"], 1)
+
+	code, response = do("GET", "/leaderboard", bob, nil)
+	assert.Equal(t, http.StatusOK, code)
+	leaderboard, _ := response["leaderboard"].([]interface{})
+	if assert.Len(t, leaderboard, 2) {
+		first, _ := leaderboard[0].(map[string]interface{})
+		assert.Equal(t, float64(bobID), first["user_id"])
+		assert.Equal(t, float64(1), first["rank"])
+	}
+
+	code, response = do("GET", "/leaderboard/teams", bob, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, response["leaderboard"], 1)
+
+	// Predictions in each status, listed newest first
+	code, response = do("POST", "/predictions", alice, models.PredictionRequest{
+		Title:       "Rates fall this year",
+		Description: "Central banks cut rates",
+		Category:    "Finance",
+		EndDate:     time.Now().AddDate(0, 1, 0),
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	pending, _ := response["prediction"].(map[string]interface{})
+
+	now := time.Now()
+	ended := models.Prediction{UserID: int(aliceID), Title: "Ended", Description: "Ended", Category: "Tech", EndDate: now.Add(-time.Hour), CreatedAt: now.Add(-2 * time.Hour)}
+	resolved := models.Prediction{UserID: int(bobID), Title: "Resolved", Description: "Resolved", Category: "Tech", EndDate: now.Add(-2 * time.Hour), CreatedAt: now.Add(-3 * time.Hour)}
+	assert.NoError(t, db.Create(&ended).Error)
+	assert.NoError(t, db.Create(&resolved).Error)
+	assert.NoError(t, db.Create(&models.Result{PredictionID: resolved.ID, Outcome: true, EvidenceURL: "https://example.com"}).Error)
+
+	code, _ = do("POST", fmt.Sprintf("/predictions/%v/vote", pending["id"]), bob, models.VoteRequest{Value: true})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, response = do("GET", "/predictions", bob, nil)
+	assert.Equal(t, http.StatusOK, code)
+	predictions, _ := response["predictions"].([]interface{})
+	if assert.Len(t, predictions, 3) {
+		titles := make([]string, len(predictions))
+		for i, p := range predictions {
+			titles[i], _ = p.(map[string]interface{})["title"].(string)
+		}
+		assert.Equal(t, []string{"Rates fall this year", "Ended", "Resolved"}, titles)
+
+		newest, _ := predictions[0].(map[string]interface{})
+		assert.Equal(t, float64(1), newest["agree_count"])
+		assert.Equal(t, true, newest["user_vote"])
+	}
+
+	for status, title := range map[models.PredictionStatus]string{
+		models.PredictionPending:  "Rates fall this year",
+		models.PredictionEnded:    "Ended",
+		models.PredictionResolved: "Resolved",
+	} {
+		code, response = do("GET", "/predictions?status="+string(status), bob, nil)
+		assert.Equal(t, http.StatusOK, code, status)
+		predictions, _ := response["predictions"].([]interface{})
+		if assert.Len(t, predictions, 1, status) {
+			assert.Equal(t, title, predictions[0].(map[string]interface{})["title"], status)
+		}
+	}
+
+	code, response = do("GET", fmt.Sprintf("/predictions?category=Tech&user_id=%d", bobID), bob, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, response["predictions"], 1)
+}
+
+func TestUnsubscribeLinks(t *testing.T) {
+	handler, db := newSQLiteHandler(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/register", handler.Register)
+	r.GET("/unsubscribe", handler.ConfirmUnsubscribe)
+	r.POST("/unsubscribe", handler.Unsubscribe)
+
+	// New users get no digests until they opt in
+	code, response := serveJSON(r, "POST", "/auth/register", "", models.RegisterRequest{Name: "Reader", Email: "reader@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusCreated, code)
+	id, _ := response["user"].(map[string]interface{})["id"].(float64)
+
+	var user models.User
+	db.First(&user, uint(id))
+	assert.Equal(t, models.DigestOff, user.DigestFrequency)
+	db.Model(&user).Update("digest_frequency", models.DigestDaily)
+
+	link := "/unsubscribe?token=" + url.QueryEscape(utils.SignUnsubscribeToken(int(user.ID), services.UnsubscribeDigests))
+	send := func(method, path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Following the link only asks for confirmation
+	w := send("GET", link, "text/html")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<form method="post">`)
+	db.First(&user, user.ID)
+	assert.Equal(t, models.DigestDaily, user.DigestFrequency)
+
+	w = send("GET", "/unsubscribe?token=forged", "text/html")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotContains(t, w.Body.String(), "<form")
+
+	// Confirming from the page, or a mail client's one-click POST, unsubscribes
+	w = send("POST", link, "text/html")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "You have been unsubscribed.")
+	db.First(&user, user.ID)
+	assert.Equal(t, models.DigestOff, user.DigestFrequency)
+
+	w = send("POST", "/unsubscribe?token=forged", "application/json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid unsubscribe link")
+}
+
+func TestAdminResolvesOverdueMarket(t *testing.T) {
+	handler, db := newSQLiteHandler(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/markets/:id/resolve", handler.AuthMiddleware(), handler.ResolveMarket)
+
+	creator := models.User{Username: "creator", Email: "creator@example.com", Password: "x"}
+	admin := models.User{Username: "admin", Email: "admin@example.com", Password: "x", IsAdmin: true}
+	other := models.User{Username: "other", Email: "other@example.com", Password: "x"}
+	for _, user := range []*models.User{&creator, &admin, &other} {
+		assert.NoError(t, db.Create(user).Error)
+	}
+	adminToken, _ := utils.GenerateToken(int(admin.ID))
+	otherToken, _ := utils.GenerateToken(int(other.ID))
+
+	newMarket := func(resolveDate time.Time) string {
+		market := models.Market{
+			Title:       "Will the bridge open on time?",
+			CreatorID:   creator.ID,
+			Status:      models.MarketClosed,
+			CloseDate:   resolveDate.Add(-time.Hour),
+			ResolveDate: resolveDate,
+		}
+		assert.NoError(t, db.Create(&market).Error)
+		return fmt.Sprintf("/markets/%d/resolve", market.ID)
+	}
+
+	no := false
+	resolution := ResolveMarketInput{Outcome: &no}
+
+	// Within the grace period only the creator may resolve
+	recent := newMarket(time.Now().Add(-time.Hour))
+	code, _ := serveJSON(r, "POST", recent, adminToken, resolution)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// Once the moderators have been asked to step in, admins may resolve it, but nobody else
+	overdue := newMarket(time.Now().Add(-services.ResolutionGracePeriod - time.Hour))
+	code, _ = serveJSON(r, "POST", overdue, otherToken, resolution)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, response := serveJSON(r, "POST", overdue, adminToken, resolution)
+	assert.Equal(t, http.StatusOK, code)
+	market, _ := response["market"].(map[string]interface{})
+	assert.Equal(t, string(models.MarketResolved), market["status"])
+	assert.Equal(t, false, market["outcome"])
+
+	// The outcome must be given, and false is not the same as missing
+	unanswered := newMarket(time.Now().Add(-services.ResolutionGracePeriod - time.Hour))
+	code, _ = serveJSON(r, "POST", unanswered, adminToken, gin.H{})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestMarketWebhookPayloadsLeaveOutUsers(t *testing.T) {
+	handler, db := newSQLiteHandler(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	auth := r.Group("/")
+	auth.Use(handler.AuthMiddleware())
+	{
+		auth.POST("/webhooks", handler.CreateWebhook)
+		auth.POST("/markets", handler.CreateMarket)
+		auth.POST("/markets/:id/resolve", handler.ResolveMarket)
+	}
+
+	creator := models.User{Username: "creator", Email: "creator@example.com", Password: "x", TimeZone: "Europe/Berlin"}
+	assert.NoError(t, db.Create(&creator).Error)
+	token, _ := utils.GenerateToken(int(creator.ID))
+
+	code, _ := serveJSON(r, "POST", "/webhooks", token, models.WebhookRequest{
+		URL:    "https://example.com/hooks",
+		Events: []models.WebhookEvent{models.WebhookMarketCreated, models.WebhookMarketClosed, models.WebhookMarketResolved},
+	})
+	assert.Equal(t, http.StatusCreated, code)
+
+	// Created and resolved through the API
+	code, response := serveJSON(r, "POST", "/markets", token, CreateMarketInput{
+		Title:       "Will it rain tomorrow?",
+		Description: "Resolves true if it rains",
+		CloseDate:   time.Now().AddDate(0, 0, 1),
+		ResolveDate: time.Now().AddDate(0, 0, 2),
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	market, _ := response["market"].(map[string]interface{})
+	yes := true
+	code, _ = serveJSON(r, "POST", fmt.Sprintf("/markets/%v/resolve", market["id"]), token, ResolveMarketInput{Outcome: &yes})
+	assert.Equal(t, http.StatusOK, code)
+
+	// Closed by the lifecycle job
+	expired := models.Market{Title: "Expired", CreatorID: creator.ID, Status: models.MarketOpen, CloseDate: time.Now().Add(-time.Hour), ResolveDate: time.Now().Add(time.Hour)}
+	assert.NoError(t, db.Create(&expired).Error)
+	_, err := services.CloseExpiredMarkets(db, time.Now())
+	assert.NoError(t, err)
+
+	var deliveries []models.WebhookDelivery
+	assert.NoError(t, db.Order("id asc").Find(&deliveries).Error)
+	events := make([]models.WebhookEvent, len(deliveries))
+	for i, delivery := range deliveries {
+		events[i] = delivery.Event
+
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(delivery.Payload), &body))
+		assert.Equal(t, float64(creator.ID), body.Data["creator_id"])
+		assert.NotContains(t, body.Data, "creator")
+		assert.NotContains(t, delivery.Payload, creator.Email)
+		assert.NotContains(t, delivery.Payload, creator.TimeZone)
+		assert.NotContains(t, delivery.Payload, "is_admin")
+	}
+	assert.Equal(t, []models.WebhookEvent{models.WebhookMarketCreated, models.WebhookMarketResolved, models.WebhookMarketClosed}, events)
+}
@@ -1,58 +1,65 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/domolitom/reThink/internal/database"
 	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/internal/store"
+	"github.com/domolitom/reThink/utils"
 	"github.com/gin-gonic/gin"
 )
 
 type CreateMarketInput struct {
-	Title       string    `json:"title" binding:"required"`
-	Description string    `json:"description" binding:"required"`
-	CloseDate   time.Time `json:"close_date" binding:"required"`
-	ResolveDate time.Time `json:"resolve_date" binding:"required"`
+	Title         string    `json:"title" binding:"required"`
+	Description   string    `json:"description" binding:"required"`
+	Category      string    `json:"category"`
+	CloseDate     time.Time `json:"close_date" binding:"required"`
+	ResolveDate   time.Time `json:"resolve_date" binding:"required"`
+	HideForecasts *bool     `json:"hide_forecasts"`
 }
 
 type UpdateMarketInput struct {
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	CloseDate   time.Time `json:"close_date"`
-	ResolveDate time.Time `json:"resolve_date"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Category      string    `json:"category"`
+	CloseDate     time.Time `json:"close_date"`
+	ResolveDate   time.Time `json:"resolve_date"`
+	HideForecasts *bool     `json:"hide_forecasts"`
 }
 
+// ResolveMarketInput is the body of a resolution. Outcome is a pointer so that a missing
+// outcome is rejected while false is still a valid answer.
 type ResolveMarketInput struct {
-	Outcome bool `json:"outcome" binding:"required"`
+	Outcome *bool `json:"outcome" binding:"required"`
 }
 
 // GetMarkets returns all markets with pagination
-func GetMarkets(c *gin.Context) {
+func (h *Handler) GetMarkets(c *gin.Context) {
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset := (page - 1) * limit
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
 
-	// Get status filter if provided
-	status := c.Query("status")
-
-	var markets []models.Market
-	var total int64
-	query := database.DB.Model(&models.Market{})
-
-	// Apply status filter if provided
-	if status != "" {
-		query = query.Where("status = ?", status)
+	// Get status and category filters if provided
+	filter := store.MarketFilter{
+		Status:   models.MarketStatus(c.Query("status")),
+		Category: c.Query("category"),
 	}
 
-	// Count total records for pagination
-	query.Count(&total)
+	// Newest first unless trending is requested
+	switch c.Query("sort") {
+	case "", "new":
+	case "trending":
+		filter.Trending = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be new or trending"})
+		return
+	}
 
-	// Execute query with pagination
-	result := query.Preload("Creator").Order("created_at desc").Limit(limit).Offset(offset).Find(&markets)
-	if result.Error != nil {
+	markets, total, err := h.Markets.ListMarkets(filter, page, limit)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve markets"})
 		return
 	}
@@ -69,21 +76,67 @@ func GetMarkets(c *gin.Context) {
 }
 
 // GetMarket returns a specific market by ID
-func GetMarket(c *gin.Context) {
-	id := c.Param("id")
-
-	var market models.Market
-	result := database.DB.Preload("Creator").First(&market, id)
-	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Market not found"})
+func (h *Handler) GetMarket(c *gin.Context) {
+	market, ok := h.loadMarket(c)
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"market": market})
 }
 
+// GetMarketTimeseries returns the market's crowd probability over time for charts
+func (h *Handler) GetMarketTimeseries(c *gin.Context) {
+	market, ok := h.loadMarket(c)
+	if !ok {
+		return
+	}
+
+	resolution, err := services.ParseResolution(c.DefaultQuery("resolution", "1h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Optional time range
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The aggregate is hidden along with individual forecasts
+	visible, err := h.Forecasts.ForecastsVisible(market, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve timeseries"})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusOK, gin.H{
+			"market_id":  market.ID,
+			"resolution": resolution.String(),
+			"points":     []models.TimeseriesPoint{},
+			"hidden":     true,
+		})
+		return
+	}
+
+	points, err := h.Markets.GetMarketTimeseries(market.ID, from, to, resolution)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve timeseries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"market_id":  market.ID,
+		"resolution": resolution.String(),
+		"points":     points,
+		"hidden":     false,
+	})
+}
+
 // CreateMarket creates a new market
-func CreateMarket(c *gin.Context) {
+func (h *Handler) CreateMarket(c *gin.Context) {
 	var input CreateMarketInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -91,24 +144,33 @@ func CreateMarket(c *gin.Context) {
 	}
 
 	// Get the current user ID from context
-	userID, _ := c.Get("userID")
+	userID := currentUserID(c)
 
 	// Create new market
 	market := models.Market{
-		Title:       input.Title,
-		Description: input.Description,
-		CreatorID:   userID.(uint),
-		CloseDate:   input.CloseDate,
-		ResolveDate: input.ResolveDate,
-		Status:      models.MarketOpen,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Title:         input.Title,
+		Description:   input.Description,
+		Category:      input.Category,
+		CreatorID:     userID,
+		CloseDate:     input.CloseDate,
+		ResolveDate:   input.ResolveDate,
+		Status:        models.MarketOpen,
+		HideForecasts: input.HideForecasts,
+		HotScore:      services.HotScore(services.TrendingWeightCreated, time.Now()),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
-	if result := database.DB.Create(&market); result.Error != nil {
+	if err := h.Markets.CreateMarket(&market); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create market"})
 		return
 	}
+	h.Activities.RecordActivity(models.Activity{
+		ActorID:  market.CreatorID,
+		Type:     models.ActivityMarketCreated,
+		MarketID: market.ID,
+	})
+	h.Webhooks.DispatchWebhookEvent(models.WebhookMarketCreated, market.CreatorID, models.NewMarketWebhookPayload(&market))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Market created successfully",
@@ -117,18 +179,16 @@ func CreateMarket(c *gin.Context) {
 }
 
 // UpdateMarket updates an existing market
-func UpdateMarket(c *gin.Context) {
-	id := c.Param("id")
-	userID, _ := c.Get("userID")
+func (h *Handler) UpdateMarket(c *gin.Context) {
+	userID := currentUserID(c)
 
-	var market models.Market
-	if result := database.DB.First(&market, id); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Market not found"})
+	market, ok := h.loadMarket(c)
+	if !ok {
 		return
 	}
 
 	// Check if user is the creator
-	if market.CreatorID != userID.(uint) {
+	if market.CreatorID != userID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update markets you created"})
 		return
 	}
@@ -152,16 +212,22 @@ func UpdateMarket(c *gin.Context) {
 	if input.Description != "" {
 		market.Description = input.Description
 	}
+	if input.Category != "" {
+		market.Category = input.Category
+	}
 	if !input.CloseDate.IsZero() {
 		market.CloseDate = input.CloseDate
 	}
 	if !input.ResolveDate.IsZero() {
 		market.ResolveDate = input.ResolveDate
 	}
+	if input.HideForecasts != nil {
+		market.HideForecasts = input.HideForecasts
+	}
 
 	market.UpdatedAt = time.Now()
 
-	if result := database.DB.Save(&market); result.Error != nil {
+	if err := h.Markets.UpdateMarket(market); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update market"})
 		return
 	}
@@ -173,20 +239,25 @@ func UpdateMarket(c *gin.Context) {
 }
 
 // ResolveMarket resolves a market with a final outcome
-func ResolveMarket(c *gin.Context) {
-	id := c.Param("id")
-	userID, _ := c.Get("userID")
+func (h *Handler) ResolveMarket(c *gin.Context) {
+	userID := currentUserID(c)
 
-	var market models.Market
-	if result := database.DB.First(&market, id); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Market not found"})
+	market, ok := h.loadMarket(c)
+	if !ok {
 		return
 	}
 
-	// Check if user is the creator
-	if market.CreatorID != userID.(uint) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator can resolve this market"})
-		return
+	// The creator resolves the market; once the resolution is overdue, admins may step in
+	if market.CreatorID != userID {
+		user, err := h.Users.GetUser(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if !user.IsAdmin || !services.ResolutionOverdue(market, time.Now()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator can resolve this market"})
+			return
+		}
 	}
 
 	// Check if market is already resolved
@@ -201,60 +272,68 @@ func ResolveMarket(c *gin.Context) {
 		return
 	}
 
-	// Update market status and outcome
-	outcome := input.Outcome
-	market.Status = models.MarketResolved
-	market.Outcome = &outcome
-	market.UpdatedAt = time.Now()
-
-	// Start a transaction
-	tx := database.DB.Begin()
-
-	// Save the market resolution
-	if result := tx.Save(&market); result.Error != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve market"})
+	// Resolve the market and score its forecasts in one go
+	records, err := h.Markets.ResolveMarket(market, *input.Outcome, time.Now())
+	if errors.Is(err, services.ErrMarketAlreadyResolved) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Market is already resolved"})
 		return
 	}
-
-	// Update user prediction scores
-	var predictions []models.Prediction
-	if result := tx.Where("market_id = ?", market.ID).Preload("User").Find(&predictions); result.Error != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve predictions"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve market"})
 		return
 	}
+	h.Activities.RecordActivity(models.Activity{
+		ActorID:  market.CreatorID,
+		Type:     models.ActivityMarketResolved,
+		MarketID: market.ID,
+	})
+	h.Webhooks.DispatchWebhookEvent(models.WebhookMarketResolved, market.CreatorID, models.NewMarketWebhookPayload(market))
 
-	// Calculate and update scores for each user who predicted
-	for _, prediction := range predictions {
-		// Calculate score adjustment based on prediction accuracy
-		// This is a basic scoring algorithm; you might want more sophisticated ones
-		var scoreAdjustment float64
-		if *prediction.UserVote == outcome {
-			// Correct prediction: higher confidence = higher score
-			scoreAdjustment = prediction.Confidence / 50.0 // Normalized to 0-2
-		} else {
-			// Wrong prediction: higher confidence = larger penalty
-			scoreAdjustment = -prediction.Confidence / 100.0 // Normalized to 0-1
-		}
-
-		// Update user's prediction score
-		prediction.User.PredictionScore += scoreAdjustment
-		if result := tx.Save(&prediction.User); result.Error != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user scores"})
-			return
-		}
-	}
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete market resolution"})
-		return
+	forecasterIDs := make([]uint, len(records))
+	for i, record := range records {
+		forecasterIDs[i] = record.UserID
 	}
+	h.Achievements.CheckAchievements(models.AchievementMarketResolved, forecasterIDs...)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Market resolved successfully",
 		"market":  market,
 	})
 }
+
+// loadMarket loads the market in the URL, writing a 404 if it does not exist
+func (h *Handler) loadMarket(c *gin.Context) (*models.Market, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid market ID"})
+		return nil, false
+	}
+
+	market, err := h.Markets.GetMarket(uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Market not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve market"})
+		return nil, false
+	}
+	return market, true
+}
+
+// parseTimeRange reads the optional RFC3339 "from" and "to" query parameters
+func parseTimeRange(c *gin.Context) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, errors.New("from must be an RFC3339 timestamp")
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, errors.New("to must be an RFC3339 timestamp")
+		}
+	}
+	return from, to, nil
+}
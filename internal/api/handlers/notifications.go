@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications returns the current user's notifications, optionally only unread ones
+func (h *Handler) GetNotifications(c *gin.Context) {
+	_, limit := utils.ParsePaginationParams("", c.Query("limit"))
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, next, err := h.Notifications.ListNotifications(int(currentUserID(c)), unreadOnly, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"meta": gin.H{
+			"limit":       limit,
+			"next_cursor": next,
+		},
+	})
+}
+
+// GetUnreadNotificationCount returns how many unread notifications the current user has
+func (h *Handler) GetUnreadNotificationCount(c *gin.Context) {
+	count, err := h.Notifications.CountUnreadNotifications(int(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+// MarkNotificationRead marks one of the current user's notifications as read
+func (h *Handler) MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	if err := h.Notifications.MarkNotificationRead(int(currentUserID(c)), id); err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead marks all of the current user's notifications as read
+func (h *Handler) MarkAllNotificationsRead(c *gin.Context) {
+	updated, err := h.Notifications.MarkAllNotificationsRead(int(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notifications marked as read",
+		"updated": updated,
+	})
+}
+
+// DeleteNotification deletes one of the current user's notifications
+func (h *Handler) DeleteNotification(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	if err := h.Notifications.DeleteNotification(int(currentUserID(c)), id); err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification deleted successfully"})
+}
+
+// GetNotificationPreferences returns the current user's notification channels and digest frequency
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	userID := currentUserID(c)
+
+	user, err := h.Users.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	channels, err := h.Notifications.NotificationPreferences(int(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels":         channels,
+		"digest_frequency": user.DigestFrequency,
+	})
+}
+
+// UpdateNotificationPreferences changes the current user's notification channels and digest frequency
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	var input models.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Notifications.UpdateNotificationPreferences(int(currentUserID(c)), input); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	h.GetNotificationPreferences(c)
+}
+
+// unsubscribePage is the page unsubscribe links open. Its form posts back to the same URL,
+// token included, so only a person pressing the button unsubscribes.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe from reThink emails</title></head>
+<body>
+<p>{{.Message}}</p>
+{{if .Confirm}}<form method="post"><button type="submit">Unsubscribe</button></form>{{end}}
+</body>
+</html>
+`))
+
+// ConfirmUnsubscribe shows the page an unsubscribe link from an email opens. Mail scanners and
+// link prefetchers follow links on their own, so opening one only asks for confirmation.
+func (h *Handler) ConfirmUnsubscribe(c *gin.Context) {
+	if _, _, err := utils.VerifyUnsubscribeToken(c.Query("token")); err != nil {
+		renderUnsubscribePage(c, http.StatusBadRequest, "This unsubscribe link is invalid.", false)
+		return
+	}
+
+	renderUnsubscribePage(c, http.StatusOK, "Stop receiving these emails from reThink?", true)
+}
+
+// Unsubscribe applies a signed unsubscribe link, either confirmed on its page or posted by a
+// mail client's one-click unsubscribe (RFC 8058). It needs no login: the token itself proves
+// the link was sent to the user.
+func (h *Handler) Unsubscribe(c *gin.Context) {
+	html := c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+
+	userID, scope, err := utils.VerifyUnsubscribeToken(c.Query("token"))
+	if err == nil {
+		err = h.Notifications.Unsubscribe(userID, scope)
+	}
+
+	switch {
+	case errors.Is(err, utils.ErrInvalidToken), errors.Is(err, services.ErrInvalidUnsubscribeScope):
+		if html {
+			renderUnsubscribePage(c, http.StatusBadRequest, "This unsubscribe link is invalid.", false)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unsubscribe link"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+	case html:
+		renderUnsubscribePage(c, http.StatusOK, "You have been unsubscribed.", false)
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "You have been unsubscribed"})
+	}
+}
+
+// renderUnsubscribePage writes the unsubscribe page with a message and, if confirm is set, its button
+func renderUnsubscribePage(c *gin.Context, status int, message string, confirm bool) {
+	var page bytes.Buffer
+	data := struct {
+		Message string
+		Confirm bool
+	}{message, confirm}
+	if err := unsubscribePage.Execute(&page, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render page"})
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
@@ -1,53 +1,76 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/domolitom/reThink/internal/database"
 	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
 	"github.com/gin-gonic/gin"
 )
 
-type CreatePredictionInput struct {
-	Prediction bool    `json:"prediction" binding:"required"`
-	Confidence float64 `json:"confidence" binding:"required,min=0,max=100"`
-}
-
 type UpdatePredictionInput struct {
-	Prediction bool    `json:"prediction"`
-	Confidence float64 `json:"confidence" binding:"min=0,max=100"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	EndDate     time.Time `json:"end_date"`
 }
 
-// GetMarketPredictions returns all predictions for a specific market
-func GetMarketPredictions(c *gin.Context) {
-	marketID := c.Param("id")
+// GetPredictions returns predictions with pagination, filtered by user, category and status
+func (h *Handler) GetPredictions(c *gin.Context) {
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
 
-	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset := (page - 1) * limit
+	filter := models.PredictionFilter{
+		Category: c.Query("category"),
+		Status:   models.PredictionStatus(c.Query("status")),
+		Trending: c.Query("sort") == "trending",
+	}
 
-	var predictions []models.Prediction
-	var total int64
+	switch c.Query("sort") {
+	case "", "new", "trending":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be new or trending"})
+		return
+	}
 
-	// Count total records for pagination
-	database.DB.Model(&models.Prediction{}).Where("market_id = ?", marketID).Count(&total)
+	switch filter.Status {
+	case "", models.PredictionPending, models.PredictionEnded, models.PredictionResolved:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, ended or resolved"})
+		return
+	}
 
-	// Execute query with pagination
-	result := database.DB.Where("market_id = ?", marketID).
-		Preload("User").
-		Order("created_at desc").
-		Limit(limit).
-		Offset(offset).
-		Find(&predictions)
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		filter.UserID = id
+	}
 
-	if result.Error != nil {
+	viewerID := int(currentUserID(c))
+	var predictions []models.Prediction
+	var err error
+	if filter == (models.PredictionFilter{}) {
+		predictions, err = h.Predictions.GetPredictions(viewerID, page, limit)
+	} else {
+		predictions, err = h.Predictions.FindPredictions(filter, viewerID, page, limit)
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve predictions"})
 		return
 	}
 
+	total, err := h.Predictions.CountPredictions(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count predictions"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"predictions": predictions,
 		"meta": gin.H{
@@ -59,69 +82,56 @@ func GetMarketPredictions(c *gin.Context) {
 	})
 }
 
-// CreatePrediction adds a new prediction for a market
-func CreatePrediction(c *gin.Context) {
-	marketID := c.Param("id")
-	userID, _ := c.Get("userID")
-
-	// Check if market exists
-	var market models.Market
-	if result := database.DB.First(&market, marketID); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Market not found"})
+// GetPrediction returns a specific prediction by ID
+func (h *Handler) GetPrediction(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prediction ID"})
 		return
 	}
 
-	// Check if market is open for predictions
-	if market.Status != models.MarketOpen {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Market is not open for predictions"})
+	prediction, err := h.Predictions.GetPrediction(id, int(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Prediction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prediction"})
 		return
 	}
 
-	// Check if user already made a prediction for this market
-	var existingPrediction models.Prediction
-	result := database.DB.Where("market_id = ? AND user_id = ?", marketID, userID).First(&existingPrediction)
+	c.JSON(http.StatusOK, gin.H{"prediction": prediction})
+}
 
-	// Parse input
-	var input CreatePredictionInput
+// CreatePrediction creates a new prediction post
+func (h *Handler) CreatePrediction(c *gin.Context) {
+	var input models.PredictionRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// If user already made a prediction, update it
-	if result.RowsAffected > 0 {
-		existingPrediction.Prediction = input.Prediction
-		existingPrediction.Confidence = input.Confidence
-		existingPrediction.UpdatedAt = time.Now()
-
-		if result := database.DB.Save(&existingPrediction); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prediction"})
-			return
-		}
-
-		c.JSON(http.StatusOK, gin.H{
-			"message":    "Prediction updated successfully",
-			"prediction": existingPrediction,
-		})
-		return
+	prediction := models.Prediction{
+		UserID:      int(currentUserID(c)),
+		Title:       input.Title,
+		Description: input.Description,
+		Category:    input.Category,
+		EndDate:     input.EndDate,
+		HotScore:    services.HotScore(services.TrendingWeightCreated, time.Now()),
+		CreatedAt:   time.Now(),
 	}
 
-	// Create new prediction
-	marketIDUint, _ := strconv.ParseUint(marketID, 10, 32)
-	// Adjust field names and types to match models.Prediction struct definition
-	prediction := models.Prediction{
-		UserID:     uint(userID.(int)), // or userID.(uint) if userID is already uint
-		MarketID:   uint(marketIDUint),
-		IsTrue:     input.Prediction, // Replace with the correct boolean field name
-		Confidence: input.Confidence, // Replace with the correct confidence field name
-		CreatedAt:  time.Now(),
-		ModifiedAt: time.Now(), // Replace with the correct updated/modified field name
+	if err := prediction.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if result := database.DB.Create(&prediction); result.Error != nil {
+	if err := h.Predictions.CreatePrediction(&prediction); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prediction"})
 		return
 	}
+	prediction.Status = prediction.StatusAt(time.Now())
+	h.Webhooks.DispatchWebhookEvent(models.WebhookPredictionCreated, uint(prediction.UserID), prediction)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":    "Prediction created successfully",
@@ -129,54 +139,132 @@ func CreatePrediction(c *gin.Context) {
 	})
 }
 
-// UpdatePrediction updates an existing prediction
-func UpdatePrediction(c *gin.Context) {
-	id := c.Param("id")
-	userID, _ := c.Get("userID")
+// UpdatePrediction updates a prediction post that has not yet ended
+func (h *Handler) UpdatePrediction(c *gin.Context) {
+	prediction, ok := h.loadOwnPrediction(c, "update")
+	if !ok {
+		return
+	}
 
-	var prediction models.Prediction
-	if result := database.DB.First(&prediction, id); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Prediction not found"})
+	if prediction.Status != models.PredictionPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending predictions can be updated"})
 		return
 	}
 
-	// Check if the prediction belongs to the user
-	if prediction.UserID != userID.(uint) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own predictions"})
+	var input UpdatePredictionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update fields if provided
+	if input.Title != "" {
+		prediction.Title = input.Title
+	}
+	if input.Description != "" {
+		prediction.Description = input.Description
+	}
+	if input.Category != "" {
+		prediction.Category = input.Category
+	}
+	if !input.EndDate.IsZero() {
+		prediction.EndDate = input.EndDate
+	}
+
+	if err := prediction.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get the associated market
-	var market models.Market
-	if result := database.DB.First(&market, prediction.MarketID); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Market not found"})
+	if err := h.Predictions.UpdatePrediction(prediction); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prediction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Prediction updated successfully",
+		"prediction": prediction,
+	})
+}
+
+// DeletePrediction deletes a prediction post along with its votes and result
+func (h *Handler) DeletePrediction(c *gin.Context) {
+	prediction, ok := h.loadOwnPrediction(c, "delete")
+	if !ok {
 		return
 	}
 
-	// Check if market is still open
-	if market.Status != models.MarketOpen {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Market is not open for predictions"})
+	if err := h.Predictions.DeletePrediction(prediction.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete prediction"})
 		return
 	}
 
-	var input UpdatePredictionInput
+	c.JSON(http.StatusOK, gin.H{"message": "Prediction deleted successfully"})
+}
+
+// ResolvePrediction records whether an ended prediction came true
+func (h *Handler) ResolvePrediction(c *gin.Context) {
+	prediction, ok := h.loadOwnPrediction(c, "resolve")
+	if !ok {
+		return
+	}
+
+	switch prediction.Status {
+	case models.PredictionPending:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prediction has not ended yet"})
+		return
+	case models.PredictionResolved:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prediction is already resolved"})
+		return
+	}
+
+	var input models.ResultRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update fields
-	prediction.Prediction = input.Prediction
-	prediction.Confidence = input.Confidence
-	prediction.UpdatedAt = time.Now()
-
-	if result := database.DB.Save(&prediction); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prediction"})
+	result := models.Result{
+		Outcome:     input.Outcome,
+		EvidenceURL: input.EvidenceURL,
+	}
+	if err := h.Predictions.ResolvePrediction(prediction, &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve prediction"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Prediction updated successfully",
+		"message":    "Prediction resolved successfully",
 		"prediction": prediction,
+		"result":     result,
 	})
 }
+
+// loadOwnPrediction loads the prediction named in the URL and checks the current user wrote it.
+// It writes the error response itself and reports whether the handler should continue.
+func (h *Handler) loadOwnPrediction(c *gin.Context, action string) (*models.Prediction, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prediction ID"})
+		return nil, false
+	}
+
+	userID := int(currentUserID(c))
+	prediction, err := h.Predictions.GetPrediction(id, userID)
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Prediction not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prediction"})
+		return nil, false
+	}
+
+	// Check if the prediction belongs to the user
+	if prediction.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only " + action + " your own predictions"})
+		return nil, false
+	}
+
+	return prediction, true
+}
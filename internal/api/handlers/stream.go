@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStreamMarkets caps how many markets one stream can subscribe to
+const maxStreamMarkets = 50
+
+// StreamHeartbeat is how often an idle stream sends a comment line to keep proxies from closing it
+var StreamHeartbeat = time.Duration(utils.GetEnvInt("STREAM_HEARTBEAT_SECONDS", 15)) * time.Second
+
+// Stream sends the current user's notifications and live updates for the markets listed in
+// the markets query parameter as Server-Sent Events.
+// Clients resume after a disconnect by sending the Last-Event-ID header (or last_event_id parameter).
+func (h *Handler) Stream(c *gin.Context) {
+	userID := currentUserID(c)
+
+	marketIDs, err := parseStreamMarkets(c.Query("markets"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Live probabilities would reveal the crowd on markets whose forecasts are hidden from the user
+	visibleIDs := make([]uint, 0, len(marketIDs))
+	for _, id := range marketIDs {
+		market, err := h.Markets.GetMarket(id)
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Market %d not found", id)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open stream"})
+			return
+		}
+		visible, err := h.Forecasts.ForecastsVisible(market, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open stream"})
+			return
+		}
+		if visible {
+			visibleIDs = append(visibleIDs, id)
+		}
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	subscriber, replay := services.Stream.Subscribe(int(userID), visibleIDs, lastEventID)
+	defer services.Stream.Unsubscribe(subscriber)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range replay {
+		if err := writeStreamEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case event, ok := <-subscriber.Events():
+			if !ok {
+				// The hub dropped a client that fell behind; it reconnects with Last-Event-ID
+				return
+			}
+			if err := writeStreamEvent(c.Writer, event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// CreateStreamTicket issues a short-lived ticket for opening the stream from a browser, whose
+// EventSource cannot send the Authorization header. Clients request a new ticket for every
+// connection, passing last_event_id to resume where the previous one stopped.
+func (h *Handler) CreateStreamTicket(c *gin.Context) {
+	ticket, expiresAt, err := utils.GenerateStreamTicket(int(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stream ticket"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
+// writeStreamEvent writes one event in the text/event-stream format
+func writeStreamEvent(w io.Writer, event services.StreamEvent) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", services.Stream.EventID(event), event.Type, event.Data)
+	return err
+}
+
+// parseStreamMarkets parses a comma-separated list of market IDs
+func parseStreamMarkets(value string) ([]uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) > maxStreamMarkets {
+		return nil, fmt.Errorf("at most %d markets can be streamed at once", maxStreamMarkets)
+	}
+
+	ids := make([]uint, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid market ID %q", part)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type InviteTeamMemberInput struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+type UpdateTeamMemberInput struct {
+	Role models.TeamRole `json:"role" binding:"required"`
+}
+
+// GetTeams returns all teams by name with pagination
+func (h *Handler) GetTeams(c *gin.Context) {
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+
+	teams, total, err := h.Teams.ListTeams(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve teams"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"teams": teams,
+		"meta": gin.H{
+			"total": total,
+			"page":  page,
+			"limit": limit,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetTeam returns a team with its members and the current user's role in it
+func (h *Handler) GetTeam(c *gin.Context) {
+	team, ok := h.loadTeam(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.Teams.ListTeamMembers(team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+
+	var role models.TeamRole
+	for _, member := range members {
+		if member.UserID == currentUserID(c) {
+			role = member.Role
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"team":    team,
+		"members": members,
+		"my_role": role,
+	})
+}
+
+// CreateTeam creates a team owned by the current user
+func (h *Handler) CreateTeam(c *gin.Context) {
+	var input models.TeamRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := h.Teams.CreateTeam(&input, currentUserID(c))
+	if errors.Is(err, services.ErrTeamNameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Team created successfully",
+		"team":    team,
+	})
+}
+
+// GetTeamStats returns a team's score totals and each member's contribution
+func (h *Handler) GetTeamStats(c *gin.Context) {
+	team, ok := h.loadTeam(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.Teams.GetTeamStats(team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": team, "stats": stats})
+}
+
+// GetTeamForecast returns the team's aggregate forecast on a market.
+// Like the crowd aggregate, it is hidden until the user may see the market's forecasts.
+func (h *Handler) GetTeamForecast(c *gin.Context) {
+	team, ok := h.loadTeam(c)
+	if !ok {
+		return
+	}
+
+	marketID, err := strconv.ParseUint(c.Param("marketId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid market ID"})
+		return
+	}
+	market, err := h.Markets.GetMarket(uint(marketID))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Market not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve market"})
+		return
+	}
+
+	visible, err := h.Forecasts.ForecastsVisible(market, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team forecast"})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusOK, gin.H{"team_id": team.ID, "market_id": market.ID, "hidden": true})
+		return
+	}
+
+	forecast, err := h.Teams.GetTeamForecast(team.ID, market.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team forecast"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team_id": team.ID, "forecast": forecast, "hidden": false})
+}
+
+// InviteTeamMember invites a user to the team; only the owner and admins can invite
+func (h *Handler) InviteTeamMember(c *gin.Context) {
+	team, ok := h.loadTeam(c)
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+
+	role, err := h.Teams.TeamRoleOf(team.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+	if !role.CanManageMembers() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only team owners and admins can invite members"})
+		return
+	}
+
+	var input InviteTeamMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitee, err := h.Users.GetUser(input.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	invite, err := h.Teams.InviteToTeam(team, userID, invitee.ID)
+	if errors.Is(err, services.ErrAlreadyTeamMember) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitation sent successfully",
+		"invite":  invite,
+	})
+}
+
+// GetTeamInvites returns the current user's pending team invitations
+func (h *Handler) GetTeamInvites(c *gin.Context) {
+	invites, err := h.Teams.ListTeamInvites(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
+}
+
+// AcceptTeamInvite joins the team the current user was invited to
+func (h *Handler) AcceptTeamInvite(c *gin.Context) {
+	h.respondToTeamInvite(c, true)
+}
+
+// DeclineTeamInvite turns down a team invitation
+func (h *Handler) DeclineTeamInvite(c *gin.Context) {
+	h.respondToTeamInvite(c, false)
+}
+
+// UpdateTeamMember changes a member's role; only the owner can change roles.
+// Giving someone the owner role hands ownership over and makes the previous owner an admin.
+func (h *Handler) UpdateTeamMember(c *gin.Context) {
+	team, ok := h.loadTeam(c)
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+
+	memberID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, err := h.Teams.TeamRoleOf(team.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	if role != models.TeamOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the team owner can change roles"})
+		return
+	}
+
+	var input UpdateTeamMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.Role.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of owner, admin or member"})
+		return
+	}
+
+	err = h.Teams.SetTeamRole(team.ID, userID, uint(memberID), input.Role)
+	switch {
+	case errors.Is(err, utils.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	case errors.Is(err, services.ErrTeamOwnerCannotLeave):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member updated successfully"})
+}
+
+// RemoveTeamMember removes a member from the team. Members can remove themselves;
+// the owner and admins can remove anyone but the owner.
+func (h *Handler) RemoveTeamMember(c *gin.Context) {
+	team, ok := h.loadTeam(c)
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+
+	memberID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if uint(memberID) != userID {
+		role, err := h.Teams.TeamRoleOf(team.ID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+			return
+		}
+		if !role.CanManageMembers() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only team owners and admins can remove other members"})
+			return
+		}
+	}
+
+	err = h.Teams.RemoveTeamMember(team.ID, uint(memberID))
+	switch {
+	case errors.Is(err, utils.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	case errors.Is(err, services.ErrTeamOwnerCannotLeave):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// GetTeamLeaderboard returns teams ranked by their members' scores (rank=total|average)
+func (h *Handler) GetTeamLeaderboard(c *gin.Context) {
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+
+	ranking, err := services.ParseTeamRanking(c.Query("rank"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, total, err := h.Teams.GetTeamLeaderboard(ranking, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leaderboard": entries,
+		"meta": gin.H{
+			"total": total,
+			"page":  page,
+			"limit": limit,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+			"rank":  ranking,
+		},
+	})
+}
+
+// respondToTeamInvite accepts or declines the invitation in the URL
+func (h *Handler) respondToTeamInvite(c *gin.Context, accept bool) {
+	inviteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	invite, err := h.Teams.RespondToTeamInvite(uint(inviteID), currentUserID(c), accept)
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to respond to invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invite": invite})
+}
+
+// loadTeam loads the team in the URL, writing a 404 if it does not exist
+func (h *Handler) loadTeam(c *gin.Context) (*models.Team, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return nil, false
+	}
+
+	team, err := h.Teams.GetTeam(uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team"})
+		return nil, false
+	}
+	return team, true
+}
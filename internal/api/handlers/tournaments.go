@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTournaments returns tournaments, latest first, optionally filtered by status
+func (h *Handler) GetTournaments(c *gin.Context) {
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+
+	status := models.TournamentStatus(c.Query("status"))
+	switch status {
+	case "", models.TournamentUpcoming, models.TournamentActive, models.TournamentFinished:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of upcoming, active or finished"})
+		return
+	}
+
+	tournaments, total, err := h.Tournaments.ListTournaments(status, time.Now(), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tournaments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tournaments": tournaments,
+		"meta": gin.H{
+			"total": total,
+			"page":  page,
+			"limit": limit,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetTournament returns a tournament with its markets, prizes and, once finished, its awards
+func (h *Handler) GetTournament(c *gin.Context) {
+	userID := currentUserID(c)
+
+	tournament, ok := h.loadTournament(c)
+	if !ok {
+		return
+	}
+
+	entrants, err := h.Tournaments.CountTournamentEntrants(tournament.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tournament entrants"})
+		return
+	}
+
+	joined, err := h.Tournaments.HasJoinedTournament(tournament.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tournament entrants"})
+		return
+	}
+
+	awards, err := h.Tournaments.ListTournamentAwards(tournament.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tournament awards"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tournament": tournament,
+		"status":     tournament.Status(time.Now()),
+		"entrants":   entrants,
+		"joined":     joined,
+		"awards":     awards,
+	})
+}
+
+// CreateTournament creates a tournament over a set of markets; only admins can run tournaments
+func (h *Handler) CreateTournament(c *gin.Context) {
+	userID := currentUserID(c)
+
+	user, err := h.Users.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create tournaments"})
+		return
+	}
+
+	var input models.TournamentRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tournament, err := h.Tournaments.CreateTournament(&input, userID)
+	if errors.Is(err, services.ErrUnknownTournamentMarkets) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tournament"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Tournament created successfully",
+		"tournament": tournament,
+	})
+}
+
+// JoinTournament signs the current user up for a tournament that requires sign-up
+func (h *Handler) JoinTournament(c *gin.Context) {
+	tournament, ok := h.loadTournament(c)
+	if !ok {
+		return
+	}
+
+	err := h.Tournaments.JoinTournament(tournament, currentUserID(c), time.Now())
+	if errors.Is(err, services.ErrSignupNotRequired) || errors.Is(err, services.ErrTournamentFinished) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join tournament"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Joined tournament successfully"})
+}
+
+// GetTournamentStandings returns a page of the tournament's leaderboard and the current user's place in it
+func (h *Handler) GetTournamentStandings(c *gin.Context) {
+	userID := currentUserID(c)
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+
+	tournament, ok := h.loadTournament(c)
+	if !ok {
+		return
+	}
+
+	standings, err := h.Tournaments.TournamentStandings(tournament)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve standings"})
+		return
+	}
+
+	var me *models.LeaderboardEntry
+	for i := range standings {
+		if standings[i].UserID == userID {
+			me = &standings[i]
+			break
+		}
+	}
+
+	total := int64(len(standings))
+	start := (page - 1) * limit
+	if start > len(standings) {
+		start = len(standings)
+	}
+	end := start + limit
+	if end > len(standings) {
+		end = len(standings)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"standings": standings[start:end],
+		"me":        me,
+		"meta": gin.H{
+			"total":        total,
+			"page":         page,
+			"limit":        limit,
+			"pages":        (total + int64(limit) - 1) / int64(limit),
+			"scoring_rule": tournament.ScoringRule,
+		},
+	})
+}
+
+// loadTournament loads the tournament in the URL, writing a 404 if it does not exist
+func (h *Handler) loadTournament(c *gin.Context) (*models.Tournament, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return nil, false
+	}
+
+	tournament, err := h.Tournaments.GetTournament(uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tournament"})
+		return nil, false
+	}
+	return tournament, true
+}
@@ -1,52 +1,81 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
-	"github.com/domolitom/reThink/internal/database"
 	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/internal/store"
+	"github.com/domolitom/reThink/utils"
 	"github.com/gin-gonic/gin"
 )
 
 // GetCurrentUser returns the currently authenticated user
-func GetCurrentUser(c *gin.Context) {
-	userID, _ := c.Get("userID")
+func (h *Handler) GetCurrentUser(c *gin.Context) {
+	userID := currentUserID(c)
 
-	var user models.User
-	if result := database.DB.First(&user, userID); result.Error != nil {
+	user, err := h.Users.GetUser(userID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Don't expose password hash
-	user.PasswordHash = ""
+	counts, err := h.Follows.GetFollowCounts(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve follow counts"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	c.JSON(http.StatusOK, gin.H{
+		"user":            user,
+		"followers_count": counts.Followers,
+		"following_count": counts.Following,
+	})
 }
 
 // GetUser returns a specific user by ID
-func GetUser(c *gin.Context) {
-	id := c.Param("id")
+func (h *Handler) GetUser(c *gin.Context) {
+	user, ok := h.loadUser(c, "id")
+	if !ok {
+		return
+	}
 
-	var user models.User
-	if result := database.DB.First(&user, id); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	counts, err := h.Follows.GetFollowCounts(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve follow counts"})
 		return
 	}
 
-	// Don't expose password hash
-	user.PasswordHash = ""
+	following, err := h.Follows.IsFollowing(currentUserID(c), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve follow counts"})
+		return
+	}
+
+	achievements, err := h.Achievements.UserAchievements(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve achievements"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	c.JSON(http.StatusOK, gin.H{
+		"user":            user,
+		"followers_count": counts.Followers,
+		"following_count": counts.Following,
+		"is_following":    following,
+		"achievements":    achievements,
+	})
 }
 
 // UpdateCurrentUser updates the current user's profile
-func UpdateCurrentUser(c *gin.Context) {
-	userID, _ := c.Get("userID")
+func (h *Handler) UpdateCurrentUser(c *gin.Context) {
+	userID := currentUserID(c)
 
-	var user models.User
-	if result := database.DB.First(&user, userID); result.Error != nil {
+	user, err := h.Users.GetUser(userID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -55,6 +84,7 @@ func UpdateCurrentUser(c *gin.Context) {
 	type UpdateUserInput struct {
 		Bio      string `json:"bio"`
 		Username string `json:"username"`
+		TimeZone string `json:"time_zone"`
 	}
 
 	var input UpdateUserInput
@@ -63,13 +93,8 @@ func UpdateCurrentUser(c *gin.Context) {
 		return
 	}
 
-	// Check if new username is available if it's being changed
-	if input.Username != "" && input.Username != user.Username {
-		var existingUser models.User
-		if result := database.DB.Where("username = ?", input.Username).First(&existingUser); result.RowsAffected > 0 {
-			c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
-			return
-		}
+	// The store checks the new username is available when saving
+	if input.Username != "" {
 		user.Username = input.Username
 	}
 
@@ -78,14 +103,24 @@ func UpdateCurrentUser(c *gin.Context) {
 		user.Bio = input.Bio
 	}
 
-	if result := database.DB.Save(&user); result.Error != nil {
+	// Update time zone if provided; it decides where the user's days, and so streaks, begin
+	if input.TimeZone != "" {
+		if err := models.ValidateTimeZone(input.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user.TimeZone = input.TimeZone
+	}
+
+	if err := h.Users.UpdateUser(user); err != nil {
+		if errors.Is(err, store.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
 
-	// Don't expose password hash
-	user.PasswordHash = ""
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User updated successfully",
 		"user":    user,
@@ -93,95 +128,186 @@ func UpdateCurrentUser(c *gin.Context) {
 }
 
 // GetUserStats returns prediction statistics for a user
-func GetUserStats(c *gin.Context) {
-	id := c.Param("id")
-
-	var user models.User
-	if result := database.DB.First(&user, id); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+func (h *Handler) GetUserStats(c *gin.Context) {
+	user, ok := h.loadUser(c, "id")
+	if !ok {
 		return
 	}
 
-	// Get total number of predictions
-	var totalPredictions int64
-	database.DB.Model(&models.Prediction{}).Where("user_id = ?", id).Count(&totalPredictions)
-
-	// Get number of predictions on resolved markets
-	var resolvedPredictions []models.Prediction
-	database.DB.Joins("JOIN markets ON predictions.market_id = markets.id").
-		Where("predictions.user_id = ? AND markets.status = ?", id, models.MarketResolved).
-		Find(&resolvedPredictions)
-
-	// Calculate correct predictions
-	correctPredictions := 0
-	for _, pred := range resolvedPredictions {
-		var market models.Market
-		database.DB.First(&market, pred.MarketID)
-
-		if market.Outcome != nil && pred.Prediction == *market.Outcome {
-			correctPredictions++
-		}
+	stats, err := h.Stats.GetUserStats(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user stats"})
+		return
 	}
 
-	// Calculate accuracy if there are resolved predictions
-	var accuracy float64
-	if len(resolvedPredictions) > 0 {
-		accuracy = float64(correctPredictions) / float64(len(resolvedPredictions)) * 100
+	achievements, err := h.Achievements.UserAchievements(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve achievements"})
+		return
 	}
 
-	// Get recent predictions
-	var recentPredictions []models.Prediction
-	database.DB.Where("user_id = ?", id).
-		Preload("Market").
-		Order("created_at desc").
-		Limit(5).
-		Find(&recentPredictions)
+	// Recent predictions leave out forecasts the viewer is not allowed to see yet
+	recentPredictions, err := h.Forecasts.RecentForecasts(user.ID, currentUserID(c), 5)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve recent predictions"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"stats": gin.H{
-			"total_predictions":    totalPredictions,
-			"resolved_predictions": len(resolvedPredictions),
-			"correct_predictions":  correctPredictions,
-			"accuracy":             accuracy,
+			"total_predictions":    stats.TotalPredictions,
+			"resolved_predictions": stats.ResolvedPredictions,
+			"correct_predictions":  stats.CorrectPredictions,
+			"accuracy":             stats.Accuracy(),
 			"prediction_score":     user.PredictionScore,
+			"current_streak":       stats.CurrentStreak,
+			"longest_streak":       stats.LongestStreak,
+			"day_streak":           stats.ActiveDayStreak(models.LocalDay(time.Now(), user.Location())),
+			"longest_day_streak":   stats.LongestDayStreak,
 			"recent_predictions":   recentPredictions,
+			"achievements":         achievements,
 		},
 	})
 }
 
-// GetLeaderboard returns top users by prediction score
-func GetLeaderboard(c *gin.Context) {
-	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset := (page - 1) * limit
+// GetUserCalibration returns a calibration report of the user's resolved predictions
+func (h *Handler) GetUserCalibration(c *gin.Context) {
+	user, ok := h.loadUser(c, "id")
+	if !ok {
+		return
+	}
+
+	buckets, err := strconv.Atoi(c.DefaultQuery("buckets", strconv.Itoa(services.DefaultCalibrationBuckets)))
+	if err != nil || buckets < 1 || buckets > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "buckets must be between 1 and 20"})
+		return
+	}
+
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := models.CalibrationFilter{
+		Category: c.Query("category"),
+		From:     from,
+		To:       to,
+	}
+	samples, err := h.Stats.LoadCalibrationSamples(user.ID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve predictions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calibration": services.ComputeCalibration(samples, buckets)})
+}
+
+// CompareUsers compares two forecasters over the markets they both forecast on
+func (h *Handler) CompareUsers(c *gin.Context) {
+	user, ok := h.loadUser(c, "id")
+	if !ok {
+		return
+	}
+	other, ok := h.loadUser(c, "otherId")
+	if !ok {
+		return
+	}
+
+	if user.ID == other.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot compare a user with themselves"})
+		return
+	}
+
+	comparison, err := h.Stats.CompareForecasters(user.ID, other.ID, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":       user,
+		"other":      other,
+		"comparison": comparison,
+	})
+}
+
+// GetLeaderboard returns users ranked by prediction score, optionally limited to a
+// time window (window=7d|30d|90d|month with month=YYYY-MM) and a market category.
+// mode=adjusted ranks by a shrunk average score instead of the raw total.
+func (h *Handler) GetLeaderboard(c *gin.Context) {
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
 
-	var users []models.User
-	var total int64
+	from, to, err := services.ParseLeaderboardWindow(c.Query("window"), c.Query("month"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Count total users
-	database.DB.Model(&models.User{}).Count(&total)
+	mode, err := services.ParseLeaderboardMode(c.Query("mode"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Get top users by prediction score
-	result := database.DB.Model(&models.User{}).
-		Select("id, username, bio, prediction_score, created_at, updated_at").
-		Order("prediction_score desc").
-		Limit(limit).
-		Offset(offset).
-		Find(&users)
+	// Adjusted rankings leave out users with too few forecasts unless asked otherwise
+	minPredictions := 0
+	if mode == models.LeaderboardAdjusted {
+		minPredictions = services.LeaderboardMinPredictions
+	}
+	if value := c.Query("min_predictions"); value != "" {
+		if minPredictions, err = strconv.Atoi(value); err != nil || minPredictions < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_predictions must be a non-negative integer"})
+			return
+		}
+	}
 
-	if result.Error != nil {
+	filter := models.LeaderboardFilter{
+		From:           from,
+		To:             to,
+		Category:       c.Query("category"),
+		Mode:           mode,
+		MinPredictions: minPredictions,
+	}
+
+	entries, total, me, err := h.Stats.GetLeaderboard(filter, page, limit, currentUserID(c))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve leaderboard"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"leaderboard": users,
+		"leaderboard": entries,
+		"me":          me,
 		"meta": gin.H{
-			"total": total,
-			"page":  page,
-			"limit": limit,
-			"pages": (total + int64(limit) - 1) / int64(limit),
+			"total":           total,
+			"page":            page,
+			"limit":           limit,
+			"pages":           (total + int64(limit) - 1) / int64(limit),
+			"window":          c.DefaultQuery("window", "all"),
+			"category":        filter.Category,
+			"mode":            mode,
+			"min_predictions": minPredictions,
 		},
 	})
 }
+
+// loadUser loads the user whose ID is in the named URL parameter, writing a 404 if it does not exist
+func (h *Handler) loadUser(c *gin.Context, param string) (*models.User, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+
+	user, err := h.Users.GetUser(uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return nil, false
+	}
+	return user, true
+}
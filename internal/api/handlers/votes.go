@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// VotePrediction records the current user's agree/disagree vote on a prediction
+func (h *Handler) VotePrediction(c *gin.Context) {
+	predictionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prediction ID"})
+		return
+	}
+
+	var input models.VoteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vote := models.Vote{
+		UserID:       int(currentUserID(c)),
+		PredictionID: predictionID,
+		Value:        input.Value,
+	}
+
+	if err := vote.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Votes.VotePrediction(&vote); err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Prediction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vote recorded successfully",
+		"vote":    vote,
+	})
+}
+
+// RemoveVote deletes the current user's vote on a prediction
+func (h *Handler) RemoveVote(c *gin.Context) {
+	predictionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prediction ID"})
+		return
+	}
+
+	prediction, err := h.Votes.RemoveVote(int(currentUserID(c)), predictionID)
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vote not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Vote removed successfully",
+		"prediction": prediction,
+	})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhooks returns the current user's webhooks
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.Webhooks.ListWebhooks(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+// CreateWebhook subscribes a URL to events. The signing secret is only returned here.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	userID := currentUserID(c)
+
+	var input models.WebhookRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.CheckWebhookURL(input.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only admins may receive events about everyone's markets and predictions
+	if input.Global {
+		user, err := h.Users.GetUser(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create global webhooks"})
+			return
+		}
+	}
+
+	secret, err := services.NewWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	webhook := models.Webhook{
+		UserID:    userID,
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    input.Events,
+		Global:    input.Global,
+		Active:    true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := h.Webhooks.CreateWebhook(&webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully",
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+// DeleteWebhook deletes one of the current user's webhooks and its delivery log
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	webhook, ok := h.loadOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Webhooks.DeleteWebhook(webhook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns a webhook's delivery log, newest first, optionally filtered by status
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := h.loadOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, limit := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+
+	status := models.WebhookDeliveryStatus(c.Query("status"))
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, succeeded or failed"})
+		return
+	}
+
+	deliveries, total, err := h.Webhooks.ListWebhookDeliveries(webhook.ID, status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"meta": gin.H{
+			"total": total,
+			"page":  page,
+			"limit": limit,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetWebhookDelivery returns one delivery with the log of its attempts
+func (h *Handler) GetWebhookDelivery(c *gin.Context) {
+	delivery, ok := h.loadOwnDelivery(c)
+	if !ok {
+		return
+	}
+
+	attempts, err := h.Webhooks.ListWebhookAttempts(delivery.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve delivery attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivery": delivery,
+		"attempts": attempts,
+	})
+}
+
+// ReplayWebhookDelivery sends a delivery again, whatever its current status
+func (h *Handler) ReplayWebhookDelivery(c *gin.Context) {
+	delivery, ok := h.loadOwnDelivery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Webhooks.ReplayWebhookDelivery(delivery); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay delivery"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Delivery queued for replay",
+		"delivery": delivery,
+	})
+}
+
+// loadOwnWebhook loads the webhook named in the URL and checks the current user owns it.
+// It writes the error response itself and reports whether the handler should continue.
+func (h *Handler) loadOwnWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+
+	webhook, err := h.Webhooks.GetWebhook(uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook"})
+		return nil, false
+	}
+
+	// Other users' webhooks are reported as missing so their existence is not revealed
+	if webhook.UserID != currentUserID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// loadOwnDelivery loads the delivery named in the URL from one of the current user's webhooks
+func (h *Handler) loadOwnDelivery(c *gin.Context) (*models.WebhookDelivery, bool) {
+	webhook, ok := h.loadOwnWebhook(c)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return nil, false
+	}
+
+	delivery, err := h.Webhooks.GetWebhookDelivery(webhook.ID, uint(id))
+	if errors.Is(err, utils.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve delivery"})
+		return nil, false
+	}
+	return delivery, true
+}
@@ -39,3 +39,30 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// StreamAuthMiddleware authenticates streaming requests. Browsers' EventSource cannot set
+// headers, so besides the Authorization header it accepts a short-lived stream ticket in the
+// ticket query parameter. Session tokens are never accepted in the URL, where logs would keep them.
+func StreamAuthMiddleware() gin.HandlerFunc {
+	auth := AuthMiddleware()
+
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" || c.GetHeader("Authorization") != "" {
+			auth(c)
+			return
+		}
+
+		// Verify the ticket
+		userID, err := utils.VerifyStreamTicket(ticket)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket"})
+			c.Abort()
+			return
+		}
+
+		// Set the user ID in the context
+		c.Set("userID", userID)
+		c.Next()
+	}
+}
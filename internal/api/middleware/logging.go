@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"strconv"
@@ -9,6 +12,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// redactedParams are query parameters that carry credentials and must not reach access logs
+var redactedParams = []string{"ticket", "token"}
+
 // LoggingMiddleware logs HTTP requests
 func LoggingMiddleware(logger *utils.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -33,3 +39,45 @@ func LoggingMiddleware(logger *utils.Logger) gin.HandlerFunc {
 		)
 	}
 }
+
+// RequestLogger logs requests in the format of gin's default logger, with credentials
+// in the query string redacted
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			RedactPath(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// RedactPath replaces the values of credential query parameters in a request path
+func RedactPath(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Do not risk logging what could not be parsed
+		return base + "?REDACTED"
+	}
+
+	redacted := false
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
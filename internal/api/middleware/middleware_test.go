@@ -98,3 +98,55 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/stream", StreamAuthMiddleware(), func(c *gin.Context) {
+		userID, _ := c.Get("userID")
+		c.JSON(http.StatusOK, gin.H{"user_id": userID})
+	})
+
+	token, err := utils.GenerateToken(42)
+	assert.NoError(t, err)
+	ticket, _, err := utils.GenerateStreamTicket(42)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		url            string
+		header         string
+		expectedStatus int
+	}{
+		{"Query Ticket", "/stream?ticket=" + ticket, "", http.StatusOK},
+		{"Header Token", "/stream", "Bearer " + token, http.StatusOK},
+		{"Session Token As Ticket", "/stream?ticket=" + token, "", http.StatusUnauthorized},
+		{"Query Token", "/stream?token=" + token, "", http.StatusUnauthorized},
+		{"Ticket As Header Token", "/stream", "Bearer " + ticket, http.StatusUnauthorized},
+		{"Invalid Query Ticket", "/stream?ticket=invalid", "", http.StatusUnauthorized},
+		{"No Token", "/stream", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRedactPath(t *testing.T) {
+	assert.Equal(t, "/api/markets?page=2", RedactPath("/api/markets?page=2"))
+	assert.Equal(t, "/api/stream", RedactPath("/api/stream"))
+	assert.Equal(t, "/api/stream?markets=1%2C2&ticket=REDACTED", RedactPath("/api/stream?ticket=abc.def.ghi&markets=1,2"))
+	assert.Equal(t, "/api/notifications/unsubscribe?token=REDACTED", RedactPath("/api/notifications/unsubscribe?token=secret"))
+	assert.Equal(t, "/api/stream?REDACTED", RedactPath("/api/stream?ticket=%zz"))
+}
@@ -7,34 +7,115 @@ import (
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(r *gin.Engine) {
+func SetupRoutes(r *gin.Engine, h *handlers.Handler) {
 	// Public routes
-	r.POST("/api/auth/register", handlers.Register)
-	r.POST("/api/auth/login", handlers.Login)
+	r.POST("/api/auth/register", h.Register)
+	r.POST("/api/auth/login", h.Login)
+
+	// Real-time stream; authenticated separately because EventSource cannot send headers,
+	// by a ticket from POST /api/stream/ticket
+	r.GET("/api/stream", middleware.StreamAuthMiddleware(), h.Stream)
+
+	// Unsubscribe links from emails carry their own signed token; opening one asks for
+	// confirmation and only a POST, from that page or a mail client, unsubscribes
+	r.GET("/api/notifications/unsubscribe", h.ConfirmUnsubscribe)
+	r.POST("/api/notifications/unsubscribe", h.Unsubscribe)
 
 	// API routes with authentication
 	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(h.AuthMiddleware())
 	{
 		// User routes
-		api.GET("/users/me", handlers.GetCurrentUser)
-		api.GET("/users/:id", handlers.GetUser)
-		api.PUT("/users/me", handlers.UpdateCurrentUser)
+		api.GET("/users/me", h.GetCurrentUser)
+		api.GET("/users/:id", h.GetUser)
+		api.PUT("/users/me", h.UpdateCurrentUser)
+
+		// Follow routes
+		api.POST("/users/:id/follow", h.FollowUser)
+		api.DELETE("/users/:id/follow", h.UnfollowUser)
+		api.GET("/users/:id/followers", h.GetFollowers)
+		api.GET("/users/:id/following", h.GetFollowing)
+		api.GET("/feed", h.GetFeed)
+
+		// Stream routes
+		api.POST("/stream/ticket", h.CreateStreamTicket)
+
+		// Notification routes
+		api.GET("/notifications", h.GetNotifications)
+		api.GET("/notifications/unread-count", h.GetUnreadNotificationCount)
+		api.GET("/notifications/preferences", h.GetNotificationPreferences)
+		api.PUT("/notifications/preferences", h.UpdateNotificationPreferences)
+		api.POST("/notifications/read", h.MarkAllNotificationsRead)
+		api.POST("/notifications/:id/read", h.MarkNotificationRead)
+		api.DELETE("/notifications/:id", h.DeleteNotification)
+
+		// Webhook routes
+		api.GET("/webhooks", h.GetWebhooks)
+		api.POST("/webhooks", h.CreateWebhook)
+		api.DELETE("/webhooks/:id", h.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+		api.GET("/webhooks/:id/deliveries/:deliveryId", h.GetWebhookDelivery)
+		api.POST("/webhooks/:id/deliveries/:deliveryId/replay", h.ReplayWebhookDelivery)
 
 		// Market routes
-		api.GET("/markets", handlers.GetMarkets)
-		api.GET("/markets/:id", handlers.GetMarket)
-		api.POST("/markets", handlers.CreateMarket)
-		api.PUT("/markets/:id", handlers.UpdateMarket)
-		api.POST("/markets/:id/resolve", handlers.ResolveMarket)
+		api.GET("/markets", h.GetMarkets)
+		api.GET("/markets/:id", h.GetMarket)
+		api.GET("/markets/:id/timeseries", h.GetMarketTimeseries)
+		api.POST("/markets", h.CreateMarket)
+		api.PUT("/markets/:id", h.UpdateMarket)
+		api.POST("/markets/:id/resolve", h.ResolveMarket)
+
+		// Market prediction routes
+		api.GET("/markets/:id/predictions", h.GetMarketPredictions)
+		api.POST("/markets/:id/predict", h.CreateMarketPrediction)
+		api.PUT("/forecasts/:id", h.UpdateMarketPrediction)
+		api.DELETE("/forecasts/:id", h.WithdrawMarketPrediction)
+
+		// Prediction post routes
+		api.GET("/predictions", h.GetPredictions)
+		api.POST("/predictions", h.CreatePrediction)
+		api.GET("/predictions/:id", h.GetPrediction)
+		api.PUT("/predictions/:id", h.UpdatePrediction)
+		api.DELETE("/predictions/:id", h.DeletePrediction)
+		api.POST("/predictions/:id/result", h.ResolvePrediction)
+		api.POST("/predictions/:id/vote", h.VotePrediction)
+		api.DELETE("/predictions/:id/vote", h.RemoveVote)
+
+		// Comment routes
+		api.GET("/markets/:id/comments", h.GetMarketComments)
+		api.POST("/markets/:id/comments", h.CreateComment)
+		api.PUT("/comments/:id", h.UpdateComment)
+		api.DELETE("/comments/:id", h.DeleteComment)
+
+		// Tournament routes
+		api.GET("/tournaments", h.GetTournaments)
+		api.POST("/tournaments", h.CreateTournament)
+		api.GET("/tournaments/:id", h.GetTournament)
+		api.POST("/tournaments/:id/join", h.JoinTournament)
+		api.GET("/tournaments/:id/standings", h.GetTournamentStandings)
+
+		// Team routes
+		api.GET("/teams", h.GetTeams)
+		api.POST("/teams", h.CreateTeam)
+		api.GET("/teams/:id", h.GetTeam)
+		api.GET("/teams/:id/stats", h.GetTeamStats)
+		api.GET("/teams/:id/markets/:marketId/forecast", h.GetTeamForecast)
+		api.POST("/teams/:id/invites", h.InviteTeamMember)
+		api.PUT("/teams/:id/members/:userId", h.UpdateTeamMember)
+		api.DELETE("/teams/:id/members/:userId", h.RemoveTeamMember)
+		api.GET("/team-invites", h.GetTeamInvites)
+		api.POST("/team-invites/:id/accept", h.AcceptTeamInvite)
+		api.POST("/team-invites/:id/decline", h.DeclineTeamInvite)
+		api.GET("/leaderboard/teams", h.GetTeamLeaderboard)
 
-		// Prediction routes
-		api.GET("/markets/:id/predictions", handlers.GetMarketPredictions)
-		api.POST("/markets/:id/predict", handlers.CreatePrediction)
-		api.PUT("/predictions/:id", handlers.UpdatePrediction)
+		// Daily challenge routes
+		api.GET("/challenges/today", h.GetTodaysChallenge)
+		api.POST("/challenges", h.CreateDailyChallenge)
 
 		// Stats routes
-		api.GET("/users/:id/stats", handlers.GetUserStats)
-		api.GET("/leaderboard", handlers.GetLeaderboard)
+		api.GET("/users/:id/stats", h.GetUserStats)
+		api.GET("/users/:id/calibration", h.GetUserCalibration)
+		api.GET("/users/:id/compare/:otherId", h.CompareUsers)
+		api.GET("/leaderboard", h.GetLeaderboard)
 	}
 }
@@ -0,0 +1,302 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/domolitom/reThink/internal/api/handlers"
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/store"
+	"github.com/domolitom/reThink/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiClient sends requests to the router and records which routes answered them
+type apiClient struct {
+	t      *testing.T
+	router *gin.Engine
+
+	mu  sync.Mutex
+	hit map[string]bool
+}
+
+func newAPIClient(t *testing.T, s store.Store) *apiClient {
+	gin.SetMode(gin.TestMode)
+	client := &apiClient{t: t, router: gin.New(), hit: make(map[string]bool)}
+
+	client.router.Use(func(c *gin.Context) {
+		client.mu.Lock()
+		client.hit[c.Request.Method+" "+c.FullPath()] = true
+		client.mu.Unlock()
+		c.Next()
+	})
+	SetupRoutes(client.router, handlers.NewHandler(s))
+	return client
+}
+
+// do sends a JSON request, checks the response status and returns the decoded body
+func (a *apiClient) do(method, path, token string, payload interface{}, status int) map[string]interface{} {
+	a.t.Helper()
+
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+
+	require.Equal(a.t, status, w.Code, "%s %s: %s", method, path, w.Body.String())
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+// id reads the ID of the object under key in a response
+func id(t *testing.T, response map[string]interface{}, key string) uint {
+	t.Helper()
+	object, ok := response[key].(map[string]interface{})
+	require.True(t, ok, "response has no %q object: %v", key, response)
+	value, ok := object["id"].(float64)
+	require.True(t, ok, "%q has no ID: %v", key, object)
+	return uint(value)
+}
+
+// TestEveryRouteWithMemoryStore drives every API route against the in-memory store
+func TestEveryRouteWithMemoryStore(t *testing.T) {
+	memory := store.NewMemoryStore()
+	api := newAPIClient(t, memory)
+	now := time.Now()
+
+	// Auth and users
+	register := api.do("POST", "/api/auth/register", "", models.RegisterRequest{
+		Name: "Alice", Username: "alice", Email: "alice@example.com", Password: "password123",
+	}, http.StatusCreated)
+	aliceID := id(t, register, "user")
+	login := api.do("POST", "/api/auth/login", "", models.LoginRequest{
+		Email: "alice@example.com", Password: "password123",
+	}, http.StatusOK)
+	alice, _ := login["token"].(string)
+
+	register = api.do("POST", "/api/auth/register", "", models.RegisterRequest{
+		Name: "Bob", Username: "bob", Email: "bob@example.com", Password: "password123",
+	}, http.StatusCreated)
+	bobID := id(t, register, "user")
+	bob, _ := register["token"].(string)
+
+	admin, err := memory.GetUser(aliceID)
+	require.NoError(t, err)
+	admin.IsAdmin = true
+	require.NoError(t, memory.UpdateUser(admin))
+
+	api.do("GET", "/api/users/me", alice, nil, http.StatusOK)
+	api.do("PUT", "/api/users/me", alice, gin.H{"bio": "Forecaster", "time_zone": "Europe/Berlin"}, http.StatusOK)
+	api.do("PUT", "/api/users/me", bob, gin.H{"username": "alice"}, http.StatusConflict)
+	api.do("GET", fmt.Sprintf("/api/users/%d", bobID), alice, nil, http.StatusOK)
+
+	// Follows and feed
+	api.do("POST", fmt.Sprintf("/api/users/%d/follow", aliceID), bob, nil, http.StatusOK)
+	api.do("GET", fmt.Sprintf("/api/users/%d/followers", aliceID), bob, nil, http.StatusOK)
+	api.do("GET", fmt.Sprintf("/api/users/%d/following", bobID), bob, nil, http.StatusOK)
+
+	// Webhooks, so that the events below queue deliveries
+	api.do("POST", "/api/webhooks", alice, models.WebhookRequest{
+		URL:    "https://example.com/hooks",
+		Events: []models.WebhookEvent{models.WebhookMarketCreated, models.WebhookMarketResolved},
+	}, http.StatusCreated)
+	webhooks := api.do("GET", "/api/webhooks", alice, nil, http.StatusOK)
+	require.Len(t, webhooks["webhooks"], 1)
+	webhookID := uint(webhooks["webhooks"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+
+	// Markets
+	created := api.do("POST", "/api/markets", alice, handlers.CreateMarketInput{
+		Title:       "Will it rain tomorrow?",
+		Description: "Resolves true if it rains",
+		Category:    "Weather",
+		CloseDate:   now.AddDate(0, 0, 1),
+		ResolveDate: now.AddDate(0, 0, 2),
+	}, http.StatusCreated)
+	marketID := id(t, created, "market")
+	market := fmt.Sprintf("/api/markets/%d", marketID)
+
+	api.do("GET", "/api/markets", alice, nil, http.StatusOK)
+	api.do("GET", market, alice, nil, http.StatusOK)
+	api.do("PUT", market, alice, handlers.UpdateMarketInput{Description: "Resolves true if it rains in Berlin"}, http.StatusOK)
+	api.do("PUT", market, bob, handlers.UpdateMarketInput{Title: "Mine now"}, http.StatusForbidden)
+
+	// Forecasts
+	yes, no := true, false
+	forecast := api.do("POST", market+"/predict", bob, handlers.CreateMarketPredictionInput{Prediction: &yes, Confidence: 70}, http.StatusCreated)
+	bobForecastID := id(t, forecast, "prediction")
+	forecast = api.do("POST", market+"/predict", alice, handlers.CreateMarketPredictionInput{Prediction: &no, Confidence: 60}, http.StatusCreated)
+	aliceForecastID := id(t, forecast, "prediction")
+
+	api.do("GET", market+"/predictions", alice, nil, http.StatusOK)
+	api.do("GET", market+"/timeseries", alice, nil, http.StatusOK)
+	api.do("PUT", fmt.Sprintf("/api/forecasts/%d", bobForecastID), bob, handlers.UpdateMarketPredictionInput{Prediction: true, Confidence: 80}, http.StatusOK)
+	api.do("PUT", fmt.Sprintf("/api/forecasts/%d", bobForecastID), alice, handlers.UpdateMarketPredictionInput{Prediction: false, Confidence: 80}, http.StatusForbidden)
+	api.do("DELETE", fmt.Sprintf("/api/forecasts/%d", bobForecastID), bob, nil, http.StatusOK)
+	api.do("POST", market+"/predict", bob, handlers.CreateMarketPredictionInput{Prediction: &yes, Confidence: 80}, http.StatusOK)
+	api.do("DELETE", fmt.Sprintf("/api/forecasts/%d", aliceForecastID), alice, nil, http.StatusOK)
+	api.do("POST", market+"/predict", alice, handlers.CreateMarketPredictionInput{Prediction: &no, Confidence: 60}, http.StatusOK)
+
+	// Comments; mentioning alice notifies her
+	comment := api.do("POST", market+"/comments", bob, models.CommentRequest{Body: "What do you think, @alice?"}, http.StatusCreated)
+	commentID := id(t, comment, "comment")
+	api.do("POST", market+"/comments", alice, models.CommentRequest{Body: "Dry, I think", ParentID: &commentID}, http.StatusCreated)
+	api.do("GET", market+"/comments", alice, nil, http.StatusOK)
+	api.do("PUT", fmt.Sprintf("/api/comments/%d", commentID), bob, handlers.UpdateCommentInput{Body: "What do you think, @alice? Sunny?"}, http.StatusOK)
+	api.do("DELETE", fmt.Sprintf("/api/comments/%d", commentID), alice, nil, http.StatusForbidden)
+	api.do("DELETE", fmt.Sprintf("/api/comments/%d", commentID), bob, nil, http.StatusOK)
+	api.do("DELETE", fmt.Sprintf("/api/comments/%d", commentID), bob, nil, http.StatusNotFound)
+
+	// Prediction posts and votes
+	post := api.do("POST", "/api/predictions", bob, models.PredictionRequest{
+		Title:       "Rust overtakes Go",
+		Description: "By the end of next year",
+		Category:    "Technology",
+		EndDate:     now.AddDate(1, 0, 0),
+	}, http.StatusCreated)
+	postID := id(t, post, "prediction")
+	postPath := fmt.Sprintf("/api/predictions/%d", postID)
+
+	api.do("GET", "/api/predictions", alice, nil, http.StatusOK)
+	filtered := api.do("GET", "/api/predictions?category=Technology&status=pending", alice, nil, http.StatusOK)
+	assert.EqualValues(t, 1, filtered["meta"].(map[string]interface{})["total"])
+	api.do("GET", postPath, alice, nil, http.StatusOK)
+	api.do("PUT", postPath, bob, handlers.UpdatePredictionInput{Title: "Rust overtakes Go in surveys"}, http.StatusOK)
+	api.do("POST", postPath+"/vote", alice, models.VoteRequest{Value: true}, http.StatusOK)
+	api.do("DELETE", postPath+"/vote", alice, nil, http.StatusOK)
+	api.do("POST", postPath+"/result", bob, models.ResultRequest{Outcome: true, EvidenceURL: "https://example.com"}, http.StatusBadRequest)
+
+	// Notifications
+	notifications := api.do("GET", "/api/notifications", alice, nil, http.StatusOK)
+	require.NotEmpty(t, notifications["notifications"])
+	notificationID := uint(notifications["notifications"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+	unread := api.do("GET", "/api/notifications/unread-count", alice, nil, http.StatusOK)
+	assert.EqualValues(t, len(notifications["notifications"].([]interface{})), unread["unread"])
+	api.do("POST", fmt.Sprintf("/api/notifications/%d/read", notificationID), alice, nil, http.StatusOK)
+	api.do("POST", "/api/notifications/read", alice, nil, http.StatusOK)
+	api.do("DELETE", fmt.Sprintf("/api/notifications/%d", notificationID), alice, nil, http.StatusOK)
+	api.do("GET", "/api/notifications/preferences", alice, nil, http.StatusOK)
+	api.do("PUT", "/api/notifications/preferences", alice, models.NotificationPreferencesRequest{
+		Channels: map[models.NotificationType]models.NotificationChannel{models.NotificationMention: models.ChannelEmail},
+	}, http.StatusOK)
+
+	unsubscribe := "/api/notifications/unsubscribe?token=" + url.QueryEscape(utils.SignUnsubscribeToken(int(aliceID), string(models.NotificationMention)))
+	api.do("GET", unsubscribe, "", nil, http.StatusOK)
+	api.do("POST", unsubscribe, "", nil, http.StatusOK)
+	api.do("POST", "/api/notifications/unsubscribe?token=forged", "", nil, http.StatusBadRequest)
+
+	// Teams
+	team := api.do("POST", "/api/teams", alice, models.TeamRequest{Name: "Rainmakers"}, http.StatusCreated)
+	teamPath := fmt.Sprintf("/api/teams/%d", id(t, team, "team"))
+	api.do("POST", "/api/teams", bob, models.TeamRequest{Name: "rainmakers"}, http.StatusConflict)
+	api.do("GET", "/api/teams", alice, nil, http.StatusOK)
+
+	invite := api.do("POST", teamPath+"/invites", alice, handlers.InviteTeamMemberInput{UserID: bobID}, http.StatusCreated)
+	api.do("GET", "/api/team-invites", bob, nil, http.StatusOK)
+	api.do("POST", fmt.Sprintf("/api/team-invites/%d/accept", id(t, invite, "invite")), bob, nil, http.StatusOK)
+	api.do("GET", teamPath, bob, nil, http.StatusOK)
+	api.do("GET", teamPath+"/stats", bob, nil, http.StatusOK)
+	api.do("GET", fmt.Sprintf("%s/markets/%d/forecast", teamPath, marketID), bob, nil, http.StatusOK)
+	api.do("PUT", fmt.Sprintf("%s/members/%d", teamPath, bobID), alice, handlers.UpdateTeamMemberInput{Role: models.TeamAdmin}, http.StatusOK)
+	api.do("DELETE", fmt.Sprintf("%s/members/%d", teamPath, aliceID), bob, nil, http.StatusBadRequest)
+	api.do("DELETE", fmt.Sprintf("%s/members/%d", teamPath, bobID), bob, nil, http.StatusOK)
+
+	invite = api.do("POST", teamPath+"/invites", alice, handlers.InviteTeamMemberInput{UserID: bobID}, http.StatusCreated)
+	api.do("POST", fmt.Sprintf("/api/team-invites/%d/decline", id(t, invite, "invite")), bob, nil, http.StatusOK)
+	api.do("GET", "/api/leaderboard/teams", bob, nil, http.StatusOK)
+
+	// Tournaments
+	tournament := api.do("POST", "/api/tournaments", alice, models.TournamentRequest{
+		Name:           "Weather Week",
+		StartDate:      now.Add(-time.Hour),
+		EndDate:        now.AddDate(0, 0, 7),
+		RequiresSignup: true,
+		MarketIDs:      []uint{marketID, marketID},
+		Prizes:         []string{"Umbrella"},
+	}, http.StatusCreated)
+	tournamentPath := fmt.Sprintf("/api/tournaments/%d", id(t, tournament, "tournament"))
+	api.do("POST", "/api/tournaments", bob, models.TournamentRequest{
+		Name: "Bob's Cup", StartDate: now, EndDate: now.AddDate(0, 0, 7), MarketIDs: []uint{marketID},
+	}, http.StatusForbidden)
+	api.do("GET", "/api/tournaments?status=active", bob, nil, http.StatusOK)
+	api.do("GET", "/api/tournaments?status=someday", bob, nil, http.StatusBadRequest)
+	api.do("POST", tournamentPath+"/join", bob, nil, http.StatusOK)
+	joined := api.do("GET", tournamentPath, bob, nil, http.StatusOK)
+	assert.Equal(t, true, joined["joined"])
+	api.do("GET", tournamentPath+"/standings", bob, nil, http.StatusOK)
+
+	// Daily challenges
+	api.do("POST", "/api/challenges", alice, models.DailyChallengeRequest{
+		Day: models.LocalDay(now, time.UTC), MarketID: marketID,
+	}, http.StatusCreated)
+	api.do("POST", "/api/challenges", bob, models.DailyChallengeRequest{
+		Day: models.LocalDay(now, time.UTC), MarketID: marketID,
+	}, http.StatusForbidden)
+	challenge := api.do("GET", "/api/challenges/today", bob, nil, http.StatusOK)
+	assert.Equal(t, true, challenge["forecasted"])
+
+	// Resolution, stats and leaderboards
+	api.do("POST", market+"/resolve", bob, handlers.ResolveMarketInput{Outcome: &yes}, http.StatusForbidden)
+	api.do("POST", market+"/resolve", alice, handlers.ResolveMarketInput{Outcome: &yes}, http.StatusOK)
+	api.do("POST", market+"/resolve", alice, handlers.ResolveMarketInput{Outcome: &yes}, http.StatusBadRequest)
+
+	api.do("GET", fmt.Sprintf("/api/users/%d/stats", bobID), alice, nil, http.StatusOK)
+	api.do("GET", fmt.Sprintf("/api/users/%d/calibration", bobID), alice, nil, http.StatusOK)
+	api.do("GET", fmt.Sprintf("/api/users/%d/compare/%d", bobID, aliceID), alice, nil, http.StatusOK)
+	api.do("GET", fmt.Sprintf("/api/users/%d/compare/%d", bobID, bobID), alice, nil, http.StatusBadRequest)
+	leaderboard := api.do("GET", "/api/leaderboard", alice, nil, http.StatusOK)
+	require.NotEmpty(t, leaderboard["leaderboard"])
+	assert.EqualValues(t, bobID, leaderboard["leaderboard"].([]interface{})[0].(map[string]interface{})["user_id"])
+
+	// Feed and unfollowing, now that alice has been active
+	api.do("GET", "/api/feed", bob, nil, http.StatusOK)
+	api.do("DELETE", fmt.Sprintf("/api/users/%d/follow", aliceID), bob, nil, http.StatusOK)
+
+	// Webhook deliveries were queued for the market being created and resolved
+	webhookPath := fmt.Sprintf("/api/webhooks/%d", webhookID)
+	deliveries := api.do("GET", webhookPath+"/deliveries", alice, nil, http.StatusOK)
+	assert.EqualValues(t, 2, deliveries["meta"].(map[string]interface{})["total"])
+	deliveryID := uint(deliveries["deliveries"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+	deliveryPath := fmt.Sprintf("%s/deliveries/%d", webhookPath, deliveryID)
+	api.do("GET", deliveryPath, alice, nil, http.StatusOK)
+	api.do("GET", deliveryPath, bob, nil, http.StatusNotFound)
+	api.do("POST", deliveryPath+"/replay", alice, nil, http.StatusAccepted)
+	api.do("DELETE", webhookPath, alice, nil, http.StatusOK)
+
+	// Prediction posts can be deleted once done with
+	api.do("DELETE", postPath, alice, nil, http.StatusForbidden)
+	api.do("DELETE", postPath, bob, nil, http.StatusOK)
+	api.do("GET", postPath, alice, nil, http.StatusNotFound)
+
+	// The stream runs until its client goes away
+	ticket := api.do("POST", "/api/stream/ticket", bob, nil, http.StatusCreated)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/stream?markets=%d&ticket=%s", marketID, url.QueryEscape(ticket["ticket"].(string))), nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	for _, route := range api.router.Routes() {
+		assert.True(t, api.hit[route.Method+" "+route.Path], "route %s %s was not exercised", route.Method, route.Path)
+	}
+}
@@ -1,65 +1,158 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"strings"
+	"time"
 
 	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 // DB is the database connection
 var DB *gorm.DB
 
-// Connect establishes a connection to the database and performs migrations
-func Connect() {
-	var err error
+// Driver names the kind of database a DSN points at
+type Driver string
 
-	// Get database credentials from environment variables
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		host = "localhost"
+const (
+	Postgres Driver = "postgres"
+	SQLite   Driver = "sqlite"
+)
+
+// sqlitePrefix marks a SQLite DSN, e.g. "sqlite:rethink.db" or "sqlite::memory:"
+const sqlitePrefix = "sqlite:"
+
+// sqliteMemory is the SQLite file name of a private in-memory database
+const sqliteMemory = ":memory:"
+
+// ErrEmptySQLitePath is returned for a SQLite DSN without a file name
+var ErrEmptySQLitePath = errors.New("sqlite DSN needs a file path or :memory:")
+
+// DSN returns DATABASE_URL if it is set, and otherwise builds a Postgres connection string
+// from the DB_* environment variables
+func DSN() string {
+	if dsn := utils.GetEnvString("DATABASE_URL", ""); dsn != "" {
+		return dsn
 	}
 
-	user := os.Getenv("DB_USER")
-	if user == "" {
-		user = "postgres"
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
+		utils.GetEnvString("DB_HOST", "localhost"),
+		utils.GetEnvString("DB_USER", "postgres"),
+		utils.GetEnvString("DB_PASSWORD", "postgres"),
+		utils.GetEnvString("DB_NAME", "reThink"),
+		utils.GetEnvString("DB_PORT", "5432"))
+}
+
+// ParseDSN works out which driver a DSN is for and returns the DSN to give that driver.
+// SQLite DSNs start with "sqlite:" followed by a file path, optionally with go-sqlite3
+// query parameters, or ":memory:". Anything else is a Postgres URL or key=value string.
+func ParseDSN(dsn string) (Driver, string) {
+	if path, ok := strings.CutPrefix(dsn, sqlitePrefix); ok {
+		// Accept the URL-like "sqlite://path" form too
+		return SQLite, strings.TrimPrefix(path, "//")
 	}
+	return Postgres, dsn
+}
 
-	password := os.Getenv("DB_PASSWORD")
-	if password == "" {
-		password = "postgres"
+// Open connects to the database the DSN points at
+func Open(dsn string) (*gorm.DB, error) {
+	driver, dsn := ParseDSN(dsn)
+	if driver == Postgres {
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	}
+	return openSQLite(dsn)
+}
 
-	dbname := os.Getenv("DB_NAME")
-	if dbname == "" {
-		dbname = "reThink"
+// openSQLite opens a SQLite database. Transactions take the write lock when they begin, and
+// waiting writers retry for a while, so concurrent requests queue up instead of failing.
+// An in-memory database only exists inside its connection, so it gets exactly one.
+// Times are stored and compared in UTC whatever offset they come with; see utcConnPool.
+func openSQLite(path string) (*gorm.DB, error) {
+	if path == "" {
+		return nil, ErrEmptySQLitePath
 	}
 
-	port := os.Getenv("DB_PORT")
-	if port == "" {
-		port = "5432"
+	memory := path == sqliteMemory
+	if !strings.Contains(path, "?") {
+		path += "?_busy_timeout=5000&_txlock=immediate"
+		if !memory {
+			path += "&_journal_mode=WAL"
+		}
 	}
 
-	// Create the connection string
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-		host, user, password, dbname, port)
+	sqlDB, err := sql.Open(sqlite.DriverName, path)
+	if err != nil {
+		return nil, err
+	}
+	if memory {
+		sqlDB.SetMaxOpenConns(1)
+	}
 
-	// Connect to the database
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(sqlite.New(sqlite.Config{Conn: &utcConnPool{db: sqlDB}}), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		sqlDB.Close()
+		return nil, err
 	}
+	return db, nil
+}
 
-	log.Println("Connected to database successfully")
+// Migrate creates or updates the schema
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&models.User{},
+		&models.Market{},
+		&models.Prediction{},
+		&models.Vote{},
+		&models.Result{},
+		&models.Forecast{},
+		&models.ForecastRevision{},
+		&models.MarketSnapshot{},
+		&models.Comment{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.Follow{},
+		&models.Activity{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
+		&models.Reminder{},
+		&models.ScoreRecord{},
+		&models.UserStats{},
+		&models.Tournament{},
+		&models.TournamentPrize{},
+		&models.TournamentEntrant{},
+		&models.TournamentAward{},
+		&models.UserAchievement{},
+		&models.DailyChallenge{},
+		&models.Team{},
+		&models.TeamMember{},
+		&models.TeamInvite{},
+	)
+}
 
-	// Auto-migrate the schema
-	err = DB.AutoMigrate(&models.User{}, &models.Market{}, &models.Prediction{})
+// Connect opens the database named by DSN into DB and migrates its schema
+func Connect() error {
+	driver, _ := ParseDSN(DSN())
+	db, err := Open(DSN())
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		return fmt.Errorf("connect to database: %w", err)
 	}
+	log.Printf("Connected to %s database successfully", driver)
 
+	if err := Migrate(db); err != nil {
+		return fmt.Errorf("migrate database: %w", err)
+	}
 	log.Println("Database migration completed")
+
+	DB = db
+	return nil
 }
@@ -0,0 +1,132 @@
+// Package mailer sends email through a pluggable backend
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/domolitom/reThink/utils"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Headers holds extra headers such as List-Unsubscribe
+	Headers map[string]string
+}
+
+// Mailer sends email messages
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New returns the mailer configured by the MAILER environment variable:
+// "smtp" sends through SMTP_HOST, anything else writes messages to files in MAIL_DIR.
+func New() Mailer {
+	from := utils.GetEnvString("MAIL_FROM", "reThink <no-reply@rethink.local>")
+
+	if utils.GetEnvString("MAILER", "file") == "smtp" {
+		return &SMTPMailer{
+			Host:     utils.GetEnvString("SMTP_HOST", "localhost"),
+			Port:     utils.GetEnvInt("SMTP_PORT", 587),
+			Username: utils.GetEnvString("SMTP_USERNAME", ""),
+			Password: utils.GetEnvString("SMTP_PASSWORD", ""),
+			From:     from,
+		}
+	}
+
+	return &FileMailer{
+		Dir:  utils.GetEnvString("MAIL_DIR", "mail"),
+		From: from,
+	}
+}
+
+// FileMailer is a local stand-in for a mail server that writes each message to its own .eml file
+type FileMailer struct {
+	Dir  string
+	From string
+
+	count atomic.Uint64
+}
+
+// Send writes the message to a new file in Dir
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%d.eml", time.Now().UTC().Format("20060102T150405.000000000"), m.count.Add(1))
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, message), 0o644)
+}
+
+// SMTPMailer sends messages through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the message with net/smtp
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+	return smtp.SendMail(addr, auth, envelopeAddress(m.From), []string{message.To}, format(m.From, message))
+}
+
+// format renders a message in RFC 5322 form
+func format(from string, message Message) []byte {
+	var b strings.Builder
+
+	headers := map[string]string{
+		"From":         from,
+		"To":           message.To,
+		"Subject":      message.Subject,
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"MIME-Version": "1.0",
+		"Content-Type": "text/plain; charset=UTF-8",
+	}
+	for name, value := range message.Headers {
+		headers[name] = value
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// Header values must not break out onto new header lines
+		value := strings.NewReplacer("\r", " ", "\n", " ").Replace(headers[name])
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// envelopeAddress extracts the bare address from a "Name <address>" string
+func envelopeAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.LastIndex(from, ">"); end > start {
+			return from[start+1 : end]
+		}
+	}
+	return from
+}
@@ -0,0 +1,41 @@
+// mailer_test.go
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	m := &FileMailer{Dir: filepath.Join(t.TempDir(), "mail"), From: "reThink <no-reply@rethink.local>"}
+
+	message := Message{
+		To:      "ada@example.com",
+		Subject: "Hello\r\nBcc: someone@example.com",
+		Body:    "line one\nline two\n",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+	assert.NoError(t, m.Send(context.Background(), message))
+	assert.NoError(t, m.Send(context.Background(), message))
+
+	files, err := os.ReadDir(m.Dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	data, err := os.ReadFile(filepath.Join(m.Dir, files[0].Name()))
+	assert.NoError(t, err)
+	content := string(data)
+	assert.Contains(t, content, "To: ada@example.com\r\n")
+	assert.Contains(t, content, "Subject: Hello  Bcc: someone@example.com\r\n")
+	assert.Contains(t, content, "List-Unsubscribe: <https://example.com/unsubscribe>\r\n")
+	assert.Contains(t, content, "\r\n\r\nline one\r\nline two\r\n")
+}
+
+func TestEnvelopeAddress(t *testing.T) {
+	assert.Equal(t, "no-reply@rethink.local", envelopeAddress("reThink <no-reply@rethink.local>"))
+	assert.Equal(t, "plain@example.com", envelopeAddress("plain@example.com"))
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// AchievementKey identifies an achievement; the rules that award them are defined in services
+type AchievementKey string
+
+const (
+	AchievementFirstForecast  AchievementKey = "first_forecast"
+	AchievementHotStreak      AchievementKey = "hot_streak"
+	AchievementWellCalibrated AchievementKey = "well_calibrated"
+	AchievementMonthlyTop10   AchievementKey = "monthly_top_10"
+)
+
+// UserAchievement records a badge a user has earned. Each achievement is earned at most once.
+type UserAchievement struct {
+	ID       uint           `json:"id" db:"id"`
+	UserID   uint           `json:"user_id" db:"user_id" gorm:"uniqueIndex:idx_user_achievements_once,priority:1"`
+	Key      AchievementKey `json:"key" db:"key" gorm:"uniqueIndex:idx_user_achievements_once,priority:2"`
+	EarnedAt time.Time      `json:"earned_at" db:"earned_at"`
+}
+
+// AchievementEvent is something that happened which may earn users achievements
+type AchievementEvent string
+
+const (
+	AchievementForecastCreated AchievementEvent = "forecast_created"
+	AchievementMarketResolved  AchievementEvent = "market_resolved"
+	AchievementMonthClosed     AchievementEvent = "month_closed" // a month's leaderboard is final
+)
+
+// EarnedAchievement is an achievement a user has, with its definition, for display
+type EarnedAchievement struct {
+	Key         AchievementKey `json:"key"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	EarnedAt    time.Time      `json:"earned_at"`
+}
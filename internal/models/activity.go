@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// ActivityType defines the kind of event shown in feeds
+type ActivityType string
+
+const (
+	ActivityMarketCreated  ActivityType = "market_created"
+	ActivityForecast       ActivityType = "forecast"
+	ActivityMarketResolved ActivityType = "market_resolved"
+)
+
+// Activity is an event performed by a user, fanned out to their followers' feeds on read
+type Activity struct {
+	ID         uint         `json:"id" gorm:"primaryKey;index:idx_activities_time,priority:2"`
+	ActorID    uint         `json:"actor_id" gorm:"not null;index:idx_activities_actor_time,priority:1"`
+	Actor      User         `json:"actor" gorm:"foreignKey:ActorID"`
+	Type       ActivityType `json:"type"`
+	MarketID   uint         `json:"market_id" gorm:"index"`
+	Market     Market       `json:"market" gorm:"foreignKey:MarketID"`
+	ForecastID *uint        `json:"forecast_id"`
+	Forecast   *Forecast    `json:"forecast,omitempty" gorm:"foreignKey:ForecastID"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"index:idx_activities_actor_time,priority:2;index:idx_activities_time,priority:1"`
+}
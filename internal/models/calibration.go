@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// CalibrationSample is a resolved forecast reduced to its stated probability and the outcome
+type CalibrationSample struct {
+	Probability float64 // probability the forecaster gave to the market resolving true
+	Outcome     bool
+}
+
+// CalibrationFilter narrows the forecasts included in a calibration report
+type CalibrationFilter struct {
+	Category string
+	From     time.Time // forecasts made at or after this time
+	To       time.Time // forecasts made at or before this time
+}
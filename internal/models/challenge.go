@@ -0,0 +1,36 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// DailyChallenge is the curated market highlighted on a given day.
+// Day is a calendar date, so each user sees it for the whole of that day in their own time zone.
+type DailyChallenge struct {
+	ID        uint      `json:"id" db:"id"`
+	Day       string    `json:"day" db:"day" gorm:"uniqueIndex"` // YYYY-MM-DD
+	MarketID  uint      `json:"market_id" db:"market_id"`
+	Market    Market    `json:"market" gorm:"foreignKey:MarketID"`
+	Note      string    `json:"note" db:"note"`
+	CreatorID uint      `json:"creator_id" db:"creator_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// DailyChallengeRequest represents the data needed to schedule a daily challenge
+type DailyChallengeRequest struct {
+	Day      string `json:"day" binding:"required"`
+	MarketID uint   `json:"market_id" binding:"required"`
+	Note     string `json:"note"`
+}
+
+// Validate performs validation on the daily challenge request
+func (r *DailyChallengeRequest) Validate() error {
+	if _, err := time.Parse(DayLayout, r.Day); err != nil {
+		return errors.New("day: must be a date given as YYYY-MM-DD")
+	}
+	if len(r.Note) > 500 {
+		return errors.New("note: must be at most 500 characters")
+	}
+	return nil
+}
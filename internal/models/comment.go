@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxCommentLength is the maximum number of characters in a comment body
+const MaxCommentLength = 5000
+
+// Comment represents a comment on a market, optionally replying to another comment
+type Comment struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	MarketID  uint       `json:"market_id" gorm:"not null;index"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"user" gorm:"foreignKey:UserID"`
+	ParentID  *uint      `json:"parent_id"`              // comment being replied to
+	ThreadID  *uint      `json:"thread_id" gorm:"index"` // top-level comment of the thread
+	Body      string     `json:"body"`
+	EditedAt  *time.Time `json:"edited_at"`
+	DeletedAt *time.Time `json:"deleted_at"` // deleted comments keep their place in the thread
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Replies   []Comment  `json:"replies,omitempty" gorm:"-"`
+}
+
+// CommentRequest represents the data needed to create or edit a comment
+type CommentRequest struct {
+	Body     string `json:"body" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// Validate performs validation on the comment model
+func (c *Comment) Validate() error {
+	if c.Body == "" {
+		return errors.New("body: body is required")
+	}
+	if len(c.Body) > MaxCommentLength {
+		return errors.New("body: body must be less than 5000 characters")
+	}
+
+	return nil
+}
+
+// CanEdit reports whether the comment can still be edited at the given time
+func (c *Comment) CanEdit(now time.Time, window time.Duration) bool {
+	return c.DeletedAt == nil && now.Sub(c.CreatedAt) <= window
+}
@@ -0,0 +1,46 @@
+package models
+
+// ComparedForecast is one side of a compared market
+type ComparedForecast struct {
+	Prediction  bool     `json:"prediction"`
+	Confidence  float64  `json:"confidence"`
+	Probability float64  `json:"probability"`     // probability given to the market resolving true
+	Score       *float64 `json:"score,omitempty"` // set once the market resolves
+	Brier       *float64 `json:"brier,omitempty"` // squared error of Probability; lower is better
+}
+
+// ComparedMarket is a market both users forecast on, with their forecasts side by side
+type ComparedMarket struct {
+	MarketID  uint             `json:"market_id"`
+	Title     string           `json:"title"`
+	Status    MarketStatus     `json:"status"`
+	Outcome   *bool            `json:"outcome"`
+	User      ComparedForecast `json:"user"`
+	Other     ComparedForecast `json:"other"`
+	ScoreDiff *float64         `json:"score_diff,omitempty"` // user's score minus other's, once resolved
+}
+
+// PairedTest is a paired t-test of the per-market Brier score differences (user minus other).
+// A negative mean difference means the user's forecasts were more accurate.
+type PairedTest struct {
+	N              int      `json:"n"`
+	MeanDifference float64  `json:"mean_difference"`
+	StdDev         float64  `json:"std_dev"`
+	TStatistic     *float64 `json:"t_statistic"` // nil with fewer than two markets or identical differences
+	PValue         *float64 `json:"p_value"`     // two-sided
+	Significant    bool     `json:"significant"`
+}
+
+// HeadToHead compares two users over the markets they both forecast on
+type HeadToHead struct {
+	Markets        []ComparedMarket `json:"markets"`
+	Shared         int              `json:"shared"`
+	Resolved       int              `json:"resolved"`
+	Wins           int              `json:"wins"` // resolved markets where the user scored higher
+	Losses         int              `json:"losses"`
+	Ties           int              `json:"ties"`
+	ScoreDiff      float64          `json:"score_diff"` // total of the per-market differences
+	UserMeanBrier  float64          `json:"user_mean_brier"`
+	OtherMeanBrier float64          `json:"other_mean_brier"`
+	BrierTest      PairedTest       `json:"brier_test"`
+}
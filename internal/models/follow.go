@@ -0,0 +1,31 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Follow represents one user following another
+type Follow struct {
+	FollowerID uint      `json:"follower_id" gorm:"primaryKey"`
+	FolloweeID uint      `json:"followee_id" gorm:"primaryKey;index"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Validate performs validation on the follow model
+func (f *Follow) Validate() error {
+	if f.FollowerID == 0 || f.FolloweeID == 0 {
+		return errors.New("user_id: user ids must be positive")
+	}
+	if f.FollowerID == f.FolloweeID {
+		return errors.New("user_id: users cannot follow themselves")
+	}
+
+	return nil
+}
+
+// FollowCounts holds how many users follow a user and how many they follow
+type FollowCounts struct {
+	Followers int64 `json:"followers_count"`
+	Following int64 `json:"following_count"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// Forecast represents a user's prediction on a market outcome
+type Forecast struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	MarketID    uint       `json:"market_id" gorm:"not null;index"`
+	Market      Market     `json:"market" gorm:"foreignKey:MarketID"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	User        User       `json:"user" gorm:"foreignKey:UserID"`
+	Prediction  bool       `json:"prediction"`
+	Confidence  float64    `json:"confidence"` // 0-100, confidence in Prediction
+	Rationale   string     `json:"rationale"`
+	WithdrawnAt *time.Time `json:"withdrawn_at" gorm:"index"` // withdrawn forecasts are kept but not scored
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ForecastRevision is an append-only record of a forecast's value over time, including withdrawals
+type ForecastRevision struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ForecastID uint      `json:"forecast_id" gorm:"not null;index"`
+	MarketID   uint      `json:"market_id" gorm:"not null;index"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	Prediction bool      `json:"prediction"`
+	Confidence float64   `json:"confidence"`
+	Withdrawn  bool      `json:"withdrawn"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsActive reports whether the forecast has not been withdrawn
+func (f *Forecast) IsActive() bool {
+	return f.WithdrawnAt == nil
+}
+
+// Revision returns a history record of the forecast's current state
+func (f *Forecast) Revision() ForecastRevision {
+	return ForecastRevision{
+		ForecastID: f.ID,
+		MarketID:   f.MarketID,
+		UserID:     f.UserID,
+		Prediction: f.Prediction,
+		Confidence: f.Confidence,
+		Withdrawn:  !f.IsActive(),
+		CreatedAt:  f.UpdatedAt,
+	}
+}
+
+// Probability returns the forecast as the probability that the market resolves true
+func (f *Forecast) Probability() float64 {
+	p := f.Confidence / 100
+	if !f.Prediction {
+		return 1 - p
+	}
+	return p
+}
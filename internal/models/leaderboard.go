@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// LeaderboardMode decides what a leaderboard is ranked by
+type LeaderboardMode string
+
+const (
+	// LeaderboardRaw ranks by total score, which rewards volume and lucky streaks alike
+	LeaderboardRaw LeaderboardMode = "raw"
+	// LeaderboardAdjusted ranks by average score per forecast shrunk towards the population
+	// mean, so a handful of lucky forecasts cannot top the board
+	LeaderboardAdjusted LeaderboardMode = "adjusted"
+)
+
+// LeaderboardFilter selects the score records a leaderboard is built from and how it is ranked
+type LeaderboardFilter struct {
+	From           time.Time // inclusive; zero means no lower bound
+	To             time.Time // exclusive; zero means no upper bound
+	Category       string
+	Mode           LeaderboardMode
+	MinPredictions int // users with fewer resolved forecasts are left off
+}
+
+// LeaderboardEntry is one user's row on a leaderboard.
+// Tied scores share a rank, and the next rank skips past them (1, 1, 3).
+type LeaderboardEntry struct {
+	Rank          int     `json:"rank"`
+	UserID        uint    `json:"user_id"`
+	Username      string  `json:"username"`
+	Score         float64 `json:"score"`
+	AdjustedScore float64 `json:"adjusted_score"` // shrunk average score per forecast
+	Resolved      int     `json:"resolved"`
+	Correct       int     `json:"correct"`
+}
@@ -13,15 +13,35 @@ const (
 )
 
 type Market struct {
-	ID          uint         `json:"id" gorm:"primaryKey"`
-	Title       string       `json:"title" gorm:"not null"`
-	Description string       `json:"description"`
-	CreatorID   uint         `json:"creator_id"`
-	Creator     User         `json:"creator" gorm:"foreignKey:CreatorID"`
-	CloseDate   time.Time    `json:"close_date"`
-	ResolveDate time.Time    `json:"resolve_date"`
-	Status      MarketStatus `json:"status" gorm:"default:'open'"`
-	Outcome     *bool        `json:"outcome"`
-	CreatedAt   time.Time    `json:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at"`
+	ID            uint         `json:"id" gorm:"primaryKey"`
+	Title         string       `json:"title" gorm:"not null"`
+	Description   string       `json:"description"`
+	Category      string       `json:"category" gorm:"index"`
+	CreatorID     uint         `json:"creator_id"`
+	Creator       User         `json:"creator" gorm:"foreignKey:CreatorID"`
+	CloseDate     time.Time    `json:"close_date"`
+	ResolveDate   time.Time    `json:"resolve_date"`
+	Status        MarketStatus `json:"status" gorm:"default:'open'"`
+	Outcome       *bool        `json:"outcome"`
+	HideForecasts *bool        `json:"hide_forecasts"` // nil uses the site default
+	HotScore      float64      `json:"hot_score" gorm:"index"`
+	ResolvedAt    *time.Time   `json:"resolved_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// IsClosed reports whether the market no longer accepts predictions at the given time
+func (m *Market) IsClosed(now time.Time) bool {
+	if m.Status != MarketOpen {
+		return true
+	}
+	return !m.CloseDate.IsZero() && now.After(m.CloseDate)
+}
+
+// HidesForecasts reports whether forecasts are hidden from users who have not predicted
+func (m *Market) HidesForecasts(siteDefault bool) bool {
+	if m.HideForecasts != nil {
+		return *m.HideForecasts
+	}
+	return siteDefault
 }
@@ -2,6 +2,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -257,4 +258,195 @@ func TestVoteValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestForecastProbability(t *testing.T) {
+	yes := Forecast{Prediction: true, Confidence: 80}
+	no := Forecast{Prediction: false, Confidence: 80}
+
+	assert.InDelta(t, 0.8, yes.Probability(), 1e-9)
+	assert.InDelta(t, 0.2, no.Probability(), 1e-9)
+}
+
+func TestMarketHidesForecasts(t *testing.T) {
+	hide, show := true, false
+
+	assert.True(t, (&Market{}).HidesForecasts(true))
+	assert.False(t, (&Market{}).HidesForecasts(false))
+	assert.True(t, (&Market{HideForecasts: &hide}).HidesForecasts(false))
+	assert.False(t, (&Market{HideForecasts: &show}).HidesForecasts(true))
+}
+
+func TestMarketIsClosed(t *testing.T) {
+	now := time.Now()
+
+	assert.False(t, (&Market{Status: MarketOpen, CloseDate: now.Add(time.Hour)}).IsClosed(now))
+	assert.False(t, (&Market{Status: MarketOpen}).IsClosed(now))
+	assert.True(t, (&Market{Status: MarketOpen, CloseDate: now.Add(-time.Hour)}).IsClosed(now))
+	assert.True(t, (&Market{Status: MarketClosed, CloseDate: now.Add(time.Hour)}).IsClosed(now))
+	assert.True(t, (&Market{Status: MarketResolved}).IsClosed(now))
+}
+
+func TestCommentCanEdit(t *testing.T) {
+	now := time.Now()
+	deletedAt := now
+
+	assert.True(t, (&Comment{CreatedAt: now.Add(-5 * time.Minute)}).CanEdit(now, 15*time.Minute))
+	assert.False(t, (&Comment{CreatedAt: now.Add(-20 * time.Minute)}).CanEdit(now, 15*time.Minute))
+	assert.False(t, (&Comment{CreatedAt: now, DeletedAt: &deletedAt}).CanEdit(now, 15*time.Minute))
+}
+
+func TestForecastRevision(t *testing.T) {
+	now := time.Now()
+	forecast := Forecast{ID: 7, MarketID: 3, UserID: 2, Prediction: true, Confidence: 70, UpdatedAt: now}
+
+	revision := forecast.Revision()
+	assert.True(t, forecast.IsActive())
+	assert.Equal(t, uint(7), revision.ForecastID)
+	assert.False(t, revision.Withdrawn)
+	assert.Equal(t, now, revision.CreatedAt)
+
+	forecast.WithdrawnAt = &now
+	assert.False(t, forecast.IsActive())
+	assert.True(t, forecast.Revision().Withdrawn)
+}
+
+func TestPredictionStatusAt(t *testing.T) {
+	now := time.Now()
+	outcome := true
+
+	assert.Equal(t, PredictionPending, (&Prediction{EndDate: now.Add(time.Hour)}).StatusAt(now))
+	assert.Equal(t, PredictionEnded, (&Prediction{EndDate: now.Add(-time.Hour)}).StatusAt(now))
+	assert.Equal(t, PredictionResolved, (&Prediction{EndDate: now.Add(-time.Hour), Outcome: &outcome}).StatusAt(now))
+}
+
+func TestFollowValidation(t *testing.T) {
+	assert.NoError(t, (&Follow{FollowerID: 1, FolloweeID: 2}).Validate())
+	assert.Error(t, (&Follow{FollowerID: 1, FolloweeID: 1}).Validate())
+	assert.Error(t, (&Follow{FollowerID: 0, FolloweeID: 2}).Validate())
+}
+
+func TestNotificationPreferencesValidation(t *testing.T) {
+	valid := NotificationPreferencesRequest{
+		Channels:        map[NotificationType]NotificationChannel{NotificationVote: ChannelOff, NotificationMention: ChannelEmail},
+		DigestFrequency: DigestWeekly,
+	}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, (&NotificationPreferencesRequest{}).Validate())
+
+	assert.Error(t, (&NotificationPreferencesRequest{Channels: map[NotificationType]NotificationChannel{"unknown": ChannelOff}}).Validate())
+	assert.Error(t, (&NotificationPreferencesRequest{Channels: map[NotificationType]NotificationChannel{NotificationVote: "sms"}}).Validate())
+	assert.Error(t, (&NotificationPreferencesRequest{DigestFrequency: "hourly"}).Validate())
+
+	assert.Equal(t, ChannelInApp, DefaultNotificationChannel(NotificationVote))
+	assert.Equal(t, ChannelEmail, DefaultNotificationChannel(NotificationMention))
+	assert.Zero(t, DigestOff.Interval())
+	assert.Equal(t, 7*24*time.Hour, DigestWeekly.Interval())
+}
+
+func TestWebhookRequestValidation(t *testing.T) {
+	valid := WebhookRequest{URL: "https://example.com/hooks", Events: []WebhookEvent{WebhookMarketCreated}}
+	assert.NoError(t, valid.Validate())
+
+	assert.Error(t, (&WebhookRequest{URL: "ftp://example.com", Events: []WebhookEvent{WebhookMarketCreated}}).Validate())
+	assert.Error(t, (&WebhookRequest{URL: "/relative", Events: []WebhookEvent{WebhookMarketCreated}}).Validate())
+	assert.Error(t, (&WebhookRequest{URL: "https://example.com"}).Validate())
+	assert.Error(t, (&WebhookRequest{URL: "https://example.com", Events: []WebhookEvent{"market.deleted"}}).Validate())
+}
+
+func TestWebhookEventList(t *testing.T) {
+	list := WebhookEventList{WebhookMarketCreated, WebhookPredictionCreated}
+
+	value, err := list.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "market.created,prediction.created", value)
+
+	var scanned WebhookEventList
+	assert.NoError(t, scanned.Scan([]byte("market.created,prediction.created")))
+	assert.Equal(t, list, scanned)
+	assert.True(t, scanned.Contains(WebhookPredictionCreated))
+	assert.False(t, scanned.Contains(WebhookMarketResolved))
+
+	assert.NoError(t, scanned.Scan(""))
+	assert.Empty(t, scanned)
+}
+
+func TestTournamentRequestValidation(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := TournamentRequest{
+		Name:      "Q1 Championship",
+		StartDate: start,
+		EndDate:   start.AddDate(0, 3, 0),
+		MarketIDs: []uint{1, 2, 3},
+		Prizes:    []string{"Trophy"},
+	}
+	assert.NoError(t, valid.Validate())
+
+	invalid := valid
+	invalid.EndDate = start
+	assert.Error(t, invalid.Validate())
+
+	invalid = valid
+	invalid.ScoringRule = "log"
+	assert.Error(t, invalid.Validate())
+
+	// A market listed twice is only part of the tournament once
+	duplicated := valid
+	duplicated.MarketIDs = []uint{2, 1, 2}
+	assert.NoError(t, duplicated.Validate())
+	assert.Equal(t, []uint{2, 1}, duplicated.UniqueMarketIDs())
+
+	invalid = valid
+	invalid.MarketIDs = nil
+	assert.Error(t, invalid.Validate())
+
+	invalid = valid
+	invalid.Prizes = []string{"Trophy", ""}
+	assert.Error(t, invalid.Validate())
+}
+
+func TestTournamentStatus(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tournament := Tournament{StartDate: start, EndDate: start.AddDate(0, 3, 0)}
+
+	assert.Equal(t, TournamentUpcoming, tournament.Status(start.Add(-time.Hour)))
+	assert.Equal(t, TournamentActive, tournament.Status(start))
+	assert.Equal(t, TournamentFinished, tournament.Status(start.AddDate(0, 3, 0)))
+}
+
+func TestActiveDayStreak(t *testing.T) {
+	stats := UserStats{DayStreak: 4, LastForecastDay: "2025-03-01"}
+
+	assert.Equal(t, 4, stats.ActiveDayStreak("2025-03-01"))
+	assert.Equal(t, 4, stats.ActiveDayStreak("2025-03-02"))
+	assert.Equal(t, 0, stats.ActiveDayStreak("2025-03-03"))
+	assert.Equal(t, "2024-02-29", PreviousDay("2024-03-01"))
+}
+
+func TestValidateTimeZone(t *testing.T) {
+	assert.NoError(t, ValidateTimeZone("Europe/Berlin"))
+	assert.NoError(t, ValidateTimeZone("UTC"))
+	assert.Error(t, ValidateTimeZone("Mars/Olympus"))
+	assert.Error(t, ValidateTimeZone(""))
+	assert.Equal(t, time.UTC, (&User{TimeZone: "nope"}).Location())
+}
+
+func TestDailyChallengeRequestValidation(t *testing.T) {
+	assert.NoError(t, (&DailyChallengeRequest{Day: "2025-06-01", MarketID: 1}).Validate())
+	assert.Error(t, (&DailyChallengeRequest{Day: "06/01/2025", MarketID: 1}).Validate())
+}
+
+func TestTeamRequestValidation(t *testing.T) {
+	assert.NoError(t, (&TeamRequest{Name: "Superforecasters"}).Validate())
+	assert.Error(t, (&TeamRequest{Name: "ab"}).Validate())
+	assert.Error(t, (&TeamRequest{Name: strings.Repeat("a", 51)}).Validate())
+	assert.Error(t, (&TeamRequest{Name: "Team", Description: strings.Repeat("a", 1001)}).Validate())
+}
+
+func TestTeamRole(t *testing.T) {
+	assert.True(t, TeamOwner.CanManageMembers())
+	assert.True(t, TeamAdmin.CanManageMembers())
+	assert.False(t, TeamMemberRole.CanManageMembers())
+	assert.False(t, TeamRole("").CanManageMembers())
+	assert.True(t, TeamMemberRole.IsValid())
+	assert.False(t, TeamRole("captain").IsValid())
+}
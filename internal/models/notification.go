@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -12,15 +14,130 @@ const (
 	NotificationResult     NotificationType = "result"
 	NotificationMention    NotificationType = "mention"
 	NotificationEndingSoon NotificationType = "ending_soon"
+	// NotificationResolutionDue tells a market's creator, and later the moderators, that it needs resolving
+	NotificationResolutionDue NotificationType = "resolution_due"
+	// NotificationTournamentAward tells a user they placed in a finished tournament
+	NotificationTournamentAward NotificationType = "tournament_award"
+	// NotificationAchievement tells a user they earned an achievement badge
+	NotificationAchievement NotificationType = "achievement"
+	// NotificationStreakAtRisk warns a user their daily forecasting streak ends unless they forecast today
+	NotificationStreakAtRisk NotificationType = "streak_at_risk"
+	// NotificationTeamInvite tells a user they were invited to join a team
+	NotificationTeamInvite NotificationType = "team_invite"
 )
 
+// NotificationTypes lists every notification type
+var NotificationTypes = []NotificationType{
+	NotificationVote,
+	NotificationResult,
+	NotificationMention,
+	NotificationEndingSoon,
+	NotificationResolutionDue,
+	NotificationTournamentAward,
+	NotificationAchievement,
+	NotificationStreakAtRisk,
+	NotificationTeamInvite,
+}
+
+// IsValid reports whether the notification type is known
+func (t NotificationType) IsValid() bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationChannel is how a user wants to receive a type of notification
+type NotificationChannel string
+
+const (
+	// ChannelInApp shows notifications in the inbox only
+	ChannelInApp NotificationChannel = "in_app"
+	// ChannelEmail shows notifications in the inbox and includes unread ones in email digests
+	ChannelEmail NotificationChannel = "email"
+	// ChannelOff drops notifications entirely
+	ChannelOff NotificationChannel = "off"
+)
+
+// IsValid reports whether the channel is known
+func (c NotificationChannel) IsValid() bool {
+	return c == ChannelInApp || c == ChannelEmail || c == ChannelOff
+}
+
+// DefaultNotificationChannel is the channel used for a type the user has not configured.
+// Votes are frequent, so they stay out of email unless asked for.
+func DefaultNotificationChannel(t NotificationType) NotificationChannel {
+	if t == NotificationVote {
+		return ChannelInApp
+	}
+	return ChannelEmail
+}
+
+// DigestFrequency is how often a user receives email digests
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+// Interval returns the time between digests, or zero when digests are off
+func (f DigestFrequency) Interval() time.Duration {
+	switch f {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// IsValid reports whether the frequency is known
+func (f DigestFrequency) IsValid() bool {
+	return f == DigestOff || f == DigestDaily || f == DigestWeekly
+}
+
 // Notification represents a notification for a user
 type Notification struct {
 	ID        int              `json:"id" db:"id"`
-	UserID    int              `json:"user_id" db:"user_id"`
+	UserID    int              `json:"user_id" db:"user_id" gorm:"index:idx_notifications_user_time,priority:1"`
 	Type      NotificationType `json:"type" db:"type"`
 	Message   string           `json:"message" db:"message"`
 	Link      string           `json:"link" db:"link"`
-	Read      bool             `json:"read" db:"read"`
-	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	Read      bool             `json:"read" db:"read" gorm:"index"`
+	EmailedAt *time.Time       `json:"-" db:"emailed_at"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at" gorm:"index:idx_notifications_user_time,priority:2"`
+}
+
+// NotificationPreference is a user's chosen channel for one notification type
+type NotificationPreference struct {
+	UserID    int                 `json:"-" db:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Type      NotificationType    `json:"type" db:"type" gorm:"primaryKey"`
+	Channel   NotificationChannel `json:"channel" db:"channel"`
+	UpdatedAt time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationPreferencesRequest represents the data needed to update notification preferences
+type NotificationPreferencesRequest struct {
+	Channels        map[NotificationType]NotificationChannel `json:"channels"`
+	DigestFrequency DigestFrequency                          `json:"digest_frequency"`
+}
+
+// Validate performs validation on the preferences request
+func (r *NotificationPreferencesRequest) Validate() error {
+	for t, channel := range r.Channels {
+		if !t.IsValid() {
+			return fmt.Errorf("channels: unknown notification type %q", t)
+		}
+		if !channel.IsValid() {
+			return fmt.Errorf("channels: channel for %s must be one of in_app, email or off", t)
+		}
+	}
+	if r.DigestFrequency != "" && !r.DigestFrequency.IsValid() {
+		return errors.New("digest_frequency: must be one of off, daily or weekly")
+	}
+	return nil
 }
@@ -5,6 +5,15 @@ import (
 	"time"
 )
 
+// PredictionStatus describes where a prediction is in its lifecycle
+type PredictionStatus string
+
+const (
+	PredictionPending  PredictionStatus = "pending"  // end date not reached
+	PredictionEnded    PredictionStatus = "ended"    // end date passed, awaiting a result
+	PredictionResolved PredictionStatus = "resolved" // result submitted
+)
+
 // Prediction represents a prediction made by a user
 type Prediction struct {
 	ID            int       `json:"id" db:"id"`
@@ -16,9 +25,13 @@ type Prediction struct {
 	EndDate       time.Time `json:"end_date" db:"end_date"`
 	AgreeCount    int       `json:"agree_count" db:"agree_count"`
 	DisagreeCount int       `json:"disagree_count" db:"disagree_count"`
+	HotScore      float64   `json:"hot_score" db:"hot_score" gorm:"index"`
 	// Optional fields that might be populated with JOIN queries
-	UserName string `json:"user_name,omitempty" db:"user_name"`
-	UserVote *bool  `json:"user_vote,omitempty" db:"user_vote"`
+	UserName string `json:"user_name,omitempty" db:"user_name" gorm:"->;-:migration"`
+	UserVote *bool  `json:"user_vote,omitempty" db:"user_vote" gorm:"->;-:migration"`
+	Outcome  *bool  `json:"outcome,omitempty" db:"outcome" gorm:"->;-:migration"`
+	// Status is derived from EndDate and Outcome when the prediction is loaded
+	Status PredictionStatus `json:"status,omitempty" db:"-" gorm:"-"`
 }
 
 // PredictionRequest represents the data needed to create a new prediction
@@ -29,6 +42,17 @@ type PredictionRequest struct {
 	EndDate     time.Time `json:"end_date" binding:"required"`
 }
 
+// StatusAt returns the prediction's status at the given time
+func (p *Prediction) StatusAt(now time.Time) PredictionStatus {
+	if p.Outcome != nil {
+		return PredictionResolved
+	}
+	if !now.Before(p.EndDate) {
+		return PredictionEnded
+	}
+	return PredictionPending
+}
+
 // Validate performs validation on the prediction model
 func (p *Prediction) Validate() error {
 	// Title validation
@@ -63,3 +87,11 @@ func (p *Prediction) Validate() error {
 
 	return nil
 }
+
+// PredictionFilter narrows a listing of social predictions
+type PredictionFilter struct {
+	UserID   int
+	Category string
+	Status   PredictionStatus
+	Trending bool // order by hot score instead of newest first
+}
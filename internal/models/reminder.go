@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// ReminderKind identifies what a reminder is about
+type ReminderKind string
+
+const (
+	ReminderMarketEndingSoon     ReminderKind = "market_ending_soon"
+	ReminderPredictionEndingSoon ReminderKind = "prediction_ending_soon"
+	ReminderResolutionDue        ReminderKind = "resolution_due"
+	ReminderResolutionEscalated  ReminderKind = "resolution_escalated"
+	ReminderStreakAtRisk         ReminderKind = "streak_at_risk"
+)
+
+// Reminder records that a reminder was sent, so the job never sends it twice.
+// Sequence distinguishes repeated reminders, such as the daily nags about an overdue resolution.
+type Reminder struct {
+	ID        uint         `json:"id" db:"id"`
+	Kind      ReminderKind `json:"kind" db:"kind" gorm:"uniqueIndex:idx_reminders_once,priority:1"`
+	SubjectID uint         `json:"subject_id" db:"subject_id" gorm:"uniqueIndex:idx_reminders_once,priority:2"`
+	UserID    uint         `json:"user_id" db:"user_id" gorm:"uniqueIndex:idx_reminders_once,priority:3"`
+	Sequence  int          `json:"sequence" db:"sequence" gorm:"uniqueIndex:idx_reminders_once,priority:4"`
+	SentAt    time.Time    `json:"sent_at" db:"sent_at"`
+}
@@ -7,14 +7,15 @@ import (
 // Result represents the outcome of a prediction after its end date
 type Result struct {
 	ID           int       `json:"id" db:"id"`
-	PredictionID int       `json:"prediction_id" db:"prediction_id"`
+	PredictionID int       `json:"prediction_id" db:"prediction_id" gorm:"uniqueIndex"`
 	Outcome      bool      `json:"outcome" db:"outcome"` // true = came true, false = didn't come true
 	EvidenceURL  string    `json:"evidence_url" db:"evidence_url"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
-// ResultRequest represents the data needed to submit a prediction result
+// ResultRequest represents the data needed to submit a prediction result.
+// Outcome is not marked required because "didn't come true" is its zero value.
 type ResultRequest struct {
-	Outcome     bool   `json:"outcome" binding:"required"`
+	Outcome     bool   `json:"outcome"`
 	EvidenceURL string `json:"evidence_url" binding:"required"`
 }
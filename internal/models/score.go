@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ScoreRecord is the score one forecast earned when its market resolved.
+// Leaderboards sum these over a time window or category.
+type ScoreRecord struct {
+	ID         uint      `json:"id" db:"id"`
+	UserID     uint      `json:"user_id" db:"user_id" gorm:"uniqueIndex:idx_scores_user_market,priority:1"`
+	MarketID   uint      `json:"market_id" db:"market_id" gorm:"uniqueIndex:idx_scores_user_market,priority:2"`
+	ForecastID uint      `json:"forecast_id" db:"forecast_id"`
+	Category   string    `json:"category" db:"category" gorm:"index"`
+	Score      float64   `json:"score" db:"score"`
+	Correct    bool      `json:"correct" db:"correct"`
+	ResolvedAt time.Time `json:"resolved_at" db:"resolved_at" gorm:"index"`
+}
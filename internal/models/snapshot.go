@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MarketSnapshot records the aggregated crowd forecast of a market at a point in time
+type MarketSnapshot struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	MarketID        uint      `json:"market_id" gorm:"not null;index:idx_snapshot_market_time,priority:1"`
+	Probability     float64   `json:"probability"` // mean probability that the market resolves true
+	PredictionCount int       `json:"prediction_count"`
+	CreatedAt       time.Time `json:"created_at" gorm:"index:idx_snapshot_market_time,priority:2"`
+}
+
+// TimeseriesPoint is a single downsampled point of a market's probability history
+type TimeseriesPoint struct {
+	Time            time.Time `json:"time"`
+	Probability     float64   `json:"probability"`
+	PredictionCount int       `json:"prediction_count"`
+}
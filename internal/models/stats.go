@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// UserStats holds a user's running prediction totals, kept up to date as forecasts are
+// made and markets resolve so profile stats never have to be recomputed per request
+type UserStats struct {
+	UserID              uint      `json:"user_id" db:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	TotalPredictions    int       `json:"total_predictions" db:"total_predictions"`
+	ResolvedPredictions int       `json:"resolved_predictions" db:"resolved_predictions"`
+	CorrectPredictions  int       `json:"correct_predictions" db:"correct_predictions"`
+	Score               float64   `json:"score" db:"score"`
+	CurrentStreak       int       `json:"current_streak" db:"current_streak"` // correct predictions in a row, most recent first
+	LongestStreak       int       `json:"longest_streak" db:"longest_streak"`
+	DayStreak           int       `json:"day_streak" db:"day_streak"` // consecutive days with a new or updated forecast
+	LongestDayStreak    int       `json:"longest_day_streak" db:"longest_day_streak"`
+	LastForecastDay     string    `json:"last_forecast_day" db:"last_forecast_day"` // YYYY-MM-DD in the user's time zone
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TableName names the table explicitly so the already plural "stats" is left alone
+func (UserStats) TableName() string {
+	return "user_stats"
+}
+
+// Accuracy returns the percentage of resolved predictions that were correct
+func (s *UserStats) Accuracy() float64 {
+	if s.ResolvedPredictions == 0 {
+		return 0
+	}
+	return float64(s.CorrectPredictions) / float64(s.ResolvedPredictions) * 100
+}
+
+// ActiveDayStreak returns the day streak as of today (YYYY-MM-DD in the user's time zone).
+// A streak stays alive through today until the day ends; after a missed day it is zero.
+func (s *UserStats) ActiveDayStreak(today string) int {
+	if s.LastForecastDay == today || s.LastForecastDay == PreviousDay(today) {
+		return s.DayStreak
+	}
+	return 0
+}
+
+// DayLayout formats calendar days
+const DayLayout = "2006-01-02"
+
+// LocalDay returns the calendar day of t in loc
+func LocalDay(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(DayLayout)
+}
+
+// PreviousDay returns the calendar day before day, or "" if day is malformed
+func PreviousDay(day string) string {
+	t, err := time.Parse(DayLayout, day)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, -1).Format(DayLayout)
+}
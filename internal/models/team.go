@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// TeamRole is a member's role in a team
+type TeamRole string
+
+const (
+	// TeamOwner created the team and manages its members and their roles
+	TeamOwner TeamRole = "owner"
+	// TeamAdmin can invite and remove members
+	TeamAdmin TeamRole = "admin"
+	// TeamMemberRole forecasts for the team
+	TeamMemberRole TeamRole = "member"
+)
+
+// IsValid reports whether the role is known
+func (r TeamRole) IsValid() bool {
+	return r == TeamOwner || r == TeamAdmin || r == TeamMemberRole
+}
+
+// CanManageMembers reports whether the role may invite and remove members
+func (r TeamRole) CanManageMembers() bool {
+	return r == TeamOwner || r == TeamAdmin
+}
+
+// Team is a group of forecasters competing together
+type Team struct {
+	ID          uint      `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name" gorm:"uniqueIndex"`
+	Description string    `json:"description" db:"description"`
+	CreatorID   uint      `json:"creator_id" db:"creator_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TeamMember is a user's membership of a team.
+// Only forecasts resolved after JoinedAt count towards the team's score.
+type TeamMember struct {
+	TeamID   uint      `json:"team_id" db:"team_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID   uint      `json:"user_id" db:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	User     User      `json:"user" gorm:"foreignKey:UserID"`
+	Role     TeamRole  `json:"role" db:"role"`
+	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+}
+
+// TeamInviteStatus is the state of a team invitation
+type TeamInviteStatus string
+
+const (
+	InvitePending  TeamInviteStatus = "pending"
+	InviteAccepted TeamInviteStatus = "accepted"
+	InviteDeclined TeamInviteStatus = "declined"
+)
+
+// TeamInvite invites a user to join a team. Inviting a user again reopens their invitation.
+type TeamInvite struct {
+	ID          uint             `json:"id" db:"id"`
+	TeamID      uint             `json:"team_id" db:"team_id" gorm:"uniqueIndex:idx_team_invites_user,priority:1"`
+	Team        Team             `json:"team" gorm:"foreignKey:TeamID"`
+	UserID      uint             `json:"user_id" db:"user_id" gorm:"uniqueIndex:idx_team_invites_user,priority:2;index"`
+	InviterID   uint             `json:"inviter_id" db:"inviter_id"`
+	Status      TeamInviteStatus `json:"status" db:"status"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	RespondedAt *time.Time       `json:"responded_at" db:"responded_at"`
+}
+
+// TeamRequest represents the data needed to create a team
+type TeamRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// Validate performs validation on the team request
+func (r *TeamRequest) Validate() error {
+	if len(r.Name) < 3 || len(r.Name) > 50 {
+		return errors.New("name: must be between 3 and 50 characters")
+	}
+	if len(r.Description) > 1000 {
+		return errors.New("description: must be at most 1000 characters")
+	}
+	return nil
+}
+
+// TeamRanking decides what the team leaderboard is ranked by
+type TeamRanking string
+
+const (
+	// TeamRankTotal ranks teams by the total score of their members
+	TeamRankTotal TeamRanking = "total"
+	// TeamRankAverage ranks teams by score per resolved forecast, so small teams can compete
+	TeamRankAverage TeamRanking = "average"
+)
+
+// TeamEntry is a team's row on the team leaderboard
+type TeamEntry struct {
+	Rank         int     `json:"rank"`
+	TeamID       uint    `json:"team_id"`
+	Name         string  `json:"name"`
+	Members      int     `json:"members"`
+	Score        float64 `json:"score"`
+	AverageScore float64 `json:"average_score"`
+	Resolved     int     `json:"resolved"`
+	Correct      int     `json:"correct"`
+}
+
+// TeamContribution is one member's share of a team's score
+type TeamContribution struct {
+	UserID   uint    `json:"user_id"`
+	Username string  `json:"username"`
+	Score    float64 `json:"score"`
+	Resolved int     `json:"resolved"`
+	Correct  int     `json:"correct"`
+}
+
+// TeamStats summarises a team's scored forecasts
+type TeamStats struct {
+	Members       int                `json:"members"`
+	Score         float64            `json:"score"`
+	Resolved      int                `json:"resolved"`
+	Correct       int                `json:"correct"`
+	Accuracy      float64            `json:"accuracy"`
+	Contributions []TeamContribution `json:"contributions"`
+}
+
+// TeamForecast is the average of the team members' forecasts on a market
+type TeamForecast struct {
+	MarketID    uint    `json:"market_id"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TournamentScoringRule decides how each resolved forecast in a tournament is scored
+type TournamentScoringRule string
+
+const (
+	// ScoringConfidence uses the site's own score: confidence rewarded when right, penalised when wrong
+	ScoringConfidence TournamentScoringRule = "confidence"
+	// ScoringBrier awards one minus the Brier score, so a perfect forecast earns 1 and the worst 0
+	ScoringBrier TournamentScoringRule = "brier"
+	// ScoringAccuracy awards one point per correct prediction regardless of confidence
+	ScoringAccuracy TournamentScoringRule = "accuracy"
+)
+
+// IsValid reports whether the scoring rule is known
+func (r TournamentScoringRule) IsValid() bool {
+	return r == ScoringConfidence || r == ScoringBrier || r == ScoringAccuracy
+}
+
+// TournamentStatus is where a tournament is in its schedule
+type TournamentStatus string
+
+const (
+	TournamentUpcoming TournamentStatus = "upcoming"
+	TournamentActive   TournamentStatus = "active"
+	TournamentFinished TournamentStatus = "finished"
+)
+
+// MaxTournamentMarkets caps how many markets a tournament can group
+const MaxTournamentMarkets = 200
+
+// Tournament is a competition over a fixed set of markets with its own leaderboard.
+// Tournament scores are computed separately and never change a user's PredictionScore.
+type Tournament struct {
+	ID             uint                  `json:"id" db:"id"`
+	Name           string                `json:"name" db:"name"`
+	Description    string                `json:"description" db:"description"`
+	CreatorID      uint                  `json:"creator_id" db:"creator_id"`
+	StartDate      time.Time             `json:"start_date" db:"start_date" gorm:"index"`
+	EndDate        time.Time             `json:"end_date" db:"end_date" gorm:"index"`
+	RequiresSignup bool                  `json:"requires_signup" db:"requires_signup"` // only users who joined are ranked
+	ScoringRule    TournamentScoringRule `json:"scoring_rule" db:"scoring_rule" gorm:"default:confidence"`
+	AwardedAt      *time.Time            `json:"awarded_at" db:"awarded_at"` // set once prizes and badges are handed out
+	Markets        []Market              `json:"markets,omitempty" gorm:"many2many:tournament_markets"`
+	Prizes         []TournamentPrize     `json:"prizes,omitempty" gorm:"foreignKey:TournamentID"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// Status returns the tournament's status at the given time
+func (t *Tournament) Status(now time.Time) TournamentStatus {
+	switch {
+	case now.Before(t.StartDate):
+		return TournamentUpcoming
+	case now.Before(t.EndDate):
+		return TournamentActive
+	}
+	return TournamentFinished
+}
+
+// TournamentPrize is what the user finishing at Rank receives
+type TournamentPrize struct {
+	TournamentID uint   `json:"tournament_id" db:"tournament_id" gorm:"primaryKey;autoIncrement:false"`
+	Rank         int    `json:"rank" db:"rank" gorm:"primaryKey;autoIncrement:false"`
+	Description  string `json:"description" db:"description"`
+}
+
+// TournamentEntrant records a user signing up for a tournament
+type TournamentEntrant struct {
+	TournamentID uint      `json:"tournament_id" db:"tournament_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID       uint      `json:"user_id" db:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	JoinedAt     time.Time `json:"joined_at" db:"joined_at"`
+}
+
+// TournamentAward is the badge, and prize if any, a user won in a finished tournament
+type TournamentAward struct {
+	ID           uint      `json:"id" db:"id"`
+	TournamentID uint      `json:"tournament_id" db:"tournament_id" gorm:"uniqueIndex:idx_tournament_awards_user,priority:1"`
+	UserID       uint      `json:"user_id" db:"user_id" gorm:"uniqueIndex:idx_tournament_awards_user,priority:2;index"`
+	Rank         int       `json:"rank" db:"rank"`
+	Score        float64   `json:"score" db:"score"`
+	Badge        string    `json:"badge" db:"badge"`
+	Prize        string    `json:"prize,omitempty" db:"prize"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// TournamentRequest represents the data needed to create a tournament
+type TournamentRequest struct {
+	Name           string                `json:"name" binding:"required"`
+	Description    string                `json:"description"`
+	StartDate      time.Time             `json:"start_date" binding:"required"`
+	EndDate        time.Time             `json:"end_date" binding:"required"`
+	RequiresSignup bool                  `json:"requires_signup"`
+	ScoringRule    TournamentScoringRule `json:"scoring_rule"`
+	MarketIDs      []uint                `json:"market_ids" binding:"required"`
+	Prizes         []string              `json:"prizes"` // prizes[0] goes to first place, and so on
+}
+
+// UniqueMarketIDs returns the requested market IDs in order, listing each market once
+func (r *TournamentRequest) UniqueMarketIDs() []uint {
+	seen := make(map[uint]bool, len(r.MarketIDs))
+	ids := make([]uint, 0, len(r.MarketIDs))
+	for _, id := range r.MarketIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Validate performs validation on the tournament request
+func (r *TournamentRequest) Validate() error {
+	if len(r.Name) < 3 || len(r.Name) > 100 {
+		return errors.New("name: must be between 3 and 100 characters")
+	}
+
+	if !r.EndDate.After(r.StartDate) {
+		return errors.New("end_date: must be after start_date")
+	}
+
+	if r.ScoringRule != "" && !r.ScoringRule.IsValid() {
+		return fmt.Errorf("scoring_rule: must be one of %s, %s or %s", ScoringConfidence, ScoringBrier, ScoringAccuracy)
+	}
+
+	marketIDs := r.UniqueMarketIDs()
+	if len(marketIDs) == 0 {
+		return errors.New("market_ids: at least one market is required")
+	}
+	if len(marketIDs) > MaxTournamentMarkets {
+		return fmt.Errorf("market_ids: at most %d markets are allowed", MaxTournamentMarkets)
+	}
+
+	for i, prize := range r.Prizes {
+		if prize == "" {
+			return fmt.Errorf("prizes: prize for rank %d is empty", i+1)
+		}
+	}
+
+	return nil
+}
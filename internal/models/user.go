@@ -9,16 +9,25 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        uint      `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	Email     string    `json:"email" db:"email"`
-	Password  string    `json:"-" db:"password"` // never expose in JSON
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID              uint            `json:"id" db:"id"`
+	Name            string          `json:"name" db:"name"`
+	Username        string          `json:"username" db:"username"`
+	Email           string          `json:"email" db:"email"`
+	Password        string          `json:"-" db:"password"` // never expose in JSON
+	Bio             string          `json:"bio" db:"bio"`
+	PredictionScore float64         `json:"prediction_score" db:"prediction_score"`
+	DigestFrequency DigestFrequency `json:"digest_frequency" db:"digest_frequency" gorm:"default:off"` // users opt in to digests
+	LastDigestAt    *time.Time      `json:"-" db:"last_digest_at"`
+	IsAdmin         bool            `json:"is_admin" db:"is_admin" gorm:"default:false"`
+	TimeZone        string          `json:"time_zone" db:"time_zone" gorm:"default:UTC"` // IANA name; decides where the user's days begin
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
 }
 
 // RegisterRequest represents the data needed to register a new user
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
+	Username string `json:"username"` // defaults to the name
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
@@ -61,6 +70,29 @@ func (u *User) Validate() error {
 	return nil
 }
 
+// ValidateTimeZone checks that name is an IANA time zone such as "Europe/Berlin"
+func ValidateTimeZone(name string) error {
+	if name == "" || name == "Local" {
+		return errors.New("time_zone: must be an IANA time zone such as Europe/Berlin")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return errors.New("time_zone: must be an IANA time zone such as Europe/Berlin")
+	}
+	return nil
+}
+
+// Location returns the user's time zone, falling back to UTC if it is unset or unknown
+func (u *User) Location() *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 // UserProfile represents public user information
 type UserProfile struct {
 	ID        uint      `json:"id"`
@@ -8,16 +8,19 @@ import (
 // Vote represents a user's vote on a prediction
 type Vote struct {
 	ID           int       `json:"id" db:"id"`
-	UserID       int       `json:"user_id" db:"user_id"`
-	PredictionID int       `json:"prediction_id" db:"prediction_id"`
+	UserID       int       `json:"user_id" db:"user_id" gorm:"uniqueIndex:idx_votes_user_prediction"`
+	PredictionID int       `json:"prediction_id" db:"prediction_id" gorm:"uniqueIndex:idx_votes_user_prediction;index"`
 	Value        bool      `json:"value" db:"value"` // true = agree, false = disagree
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	// RemovedAt marks a withdrawn vote; the row is kept so voting again is not treated as new
+	RemovedAt *time.Time `json:"-" db:"removed_at"`
 }
 
-// VoteRequest represents the data needed to vote on a prediction
+// VoteRequest represents the data needed to vote on a prediction.
+// Value is not marked required because a disagree vote is its zero value.
 type VoteRequest struct {
-	Value bool `json:"value" binding:"required"`
+	Value bool `json:"value"`
 }
 
 // Validate performs validation on the vote model
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookEvent names an event that webhooks can subscribe to
+type WebhookEvent string
+
+const (
+	WebhookMarketCreated     WebhookEvent = "market.created"
+	WebhookMarketClosed      WebhookEvent = "market.closed"
+	WebhookMarketResolved    WebhookEvent = "market.resolved"
+	WebhookPredictionCreated WebhookEvent = "prediction.created"
+)
+
+// WebhookEvents lists every webhook event
+var WebhookEvents = []WebhookEvent{
+	WebhookMarketCreated,
+	WebhookMarketClosed,
+	WebhookMarketResolved,
+	WebhookPredictionCreated,
+}
+
+// IsValid reports whether the event is known
+func (e WebhookEvent) IsValid() bool {
+	for _, known := range WebhookEvents {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEventList is a set of events stored as a comma-separated column
+type WebhookEventList []WebhookEvent
+
+// Contains reports whether the list includes the event
+func (l WebhookEventList) Contains(event WebhookEvent) bool {
+	for _, e := range l {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements driver.Valuer
+func (l WebhookEventList) Value() (driver.Value, error) {
+	events := make([]string, len(l))
+	for i, e := range l {
+		events[i] = string(e)
+	}
+	return strings.Join(events, ","), nil
+}
+
+// Scan implements sql.Scanner
+func (l *WebhookEventList) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into WebhookEventList", value)
+	}
+
+	*l = nil
+	for _, e := range strings.Split(s, ",") {
+		if e != "" {
+			*l = append(*l, WebhookEvent(e))
+		}
+	}
+	return nil
+}
+
+// Webhook is a subscription that POSTs events to a URL.
+// A user's webhook receives events about the markets and predictions they created;
+// a global webhook, which only admins can create, receives events about everything.
+type Webhook struct {
+	ID        uint             `json:"id" db:"id"`
+	UserID    uint             `json:"user_id" db:"user_id" gorm:"index"`
+	URL       string           `json:"url" db:"url"`
+	Secret    string           `json:"-" db:"secret"` // only shown once, when the webhook is created
+	Events    WebhookEventList `json:"events" db:"events" gorm:"type:text"`
+	Global    bool             `json:"global" db:"global" gorm:"index"`
+	Active    bool             `json:"active" db:"active" gorm:"default:true"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// MarketWebhookPayload is the market sent with market events. It names the creator only by
+// ID so that receivers never see anything from their account.
+type MarketWebhookPayload struct {
+	ID          uint         `json:"id"`
+	Title       string       `json:"title"`
+	Category    string       `json:"category"`
+	Status      MarketStatus `json:"status"`
+	Outcome     *bool        `json:"outcome"`
+	CloseDate   time.Time    `json:"close_date"`
+	ResolveDate time.Time    `json:"resolve_date"`
+	ResolvedAt  *time.Time   `json:"resolved_at"`
+	CreatorID   uint         `json:"creator_id"`
+}
+
+// NewMarketWebhookPayload returns the webhook payload describing a market
+func NewMarketWebhookPayload(market *Market) MarketWebhookPayload {
+	return MarketWebhookPayload{
+		ID:          market.ID,
+		Title:       market.Title,
+		Category:    market.Category,
+		Status:      market.Status,
+		Outcome:     market.Outcome,
+		CloseDate:   market.CloseDate,
+		ResolveDate: market.ResolveDate,
+		ResolvedAt:  market.ResolvedAt,
+		CreatorID:   market.CreatorID,
+	}
+}
+
+// WebhookRequest represents the data needed to create a webhook
+type WebhookRequest struct {
+	URL    string         `json:"url" binding:"required"`
+	Events []WebhookEvent `json:"events" binding:"required"`
+	Global bool           `json:"global"`
+}
+
+// Validate performs validation on the webhook request
+func (r *WebhookRequest) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url: must be an absolute http or https URL")
+	}
+
+	if len(r.Events) == 0 {
+		return errors.New("events: at least one event is required")
+	}
+	for _, event := range r.Events {
+		if !event.IsValid() {
+			return fmt.Errorf("events: unknown event %q", event)
+		}
+	}
+
+	return nil
+}
+
+// WebhookDeliveryStatus is the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent to one webhook, retried until it succeeds or gives up
+type WebhookDelivery struct {
+	ID            uint                  `json:"id" db:"id"`
+	WebhookID     uint                  `json:"webhook_id" db:"webhook_id" gorm:"index"`
+	Event         WebhookEvent          `json:"event" db:"event"`
+	Payload       string                `json:"payload" db:"payload" gorm:"type:text"`
+	Status        WebhookDeliveryStatus `json:"status" db:"status" gorm:"index:idx_deliveries_due,priority:1"`
+	Attempts      int                   `json:"attempts" db:"attempts"`
+	ResponseCode  int                   `json:"response_code" db:"response_code"`
+	Error         string                `json:"error,omitempty" db:"error"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at,omitempty" db:"next_attempt_at" gorm:"index:idx_deliveries_due,priority:2"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt     time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookAttempt logs a single HTTP attempt of a delivery
+type WebhookAttempt struct {
+	ID           uint      `json:"id" db:"id"`
+	DeliveryID   uint      `json:"delivery_id" db:"delivery_id" gorm:"index"`
+	ResponseCode int       `json:"response_code" db:"response_code"`
+	Error        string    `json:"error,omitempty" db:"error"`
+	DurationMS   int64     `json:"duration_ms" db:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,187 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// MaxPostgresPayload is the largest payload Postgres NOTIFY accepts
+const MaxPostgresPayload = 7999
+
+// ErrPayloadTooLarge is returned when a message is too large for NOTIFY
+var ErrPayloadTooLarge = errors.New("payload too large for postgres notify")
+
+// Postgres is a PubSub built on Postgres LISTEN/NOTIFY, so every server instance connected
+// to the same database receives every message, including the ones it published itself.
+type Postgres struct {
+	registry
+
+	db  *gorm.DB
+	dsn string
+
+	// wake interrupts the listener so it can LISTEN to newly subscribed topics
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	closeOnce sync.Once
+}
+
+// NewPostgres creates a Postgres PubSub. Messages are published through db, and a dedicated
+// connection opened with dsn listens for them, reconnecting if it drops.
+func NewPostgres(db *gorm.DB, dsn string) *Postgres {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p := &Postgres{
+		registry: newRegistry(),
+		db:       db,
+		dsn:      dsn,
+		wake:     make(chan struct{}, 1),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go p.listen(ctx)
+
+	return p
+}
+
+// Publish sends the message to every instance with pg_notify
+func (p *Postgres) Publish(ctx context.Context, topic string, payload []byte) error {
+	select {
+	case <-p.done:
+		return ErrClosed
+	default:
+	}
+
+	if len(payload) > MaxPostgresPayload {
+		return ErrPayloadTooLarge
+	}
+	return p.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", topic, string(payload)).Error
+}
+
+// Subscribe registers a handler for a topic, listening to it if it is new
+func (p *Postgres) Subscribe(topic string, handler Handler) func() {
+	id, first := p.add(topic, handler)
+	if first {
+		p.signal()
+	}
+	return func() {
+		p.remove(topic, id)
+		p.signal()
+	}
+}
+
+// Close stops the listener and waits for it to exit
+func (p *Postgres) Close() error {
+	p.closeOnce.Do(func() {
+		p.cancel()
+		<-p.done
+	})
+	return nil
+}
+
+// signal wakes the listener without blocking; one pending wake-up is enough
+func (p *Postgres) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// listen keeps a listening connection open until the PubSub is closed
+func (p *Postgres) listen(ctx context.Context) {
+	defer close(p.done)
+
+	backoff := time.Second
+	for {
+		connected, err := p.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = time.Second
+		}
+
+		log.Printf("Pub/sub listener disconnected: %v; reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// listenOnce opens a connection and dispatches notifications until it fails.
+// It reports whether the connection was established, so the caller can reset its backoff.
+func (p *Postgres) listenOnce(ctx context.Context) (bool, error) {
+	conn, err := pgx.Connect(ctx, p.dsn)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	// Topics are per connection, so a new connection starts from nothing
+	listening := make(map[string]bool)
+
+	for {
+		if err := p.syncTopics(ctx, conn, listening); err != nil {
+			return true, err
+		}
+
+		waitCtx, stopWaiting := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-p.wake:
+				stopWaiting()
+			case <-waitCtx.Done():
+			}
+		}()
+
+		notification, err := conn.WaitForNotification(waitCtx)
+		stopWaiting()
+
+		if err != nil {
+			// A wake-up cancels the wait but leaves the connection usable
+			if ctx.Err() == nil && waitCtx.Err() != nil {
+				continue
+			}
+			return true, err
+		}
+		p.dispatch(notification.Channel, []byte(notification.Payload))
+	}
+}
+
+// syncTopics issues LISTEN and UNLISTEN so the connection matches the subscribed topics
+func (p *Postgres) syncTopics(ctx context.Context, conn *pgx.Conn, listening map[string]bool) error {
+	wanted := make(map[string]bool)
+	for _, topic := range p.topics() {
+		wanted[topic] = true
+		if !listening[topic] {
+			if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{topic}.Sanitize()); err != nil {
+				return fmt.Errorf("listen %s: %w", topic, err)
+			}
+			listening[topic] = true
+		}
+	}
+
+	for topic := range listening {
+		if !wanted[topic] {
+			if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{topic}.Sanitize()); err != nil {
+				return fmt.Errorf("unlisten %s: %w", topic, err)
+			}
+			delete(listening, topic)
+		}
+	}
+
+	return nil
+}
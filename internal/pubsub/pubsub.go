@@ -0,0 +1,126 @@
+// Package pubsub fans messages out to subscribers, either within one process or across
+// every server instance sharing the database.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when publishing to a closed PubSub
+var ErrClosed = errors.New("pubsub is closed")
+
+// Handler receives the payload of each message published to a topic.
+// Handlers must not block; slow work should be handed off to another goroutine.
+type Handler func(payload []byte)
+
+// PubSub delivers messages published on a topic to every handler subscribed to that topic
+type PubSub interface {
+	// Publish sends a message to the topic's subscribers
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe registers a handler for a topic and returns a function that removes it
+	Subscribe(topic string, handler Handler) (unsubscribe func())
+	// Close stops delivery and releases any connections
+	Close() error
+}
+
+// registry tracks subscribed handlers by topic; it is shared by the implementations
+type registry struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers map[string]map[uint64]Handler
+}
+
+func newRegistry() registry {
+	return registry{handlers: make(map[string]map[uint64]Handler)}
+}
+
+// add registers a handler and reports whether it is the topic's first
+func (r *registry) add(topic string, handler Handler) (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	first := len(r.handlers[topic]) == 0
+	if first {
+		r.handlers[topic] = make(map[uint64]Handler)
+	}
+	r.handlers[topic][r.nextID] = handler
+	return r.nextID, first
+}
+
+func (r *registry) remove(topic string, id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.handlers[topic], id)
+	if len(r.handlers[topic]) == 0 {
+		delete(r.handlers, topic)
+	}
+}
+
+// topics returns the topics that currently have subscribers
+func (r *registry) topics() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	topics := make([]string, 0, len(r.handlers))
+	for topic := range r.handlers {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// dispatch calls every handler subscribed to the topic
+func (r *registry) dispatch(topic string, payload []byte) {
+	r.mu.RLock()
+	handlers := make([]Handler, 0, len(r.handlers[topic]))
+	for _, handler := range r.handlers[topic] {
+		handlers = append(handlers, handler)
+	}
+	r.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(payload)
+	}
+}
+
+// Memory is a PubSub that only delivers within the current process.
+// It suits single-instance deployments and tests.
+type Memory struct {
+	registry
+	closed bool
+}
+
+// NewMemory creates an in-process PubSub
+func NewMemory() *Memory {
+	return &Memory{registry: newRegistry()}
+}
+
+// Publish delivers the message synchronously to the topic's handlers
+func (m *Memory) Publish(ctx context.Context, topic string, payload []byte) error {
+	m.mu.RLock()
+	closed := m.closed
+	m.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	m.dispatch(topic, payload)
+	return nil
+}
+
+// Subscribe registers a handler for a topic
+func (m *Memory) Subscribe(topic string, handler Handler) func() {
+	id, _ := m.add(topic, handler)
+	return func() { m.remove(topic, id) }
+}
+
+// Close stops further publishing
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
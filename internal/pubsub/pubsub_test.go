@@ -0,0 +1,57 @@
+// pubsub_test.go
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPubSub(t *testing.T) {
+	ps := NewMemory()
+
+	var first, second []string
+	unsubscribe := ps.Subscribe("events", func(payload []byte) {
+		first = append(first, string(payload))
+	})
+	ps.Subscribe("events", func(payload []byte) {
+		second = append(second, string(payload))
+	})
+	ps.Subscribe("other", func(payload []byte) {
+		t.Errorf("unexpected message on other topic: %s", payload)
+	})
+
+	assert.NoError(t, ps.Publish(context.Background(), "events", []byte("one")))
+	unsubscribe()
+	assert.NoError(t, ps.Publish(context.Background(), "events", []byte("two")))
+
+	assert.Equal(t, []string{"one"}, first)
+	assert.Equal(t, []string{"one", "two"}, second)
+
+	assert.NoError(t, ps.Close())
+	assert.ErrorIs(t, ps.Publish(context.Background(), "events", []byte("three")), ErrClosed)
+}
+
+func TestRegistryTopics(t *testing.T) {
+	r := newRegistry()
+
+	id, first := r.add("a", func([]byte) {})
+	assert.True(t, first)
+	_, first = r.add("a", func([]byte) {})
+	assert.False(t, first)
+
+	r.add("b", func([]byte) {})
+	assert.ElementsMatch(t, []string{"a", "b"}, r.topics())
+
+	r.remove("b", 3)
+	r.remove("a", id)
+	assert.Equal(t, []string{"a"}, r.topics())
+}
+
+func TestPostgresPayloadLimit(t *testing.T) {
+	ps := &Postgres{registry: newRegistry(), done: make(chan struct{})}
+
+	err := ps.Publish(context.Background(), "events", make([]byte, MaxPostgresPayload+1))
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+}
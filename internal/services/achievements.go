@@ -0,0 +1,279 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AchievementFacts is what rules are evaluated against. Facts that are expensive to load are
+// only filled in for the events whose rules need them.
+type AchievementFacts struct {
+	Stats       models.UserStats
+	Calibration CalibrationReport // loaded on market resolution
+	MonthlyRank int               // rank on the final leaderboard of a month that closed; 0 when unranked or not loaded
+}
+
+// AchievementRule defines an achievement and when it is earned
+type AchievementRule struct {
+	Key         models.AchievementKey
+	Name        string
+	Description string
+	Events      []models.AchievementEvent // events after which the rule is checked
+	Earned      func(facts *AchievementFacts) bool
+}
+
+// Achievement thresholds
+const (
+	hotStreakLength            = 10
+	wellCalibratedMinForecasts = 50
+	wellCalibratedMaxError     = 0.05
+	monthlyTopRank             = 10
+)
+
+// AchievementRules lists every achievement. Add a rule here to add an achievement.
+var AchievementRules = []AchievementRule{
+	{
+		Key:         models.AchievementFirstForecast,
+		Name:        "First Forecast",
+		Description: "Made your first forecast",
+		Events:      []models.AchievementEvent{models.AchievementForecastCreated},
+		Earned: func(facts *AchievementFacts) bool {
+			return facts.Stats.TotalPredictions >= 1
+		},
+	},
+	{
+		Key:         models.AchievementHotStreak,
+		Name:        "Hot Streak",
+		Description: fmt.Sprintf("Got %d predictions in a row right", hotStreakLength),
+		Events:      []models.AchievementEvent{models.AchievementMarketResolved},
+		Earned: func(facts *AchievementFacts) bool {
+			return facts.Stats.LongestStreak >= hotStreakLength
+		},
+	},
+	{
+		Key:         models.AchievementWellCalibrated,
+		Name:        "Well Calibrated",
+		Description: fmt.Sprintf("Kept calibration error under %.0f%% over %d resolved predictions", wellCalibratedMaxError*100, wellCalibratedMinForecasts),
+		Events:      []models.AchievementEvent{models.AchievementMarketResolved},
+		Earned: func(facts *AchievementFacts) bool {
+			return facts.Calibration.Count >= wellCalibratedMinForecasts &&
+				facts.Calibration.CalibrationError <= wellCalibratedMaxError
+		},
+	},
+	{
+		Key:         models.AchievementMonthlyTop10,
+		Name:        "Monthly Top 10",
+		Description: fmt.Sprintf("Finished a month in the top %d of the monthly leaderboard", monthlyTopRank),
+		Events:      []models.AchievementEvent{models.AchievementMonthClosed},
+		Earned: func(facts *AchievementFacts) bool {
+			return facts.MonthlyRank > 0 && facts.MonthlyRank <= monthlyTopRank
+		},
+	},
+}
+
+// AchievementRuleFor returns the rule defining an achievement
+func AchievementRuleFor(key models.AchievementKey) (AchievementRule, bool) {
+	for _, rule := range AchievementRules {
+		if rule.Key == key {
+			return rule, true
+		}
+	}
+	return AchievementRule{}, false
+}
+
+// EvaluateAchievements returns the rules checked after event that the facts satisfy
+func EvaluateAchievements(event models.AchievementEvent, facts *AchievementFacts) []AchievementRule {
+	var earned []AchievementRule
+	for _, rule := range AchievementRules {
+		if triggeredBy(rule, event) && rule.Earned(facts) {
+			earned = append(earned, rule)
+		}
+	}
+	return earned
+}
+
+// CheckAchievements awards the users any achievements they earned through event and
+// notifies them. Achievements are a side channel, so failures are logged rather than returned.
+func CheckAchievements(db *gorm.DB, event models.AchievementEvent, userIDs ...uint) {
+	for _, userID := range userIDs {
+		checkUserAchievements(db, event, userID, 0)
+	}
+}
+
+// AwardMonthlyAchievements checks the achievements earned on the final leaderboard of the
+// month before now's, for the users at its top, and returns how many users were checked.
+// Each achievement is only awarded once, so checking a month again changes nothing.
+func AwardMonthlyAchievements(db *gorm.DB, now time.Time) (int, error) {
+	thisMonth := time.Date(now.UTC().Year(), now.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	ranks, err := topMonthlyRanks(db, thisMonth.AddDate(0, -1, 0))
+	if err != nil {
+		return 0, err
+	}
+
+	userIDs := make([]uint, 0, len(ranks))
+	for userID := range ranks {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	for _, userID := range userIDs {
+		checkUserAchievements(db, models.AchievementMonthClosed, userID, ranks[userID])
+	}
+	return len(userIDs), nil
+}
+
+// checkUserAchievements awards one user the achievements they earned through event.
+// monthlyRank is the user's rank on a month's final leaderboard when a month closed.
+func checkUserAchievements(db *gorm.DB, event models.AchievementEvent, userID uint, monthlyRank int) {
+	var owned []models.AchievementKey
+	if err := db.Model(&models.UserAchievement{}).Where("user_id = ?", userID).Pluck("key", &owned).Error; err != nil {
+		log.Printf("Failed to load achievements of user %d: %v", userID, err)
+		return
+	}
+	if !anyUnearned(event, owned) {
+		return
+	}
+
+	facts, err := loadAchievementFacts(db, event, userID)
+	if err != nil {
+		log.Printf("Failed to load achievement facts for user %d: %v", userID, err)
+		return
+	}
+	facts.MonthlyRank = monthlyRank
+
+	for _, rule := range EvaluateAchievements(event, facts) {
+		if hasAchievement(owned, rule.Key) {
+			continue
+		}
+		if err := awardAchievement(db, userID, rule); err != nil {
+			log.Printf("Failed to award %s to user %d: %v", rule.Key, userID, err)
+		}
+	}
+}
+
+// UserAchievements returns the achievements a user has earned, earliest first
+func UserAchievements(db *gorm.DB, userID uint) ([]models.EarnedAchievement, error) {
+	var rows []models.UserAchievement
+	if err := db.Where("user_id = ?", userID).Order("earned_at asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	earned := make([]models.EarnedAchievement, 0, len(rows))
+	for _, row := range rows {
+		rule, ok := AchievementRuleFor(row.Key)
+		if !ok {
+			// The rule was retired; keep the record but stop showing it
+			continue
+		}
+		earned = append(earned, models.EarnedAchievement{
+			Key:         rule.Key,
+			Name:        rule.Name,
+			Description: rule.Description,
+			EarnedAt:    row.EarnedAt,
+		})
+	}
+	return earned, nil
+}
+
+// triggeredBy reports whether the rule is checked after event
+func triggeredBy(rule AchievementRule, event models.AchievementEvent) bool {
+	for _, e := range rule.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// anyUnearned reports whether a rule checked after event is still to be earned
+func anyUnearned(event models.AchievementEvent, owned []models.AchievementKey) bool {
+	for _, rule := range AchievementRules {
+		if triggeredBy(rule, event) && !hasAchievement(owned, rule.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAchievement reports whether key is among the owned achievements
+func hasAchievement(owned []models.AchievementKey, key models.AchievementKey) bool {
+	for _, k := range owned {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAchievementFacts loads the facts the rules checked after event need
+func loadAchievementFacts(db *gorm.DB, event models.AchievementEvent, userID uint) (*AchievementFacts, error) {
+	stats, err := GetUserStats(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	facts := &AchievementFacts{Stats: stats}
+
+	if event == models.AchievementMarketResolved && stats.ResolvedPredictions >= wellCalibratedMinForecasts {
+		samples, err := LoadCalibrationSamples(db, userID, models.CalibrationFilter{})
+		if err != nil {
+			return nil, err
+		}
+		facts.Calibration = ComputeCalibration(samples, DefaultCalibrationBuckets)
+	}
+
+	return facts, nil
+}
+
+// topMonthlyRanks returns the ranks of the users in the top ranks of the leaderboard of the
+// month starting at month
+func topMonthlyRanks(db *gorm.DB, month time.Time) (map[uint]int, error) {
+	from, to, err := ParseLeaderboardWindow("month", month.Format("2006-01"), month)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ties can push more users than monthlyTopRank into the top ranks, so fetch a few extra
+	entries, _, _, err := GetLeaderboard(db, models.LeaderboardFilter{From: from, To: to}, 1, monthlyTopRank*5, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make(map[uint]int, len(entries))
+	for _, entry := range entries {
+		if entry.Rank <= monthlyTopRank {
+			ranks[entry.UserID] = entry.Rank
+		}
+	}
+	return ranks, nil
+}
+
+// awardAchievement records the achievement and notifies the user in one transaction.
+// Achievements already earned are skipped silently.
+func awardAchievement(db *gorm.DB, userID uint, rule AchievementRule) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		achievement := models.UserAchievement{UserID: userID, Key: rule.Key, EarnedAt: time.Now()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&achievement)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		notification := AchievementNotification(userID, rule)
+		return CreateNotification(tx, &notification)
+	})
+}
+
+// AchievementNotification tells a user they earned the rule's achievement
+func AchievementNotification(userID uint, rule AchievementRule) models.Notification {
+	return models.Notification{
+		UserID:  int(userID),
+		Type:    models.NotificationAchievement,
+		Message: fmt.Sprintf("You earned the \"%s\" badge: %s", rule.Name, rule.Description),
+		Link:    fmt.Sprintf("/users/%d", userID),
+	}
+}
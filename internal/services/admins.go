@@ -0,0 +1,26 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+)
+
+// SetAdmin grants or revokes admin rights for the user with the given email.
+// Admins can create tournaments, daily challenges and global webhooks, and resolve overdue markets.
+func SetAdmin(db *gorm.DB, email string, admin bool) (*models.User, error) {
+	var user models.User
+	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := db.Model(&user).Update("is_admin", admin).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"math"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultCalibrationBuckets is the number of probability buckets used when none is requested
+const DefaultCalibrationBuckets = 10
+
+// CalibrationBucket summarises the forecasts whose probability falls in [Lower, Upper)
+type CalibrationBucket struct {
+	Lower             float64 `json:"lower"`
+	Upper             float64 `json:"upper"`
+	Count             int     `json:"count"`
+	MeanProbability   float64 `json:"mean_probability"`
+	ObservedFrequency float64 `json:"observed_frequency"`
+}
+
+// CalibrationReport describes how well a user's stated probabilities match observed outcomes.
+// BrierScore = Reliability - Resolution + Uncertainty (Murphy decomposition over the buckets).
+type CalibrationReport struct {
+	Count            int                 `json:"count"`
+	Buckets          []CalibrationBucket `json:"buckets"`
+	CalibrationError float64             `json:"calibration_error"` // count-weighted mean |probability - frequency|
+	BrierScore       float64             `json:"brier_score"`
+	Reliability      float64             `json:"reliability"`
+	Resolution       float64             `json:"resolution"`
+	Uncertainty      float64             `json:"uncertainty"`
+}
+
+// LoadCalibrationSamples returns the user's forecasts on resolved markets matching filter
+func LoadCalibrationSamples(db *gorm.DB, userID uint, filter models.CalibrationFilter) ([]models.CalibrationSample, error) {
+	query := db.Model(&models.Forecast{}).
+		Select("forecasts.prediction, forecasts.confidence, markets.outcome").
+		Joins("JOIN markets ON forecasts.market_id = markets.id").
+		Where("forecasts.user_id = ? AND forecasts.withdrawn_at IS NULL", userID).
+		Where("markets.status = ? AND markets.outcome IS NOT NULL", models.MarketResolved)
+
+	if filter.Category != "" {
+		query = query.Where("markets.category = ?", filter.Category)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("forecasts.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("forecasts.created_at <= ?", filter.To)
+	}
+
+	var rows []struct {
+		Prediction bool
+		Confidence float64
+		Outcome    bool
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	samples := make([]models.CalibrationSample, 0, len(rows))
+	for _, row := range rows {
+		forecast := models.Forecast{Prediction: row.Prediction, Confidence: row.Confidence}
+		samples = append(samples, models.CalibrationSample{Probability: forecast.Probability(), Outcome: row.Outcome})
+	}
+
+	return samples, nil
+}
+
+// ComputeCalibration buckets samples into equal-width probability buckets and scores them
+func ComputeCalibration(samples []models.CalibrationSample, buckets int) CalibrationReport {
+	if buckets <= 0 {
+		buckets = DefaultCalibrationBuckets
+	}
+
+	report := CalibrationReport{
+		Count:   len(samples),
+		Buckets: make([]CalibrationBucket, buckets),
+	}
+	width := 1.0 / float64(buckets)
+	for i := range report.Buckets {
+		report.Buckets[i].Lower = float64(i) * width
+		report.Buckets[i].Upper = float64(i+1) * width
+	}
+	if len(samples) == 0 {
+		return report
+	}
+
+	// Accumulate per-bucket sums
+	probabilitySums := make([]float64, buckets)
+	outcomeSums := make([]float64, buckets)
+	var outcomeTotal, brierTotal float64
+	for _, sample := range samples {
+		p := math.Min(math.Max(sample.Probability, 0), 1)
+		o := 0.0
+		if sample.Outcome {
+			o = 1
+		}
+
+		i := int(p * float64(buckets))
+		if i == buckets {
+			i-- // p == 1 belongs in the last bucket
+		}
+
+		report.Buckets[i].Count++
+		probabilitySums[i] += p
+		outcomeSums[i] += o
+		outcomeTotal += o
+		brierTotal += (p - o) * (p - o)
+	}
+
+	n := float64(len(samples))
+	baseRate := outcomeTotal / n
+	report.BrierScore = brierTotal / n
+	report.Uncertainty = baseRate * (1 - baseRate)
+
+	for i := range report.Buckets {
+		bucket := &report.Buckets[i]
+		if bucket.Count == 0 {
+			continue
+		}
+
+		count := float64(bucket.Count)
+		bucket.MeanProbability = probabilitySums[i] / count
+		bucket.ObservedFrequency = outcomeSums[i] / count
+
+		gap := bucket.MeanProbability - bucket.ObservedFrequency
+		report.CalibrationError += count / n * math.Abs(gap)
+		report.Reliability += count / n * gap * gap
+		report.Resolution += count / n * (bucket.ObservedFrequency - baseRate) * (bucket.ObservedFrequency - baseRate)
+	}
+
+	return report
+}
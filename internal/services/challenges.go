@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Daily challenge errors
+var (
+	ErrChallengeScheduled = errors.New("a challenge is already scheduled for that day")
+	ErrChallengeMarket    = errors.New("market_id: market does not exist or is not open")
+)
+
+// ScheduleDailyChallenge highlights an open market as the challenge for a day
+func ScheduleDailyChallenge(db *gorm.DB, input *models.DailyChallengeRequest, creatorID uint) (*models.DailyChallenge, error) {
+	var market models.Market
+	result := db.Where("id = ? AND status = ?", input.MarketID, models.MarketOpen).Limit(1).Find(&market)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrChallengeMarket
+	}
+
+	challenge := models.DailyChallenge{
+		Day:       input.Day,
+		MarketID:  market.ID,
+		Market:    market,
+		Note:      input.Note,
+		CreatorID: creatorID,
+		CreatedAt: time.Now(),
+	}
+	result = db.Omit("Market").Clauses(clause.OnConflict{DoNothing: true}).Create(&challenge)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrChallengeScheduled
+	}
+	return &challenge, nil
+}
+
+// DailyChallengeFor returns the challenge scheduled for a day, or nil if there is none
+func DailyChallengeFor(db *gorm.DB, day string) (*models.DailyChallenge, error) {
+	var challenges []models.DailyChallenge
+	if err := db.Preload("Market").Where("day = ?", day).Limit(1).Find(&challenges).Error; err != nil {
+		return nil, err
+	}
+	if len(challenges) == 0 {
+		return nil, nil
+	}
+	return &challenges[0], nil
+}
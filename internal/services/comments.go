@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+)
+
+// CommentEditWindow is how long after posting a comment its author may edit it
+var CommentEditWindow = time.Duration(utils.GetEnvInt("COMMENT_EDIT_WINDOW_MINUTES", 15)) * time.Minute
+
+// mentionPattern matches @username, using the same length limits as registration
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w{3,30})\b`)
+
+// ExtractMentions returns the distinct usernames mentioned in text, in order of appearance
+func ExtractMentions(text string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := match[1]
+		key := strings.ToLower(username)
+		if !seen[key] {
+			seen[key] = true
+			usernames = append(usernames, username)
+		}
+	}
+
+	return usernames
+}
+
+// NotifyMentions sends a mention notification to every user mentioned in the comment,
+// skipping the author and anyone listed in alreadyNotified (e.g. mentions before an edit)
+func NotifyMentions(db *gorm.DB, author models.User, market models.Market, comment models.Comment, alreadyNotified []string) error {
+	usernames := ExtractMentions(comment.Body)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	skip := make(map[string]bool)
+	for _, username := range alreadyNotified {
+		skip[strings.ToLower(username)] = true
+	}
+
+	var users []models.User
+	if err := db.Where("username IN ?", usernames).Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if user.ID == author.ID || skip[strings.ToLower(user.Username)] {
+			continue
+		}
+
+		notification := MentionNotification(author, market, comment, user.ID)
+		if err := CreateNotification(db, &notification); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MentionNotification tells a user they were mentioned in a comment
+func MentionNotification(author models.User, market models.Market, comment models.Comment, userID uint) models.Notification {
+	return models.Notification{
+		UserID:  int(userID),
+		Type:    models.NotificationMention,
+		Message: fmt.Sprintf("%s mentioned you in a comment on \"%s\"", author.Username, market.Title),
+		Link:    fmt.Sprintf("/markets/%d#comment-%d", market.ID, comment.ID),
+	}
+}
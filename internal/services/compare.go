@@ -0,0 +1,262 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+)
+
+// significanceLevel is the p-value below which a Brier score difference is called significant
+const significanceLevel = 0.05
+
+// ForecastPair is both users' forecasts on a market they both forecast on
+type ForecastPair struct {
+	Market models.Market
+	A      models.Forecast
+	B      models.Forecast
+}
+
+// CompareForecasters finds the markets both users have active forecasts on and compares them.
+// Markets whose forecasts are still hidden from the viewer are left out.
+func CompareForecasters(db *gorm.DB, userID, otherID, viewerID uint) (models.HeadToHead, error) {
+	shared := db.Model(&models.Forecast{}).Select("market_id").Where("user_id = ? AND withdrawn_at IS NULL", otherID)
+
+	var forecasts []models.Forecast
+	err := db.Preload("Market").
+		Where("user_id = ? AND withdrawn_at IS NULL AND market_id IN (?)", userID, shared).
+		Order("market_id asc").
+		Find(&forecasts).Error
+	if err != nil {
+		return models.HeadToHead{}, err
+	}
+
+	var others []models.Forecast
+	marketIDs := make([]uint, len(forecasts))
+	for i, forecast := range forecasts {
+		marketIDs[i] = forecast.MarketID
+	}
+	if len(marketIDs) > 0 {
+		if err := db.Where("user_id = ? AND withdrawn_at IS NULL AND market_id IN ?", otherID, marketIDs).Find(&others).Error; err != nil {
+			return models.HeadToHead{}, err
+		}
+	}
+	otherByMarket := make(map[uint]models.Forecast, len(others))
+	for _, forecast := range others {
+		otherByMarket[forecast.MarketID] = forecast
+	}
+
+	// Both users have forecast on every shared market, so only a third party can be hidden from
+	viewerPredicted := map[uint]bool{}
+	if viewerID != userID && viewerID != otherID && len(marketIDs) > 0 {
+		var predicted []uint
+		err := db.Model(&models.Forecast{}).Where("user_id = ? AND market_id IN ?", viewerID, marketIDs).Pluck("market_id", &predicted).Error
+		if err != nil {
+			return models.HeadToHead{}, err
+		}
+		for _, id := range predicted {
+			viewerPredicted[id] = true
+		}
+	}
+
+	now := time.Now()
+	pairs := make([]ForecastPair, 0, len(forecasts))
+	for _, forecast := range forecasts {
+		other, ok := otherByMarket[forecast.MarketID]
+		if !ok {
+			continue
+		}
+		market := forecast.Market
+		hidden := market.HidesForecasts(HideForecastsByDefault) && !market.IsClosed(now)
+		if hidden && viewerID != userID && viewerID != otherID && !viewerPredicted[market.ID] {
+			continue
+		}
+		pairs = append(pairs, ForecastPair{Market: market, A: forecast, B: other})
+	}
+
+	return CompareForecasts(pairs), nil
+}
+
+// CompareForecasts builds the head-to-head comparison of paired forecasts
+func CompareForecasts(pairs []ForecastPair) models.HeadToHead {
+	result := models.HeadToHead{Markets: make([]models.ComparedMarket, 0, len(pairs)), Shared: len(pairs)}
+
+	var brierDiffs []float64
+	var userBrierTotal, otherBrierTotal float64
+	for i := range pairs {
+		pair := &pairs[i]
+		compared := models.ComparedMarket{
+			MarketID: pair.Market.ID,
+			Title:    pair.Market.Title,
+			Status:   pair.Market.Status,
+			Outcome:  pair.Market.Outcome,
+			User:     comparedForecast(&pair.A),
+			Other:    comparedForecast(&pair.B),
+		}
+
+		if pair.Market.Status == models.MarketResolved && pair.Market.Outcome != nil {
+			outcome := *pair.Market.Outcome
+			userScore, otherScore := ForecastScore(&pair.A, outcome), ForecastScore(&pair.B, outcome)
+			userBrier, otherBrier := brierScore(&pair.A, outcome), brierScore(&pair.B, outcome)
+			diff := userScore - otherScore
+
+			compared.User.Score, compared.User.Brier = &userScore, &userBrier
+			compared.Other.Score, compared.Other.Brier = &otherScore, &otherBrier
+			compared.ScoreDiff = &diff
+
+			result.Resolved++
+			result.ScoreDiff += diff
+			switch {
+			case math.Abs(diff) < 1e-9:
+				result.Ties++
+			case diff > 0:
+				result.Wins++
+			default:
+				result.Losses++
+			}
+
+			userBrierTotal += userBrier
+			otherBrierTotal += otherBrier
+			brierDiffs = append(brierDiffs, userBrier-otherBrier)
+		}
+
+		result.Markets = append(result.Markets, compared)
+	}
+
+	if result.Resolved > 0 {
+		result.UserMeanBrier = userBrierTotal / float64(result.Resolved)
+		result.OtherMeanBrier = otherBrierTotal / float64(result.Resolved)
+	}
+	result.BrierTest = PairedTTest(brierDiffs)
+	return result
+}
+
+// PairedTTest tests whether the mean of paired differences is zero
+func PairedTTest(diffs []float64) models.PairedTest {
+	test := models.PairedTest{N: len(diffs)}
+	if test.N == 0 {
+		return test
+	}
+
+	for _, d := range diffs {
+		test.MeanDifference += d
+	}
+	test.MeanDifference /= float64(test.N)
+	if test.N < 2 {
+		return test
+	}
+
+	var squares float64
+	for _, d := range diffs {
+		squares += (d - test.MeanDifference) * (d - test.MeanDifference)
+	}
+	test.StdDev = math.Sqrt(squares / float64(test.N-1))
+	if test.StdDev == 0 {
+		return test
+	}
+
+	t := test.MeanDifference / (test.StdDev / math.Sqrt(float64(test.N)))
+	p := studentTTwoSided(t, float64(test.N-1))
+	test.TStatistic = &t
+	test.PValue = &p
+	test.Significant = p < significanceLevel
+	return test
+}
+
+// comparedForecast describes one user's forecast in a comparison
+func comparedForecast(forecast *models.Forecast) models.ComparedForecast {
+	return models.ComparedForecast{
+		Prediction:  forecast.Prediction,
+		Confidence:  forecast.Confidence,
+		Probability: forecast.Probability(),
+	}
+}
+
+// brierScore is the squared error of the forecast's probability against the outcome
+func brierScore(forecast *models.Forecast, outcome bool) float64 {
+	o := 0.0
+	if outcome {
+		o = 1
+	}
+	p := forecast.Probability()
+	return (p - o) * (p - o)
+}
+
+// studentTTwoSided returns P(|T| >= |t|) for Student's t distribution with df degrees of freedom
+func studentTTwoSided(t, df float64) float64 {
+	return regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) with the continued fraction from Numerical Recipes
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only on this side of the mean
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction for the incomplete beta function
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+		tiny          = 1e-300
+	)
+
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+
+		// Even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// Odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
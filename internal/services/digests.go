@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/domolitom/reThink/internal/mailer"
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+)
+
+// AppURL is the public base URL used for links in emails
+var AppURL = strings.TrimRight(utils.GetEnvString("APP_URL", "http://localhost:8080"), "/")
+
+// MaxDigestItems caps how many notifications are listed in one digest
+const MaxDigestItems = 50
+
+// DigestDue reports whether a user on the given frequency is due a digest
+func DigestDue(frequency models.DigestFrequency, last *time.Time, now time.Time) bool {
+	interval := frequency.Interval()
+	if interval == 0 {
+		return false
+	}
+	return last == nil || !now.Before(last.Add(interval))
+}
+
+// UnsubscribeURL returns the one-click unsubscribe link for a user and scope
+func UnsubscribeURL(userID int, scope string) string {
+	return AppURL + "/api/notifications/unsubscribe?token=" + url.QueryEscape(utils.SignUnsubscribeToken(userID, scope))
+}
+
+// BuildDigest composes the digest email listing a user's unread notifications
+func BuildDigest(user models.User, notifications []models.Notification) mailer.Message {
+	var b strings.Builder
+
+	name := user.Name
+	if name == "" {
+		name = user.Username
+	}
+	fmt.Fprintf(&b, "Hi %s,\n\nHere is what you missed on reThink:\n\n", name)
+
+	types := make(map[models.NotificationType]bool)
+	for i, notification := range notifications {
+		types[notification.Type] = true
+		if i >= MaxDigestItems {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s\n", notification.Message)
+		if notification.Link != "" {
+			fmt.Fprintf(&b, "  %s%s\n", AppURL, notification.Link)
+		}
+	}
+	if extra := len(notifications) - MaxDigestItems; extra > 0 {
+		fmt.Fprintf(&b, "\n...and %d more in your inbox: %s/notifications\n", extra, AppURL)
+	}
+
+	b.WriteString("\n--\nYou receive this digest because of your notification settings.\n")
+	for _, t := range models.NotificationTypes {
+		if types[t] {
+			fmt.Fprintf(&b, "Stop emailing %s notifications: %s\n", strings.ReplaceAll(string(t), "_", " "), UnsubscribeURL(int(user.ID), string(t)))
+		}
+	}
+	unsubscribe := UnsubscribeURL(int(user.ID), UnsubscribeDigests)
+	fmt.Fprintf(&b, "Unsubscribe from all digests: %s\n", unsubscribe)
+
+	subject := fmt.Sprintf("Your reThink digest: %d new notifications", len(notifications))
+	if len(notifications) == 1 {
+		subject = "Your reThink digest: 1 new notification"
+	}
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    b.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribe + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}
+}
+
+// SendDigests emails every user who is due a digest their unread notifications of email
+// channel types that have not been emailed before, and returns how many digests were sent
+func SendDigests(ctx context.Context, db *gorm.DB, m mailer.Mailer, now time.Time) (int, error) {
+	var users []models.User
+	err := db.Where("digest_frequency = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", models.DigestDaily, now.Add(-models.DigestDaily.Interval())).
+		Or("digest_frequency = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", models.DigestWeekly, now.Add(-models.DigestWeekly.Interval())).
+		Find(&users).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+
+		ok, err := sendDigest(ctx, db, m, user, now)
+		if err != nil {
+			log.Printf("Failed to send digest to user %d: %v", user.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// sendDigest sends one user's digest and reports whether there was anything to send
+func sendDigest(ctx context.Context, db *gorm.DB, m mailer.Mailer, user models.User, now time.Time) (bool, error) {
+	// Claim the digest first so that another instance running the job does not send it too.
+	// The digest period restarts even when there is nothing to send.
+	claim := db.Model(&models.User{}).
+		Where("id = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", user.ID, now.Add(-user.DigestFrequency.Interval())).
+		UpdateColumn("last_digest_at", now)
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return false, claim.Error
+	}
+
+	sent, err := emailDigest(ctx, db, m, user, now)
+	if err != nil && !sent {
+		// Give the claim back so the next run tries again
+		if result := db.Model(&models.User{}).Where("id = ? AND last_digest_at = ?", user.ID, now).UpdateColumn("last_digest_at", user.LastDigestAt); result.Error != nil {
+			log.Printf("Failed to release digest claim for user %d: %v", user.ID, result.Error)
+		}
+	}
+	return sent, err
+}
+
+// emailDigest emails the user's undelivered notifications, if any, and marks them emailed
+func emailDigest(ctx context.Context, db *gorm.DB, m mailer.Mailer, user models.User, now time.Time) (bool, error) {
+	channels, err := NotificationPreferences(db, int(user.ID))
+	if err != nil {
+		return false, err
+	}
+
+	var emailed []models.NotificationType
+	for t, channel := range channels {
+		if channel == models.ChannelEmail {
+			emailed = append(emailed, t)
+		}
+	}
+
+	var notifications []models.Notification
+	if len(emailed) > 0 {
+		err = db.Where("user_id = ? AND read = ? AND emailed_at IS NULL AND type IN ? AND created_at <= ?", user.ID, false, emailed, now).
+			Order("created_at desc, id desc").
+			Find(&notifications).Error
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if len(notifications) > 0 {
+		if err := m.Send(ctx, BuildDigest(user, notifications)); err != nil {
+			return false, err
+		}
+
+		ids := make([]int, len(notifications))
+		for i, notification := range notifications {
+			ids[i] = notification.ID
+		}
+		if err := db.Model(&models.Notification{}).Where("id IN ?", ids).UpdateColumn("emailed_at", now).Error; err != nil {
+			return true, err
+		}
+	}
+	return len(notifications) > 0, nil
+}
+
+// RunDigestJob checks for due digests at every interval until ctx is cancelled
+func RunDigestJob(ctx context.Context, db *gorm.DB, m mailer.Mailer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := SendDigests(ctx, db, m, time.Now())
+			if err != nil {
+				log.Printf("Failed to send digests: %v", err)
+				continue
+			}
+			if sent > 0 {
+				log.Printf("Sent %d notification digests", sent)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FollowUser makes follower follow followee; following someone twice is a no-op
+func FollowUser(db *gorm.DB, followerID, followeeID uint) error {
+	follow := models.Follow{FollowerID: followerID, FolloweeID: followeeID, CreatedAt: time.Now()}
+	if err := follow.Validate(); err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error
+}
+
+// UnfollowUser removes a follow, returning utils.ErrNotFound if there was none
+func UnfollowUser(db *gorm.DB, followerID, followeeID uint) error {
+	result := db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&models.Follow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrNotFound
+	}
+	return nil
+}
+
+// GetFollowCounts returns the follower and following counts of a user
+func GetFollowCounts(db *gorm.DB, userID uint) (models.FollowCounts, error) {
+	var counts models.FollowCounts
+	if err := db.Model(&models.Follow{}).Where("followee_id = ?", userID).Count(&counts.Followers).Error; err != nil {
+		return counts, err
+	}
+	err := db.Model(&models.Follow{}).Where("follower_id = ?", userID).Count(&counts.Following).Error
+	return counts, err
+}
+
+// IsFollowing reports whether follower follows followee
+func IsFollowing(db *gorm.DB, followerID, followeeID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count).Error
+	return count > 0, err
+}
+
+// ListFollowers returns a page of the users following userID, most recent first
+func ListFollowers(db *gorm.DB, userID uint, page, limit int) ([]models.User, int64, error) {
+	return listFollowUsers(db, "follows.follower_id", "follows.followee_id", userID, page, limit)
+}
+
+// ListFollowing returns a page of the users userID follows, most recent first
+func ListFollowing(db *gorm.DB, userID uint, page, limit int) ([]models.User, int64, error) {
+	return listFollowUsers(db, "follows.followee_id", "follows.follower_id", userID, page, limit)
+}
+
+func listFollowUsers(db *gorm.DB, joinColumn, matchColumn string, userID uint, page, limit int) ([]models.User, int64, error) {
+	query := db.Model(&models.User{}).
+		Joins("JOIN follows ON "+joinColumn+" = users.id").
+		Where(matchColumn+" = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.Order("follows.created_at desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&users).Error
+	return users, total, err
+}
+
+// RecordActivity stores an activity for followers' feeds. Feeds are derived data,
+// so a failure is logged rather than failing the action that produced it.
+func RecordActivity(db *gorm.DB, activity models.Activity) {
+	if activity.CreatedAt.IsZero() {
+		activity.CreatedAt = time.Now()
+	}
+	if err := db.Create(&activity).Error; err != nil {
+		log.Printf("Failed to record %s activity for user %d: %v", activity.Type, activity.ActorID, err)
+	}
+}
+
+// GetFeed returns up to limit activities by users the viewer follows, newest first, starting
+// after cursor (empty for the first page). It also returns the cursor for the next page, or ""
+// when there are no more activities. Forecasts on markets that still hide them from the viewer
+// are left out before the page is cut, so pages are only short at the end of the feed.
+func GetFeed(db *gorm.DB, viewerID uint, cursor string, limit int) ([]models.Activity, string, error) {
+	followees := db.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", viewerID)
+	query := db.Joins("LEFT JOIN markets ON markets.id = activities.market_id").
+		Where("activities.actor_id IN (?)", followees).
+		Where(db.Where("activities.type <> ?", models.ActivityForecast).Or(forecastsVisibleSQL(viewerID, time.Now())))
+
+	// Keyset pagination walks idx_activities_time newest first from the cursor, so a page costs
+	// the rows scanned to find limit matches rather than everything before it. Followed users
+	// with little recent activity make that scan longer; it never sorts the whole feed.
+	if cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where("activities.created_at < ? OR (activities.created_at = ? AND activities.id < ?)", createdAt, createdAt, id)
+	}
+
+	var activities []models.Activity
+	err := query.Preload("Actor").Preload("Market").Preload("Forecast").
+		Order("activities.created_at desc, activities.id desc").
+		Limit(limit + 1).
+		Find(&activities).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(activities) > limit {
+		activities = activities[:limit]
+		last := activities[limit-1]
+		next = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return activities, next, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveForecast creates or updates a forecast, appends its new state to the revision history
+// and keeps the forecaster's stats in step: the count of active forecasts when it is made,
+// withdrawn or restored, and the daily streak when it is made or updated
+func SaveForecast(db *gorm.DB, forecast *models.Forecast) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		wasActive := false
+		if forecast.ID != 0 {
+			var previous models.Forecast
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "withdrawn_at").First(&previous, forecast.ID).Error; err != nil {
+				return err
+			}
+			wasActive = previous.IsActive()
+		}
+
+		if err := tx.Save(forecast).Error; err != nil {
+			return err
+		}
+
+		delta := 0
+		switch {
+		case forecast.IsActive() && !wasActive:
+			delta = 1
+		case !forecast.IsActive() && wasActive:
+			delta = -1
+		}
+		if err := AdjustForecastCount(tx, forecast.UserID, delta); err != nil {
+			return err
+		}
+		if forecast.IsActive() {
+			if err := RecordForecastDay(tx, forecast.UserID, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		revision := forecast.Revision()
+		return tx.Create(&revision).Error
+	})
+}
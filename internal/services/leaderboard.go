@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+)
+
+// Leaderboard errors
+var (
+	ErrInvalidLeaderboardWindow = errors.New("window must be one of all, 7d, 30d, 90d or month, with month given as YYYY-MM")
+	ErrInvalidLeaderboardMode   = errors.New("mode must be raw or adjusted")
+)
+
+// Adjusted ranking settings
+var (
+	// LeaderboardPriorWeight is how many forecasts' worth of the population mean each user's
+	// average is shrunk towards; the more forecasts a user has, the less it matters
+	LeaderboardPriorWeight = utils.GetEnvInt("LEADERBOARD_PRIOR_WEIGHT", 10)
+	// LeaderboardMinPredictions is the default minimum of resolved forecasts to be ranked in adjusted mode
+	LeaderboardMinPredictions = utils.GetEnvInt("LEADERBOARD_MIN_PREDICTIONS", 5)
+)
+
+// ParseLeaderboardMode parses the mode query parameter, defaulting to raw
+func ParseLeaderboardMode(mode string) (models.LeaderboardMode, error) {
+	switch models.LeaderboardMode(mode) {
+	case "", models.LeaderboardRaw:
+		return models.LeaderboardRaw, nil
+	case models.LeaderboardAdjusted:
+		return models.LeaderboardAdjusted, nil
+	}
+	return "", ErrInvalidLeaderboardMode
+}
+
+// AdjustedScore shrinks a user's average score per forecast towards the population mean.
+// With no forecasts it is the mean itself; as forecasts grow it approaches the user's own average.
+func AdjustedScore(total float64, resolved int, mean float64, priorWeight int) float64 {
+	if resolved+priorWeight <= 0 {
+		return mean
+	}
+	return (total + float64(priorWeight)*mean) / float64(resolved+priorWeight)
+}
+
+// ParseLeaderboardWindow turns the window and month query parameters into a filter's time range.
+// Rolling windows end now; "month" covers the calendar month given as YYYY-MM, in UTC.
+func ParseLeaderboardWindow(window, month string, now time.Time) (time.Time, time.Time, error) {
+	switch window {
+	case "", "all":
+		return time.Time{}, time.Time{}, nil
+	case "7d":
+		return now.AddDate(0, 0, -7), time.Time{}, nil
+	case "30d":
+		return now.AddDate(0, 0, -30), time.Time{}, nil
+	case "90d":
+		return now.AddDate(0, 0, -90), time.Time{}, nil
+	case "month":
+		start, err := time.Parse("2006-01", month)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidLeaderboardWindow
+		}
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, ErrInvalidLeaderboardWindow
+}
+
+// GetLeaderboard returns a page of the leaderboard, the number of ranked users and, when
+// viewerID is set, the viewer's own entry wherever they rank (nil if they are not ranked)
+func GetLeaderboard(db *gorm.DB, filter models.LeaderboardFilter, page, limit int, viewerID uint) ([]models.LeaderboardEntry, int64, *models.LeaderboardEntry, error) {
+	mean, err := leaderboardMean(db, filter)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	ranked := rankedLeaderboard(db, filter, mean)
+
+	var total int64
+	if err := db.Table("(?) AS ranked", ranked).Count(&total).Error; err != nil {
+		return nil, 0, nil, err
+	}
+
+	offset := (page - 1) * limit
+	entries := []models.LeaderboardEntry{}
+	err = leaderboardRows(db, ranked).
+		Where("ranked.position > ? AND ranked.position <= ?", offset, offset+limit).
+		Order("ranked.position").
+		Scan(&entries).Error
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if viewerID == 0 {
+		return entries, total, nil, nil
+	}
+
+	var mine []models.LeaderboardEntry
+	if err := leaderboardRows(db, ranked).Where("ranked.user_id = ?", viewerID).Scan(&mine).Error; err != nil {
+		return nil, 0, nil, err
+	}
+	if len(mine) == 0 {
+		return entries, total, nil, nil
+	}
+	return entries, total, &mine[0], nil
+}
+
+// RankLeaderboard ranks the users behind the score records matching filter the way
+// GetLeaderboard does in the database, returning every ranked entry without usernames
+func RankLeaderboard(filter models.LeaderboardFilter, records []models.ScoreRecord) []models.LeaderboardEntry {
+	var sum float64
+	var count int
+	byUser := make(map[uint]*models.LeaderboardEntry)
+	for _, record := range records {
+		if !filter.From.IsZero() && record.ResolvedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !record.ResolvedAt.Before(filter.To) {
+			continue
+		}
+		if filter.Category != "" && record.Category != filter.Category {
+			continue
+		}
+
+		entry, ok := byUser[record.UserID]
+		if !ok {
+			entry = &models.LeaderboardEntry{UserID: record.UserID}
+			byUser[record.UserID] = entry
+		}
+		entry.Score += record.Score
+		entry.Resolved++
+		if record.Correct {
+			entry.Correct++
+		}
+		sum += record.Score
+		count++
+	}
+
+	mean := 0.0
+	if count > 0 {
+		mean = sum / float64(count)
+	}
+
+	entries := make([]models.LeaderboardEntry, 0, len(byUser))
+	for _, entry := range byUser {
+		if entry.Resolved < filter.MinPredictions {
+			continue
+		}
+		// Rounding stops floating point noise in the sums from splitting ties
+		entry.AdjustedScore = roundScore(AdjustedScore(entry.Score, entry.Resolved, mean, LeaderboardPriorWeight))
+		entry.Score = roundScore(entry.Score)
+		entries = append(entries, *entry)
+	}
+
+	rankBy := func(entry models.LeaderboardEntry) float64 { return entry.Score }
+	if filter.Mode == models.LeaderboardAdjusted {
+		rankBy = func(entry models.LeaderboardEntry) float64 { return entry.AdjustedScore }
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if rankBy(entries[i]) != rankBy(entries[j]) {
+			return rankBy(entries[i]) > rankBy(entries[j])
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+	for i := range entries {
+		if i > 0 && rankBy(entries[i]) == rankBy(entries[i-1]) {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries
+}
+
+// roundScore rounds a score to six decimal places, as the leaderboard queries do
+func roundScore(score float64) float64 {
+	return math.Round(score*1e6) / 1e6
+}
+
+// filteredScores selects the score records matching the filter's window and category
+func filteredScores(db *gorm.DB, filter models.LeaderboardFilter) *gorm.DB {
+	query := db.Model(&models.ScoreRecord{})
+	if !filter.From.IsZero() {
+		query = query.Where("resolved_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("resolved_at < ?", filter.To)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	return query
+}
+
+// leaderboardMean is the average score per forecast over the filtered records
+func leaderboardMean(db *gorm.DB, filter models.LeaderboardFilter) (float64, error) {
+	var mean float64
+	err := filteredScores(db, filter).Select("COALESCE(AVG(score), 0)").Scan(&mean).Error
+	return mean, err
+}
+
+// rankedLeaderboard totals each user's score records and ranks them by the filter's mode.
+// RANK gives tied users the same rank; ROW_NUMBER breaks ties by user ID so pages are stable.
+func rankedLeaderboard(db *gorm.DB, filter models.LeaderboardFilter, mean float64) *gorm.DB {
+	// Rounding stops floating point noise in the sums from splitting ties; the adjusted score mirrors AdjustedScore
+	adjusted := fmt.Sprintf("ROUND(CAST((SUM(score) + %d * CAST(? AS DOUBLE PRECISION)) / (COUNT(*) + %d) AS NUMERIC), 6)", LeaderboardPriorWeight, LeaderboardPriorWeight)
+	totals := filteredScores(db, filter).
+		Select("user_id, ROUND(CAST(SUM(score) AS NUMERIC), 6) AS score, "+adjusted+" AS adjusted_score, "+
+			"COUNT(*) AS resolved, SUM(CASE WHEN correct THEN 1 ELSE 0 END) AS correct", mean).
+		Group("user_id")
+	if filter.MinPredictions > 0 {
+		totals = totals.Having("COUNT(*) >= ?", filter.MinPredictions)
+	}
+
+	order := "score DESC"
+	if filter.Mode == models.LeaderboardAdjusted {
+		order = "adjusted_score DESC"
+	}
+
+	return db.Table("(?) AS totals", totals).
+		Select("user_id, score, adjusted_score, resolved, correct, " +
+			"RANK() OVER (ORDER BY " + order + ") AS rank, " +
+			"ROW_NUMBER() OVER (ORDER BY " + order + ", user_id ASC) AS position")
+}
+
+// leaderboardRows selects leaderboard entries from the ranked totals
+func leaderboardRows(db *gorm.DB, ranked *gorm.DB) *gorm.DB {
+	return db.Table("(?) AS ranked", ranked).
+		Select("ranked.rank, ranked.user_id, users.username, ranked.score, ranked.adjusted_score, ranked.resolved, ranked.correct").
+		Joins("JOIN users ON users.id = ranked.user_id")
+}
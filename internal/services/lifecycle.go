@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+)
+
+// CloseExpiredMarkets closes open markets whose close date has passed and returns them
+func CloseExpiredMarkets(db *gorm.DB, now time.Time) ([]models.Market, error) {
+	var markets []models.Market
+	if err := db.Where("status = ? AND close_date <= ?", models.MarketOpen, now).Find(&markets).Error; err != nil {
+		return nil, err
+	}
+
+	closed := markets[:0]
+	for _, market := range markets {
+		// The status check keeps two instances from closing the same market twice
+		result := db.Model(&models.Market{}).
+			Where("id = ? AND status = ?", market.ID, models.MarketOpen).
+			Updates(map[string]interface{}{"status": models.MarketClosed, "updated_at": now})
+		if result.Error != nil {
+			return closed, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		market.Status = models.MarketClosed
+		market.UpdatedAt = now
+		closed = append(closed, market)
+		DispatchWebhookEvent(db, models.WebhookMarketClosed, market.CreatorID, models.NewMarketWebhookPayload(&market))
+	}
+
+	return closed, nil
+}
+
+// RunMarketLifecycleJob closes expired markets, sends due reminders and awards finished tournaments
+// at every interval until ctx is cancelled. Once a month, and when it starts, it also awards the
+// achievements earned on the previous month's final leaderboard.
+func RunMarketLifecycleJob(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	closedMonth := ""
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			closed, err := CloseExpiredMarkets(db, time.Now())
+			if err != nil {
+				log.Printf("Failed to close expired markets: %v", err)
+			}
+			if len(closed) > 0 {
+				log.Printf("Closed %d expired markets", len(closed))
+			}
+
+			if _, err := SendReminders(db, time.Now()); err != nil {
+				log.Printf("Failed to send reminders: %v", err)
+			}
+
+			if _, err := AwardFinishedTournaments(db, time.Now()); err != nil {
+				log.Printf("Failed to award finished tournaments: %v", err)
+			}
+
+			if month := time.Now().UTC().Format("2006-01"); month != closedMonth {
+				if _, err := AwardMonthlyAchievements(db, time.Now()); err != nil {
+					log.Printf("Failed to award monthly achievements: %v", err)
+				} else {
+					closedMonth = month
+				}
+			}
+		}
+	}
+}
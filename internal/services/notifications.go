@@ -0,0 +1,132 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+)
+
+// NotificationRetentionLimit is the most notifications kept per user; older ones are pruned
+var NotificationRetentionLimit = utils.GetEnvInt("NOTIFICATION_RETENTION_LIMIT", 500)
+
+// CreateNotification stores a notification for a user, pushes it to their open streams and
+// prunes their oldest notifications beyond the retention limit.
+// Notifications of a type the user has turned off are silently dropped.
+func CreateNotification(db *gorm.DB, notification *models.Notification) error {
+	channel, err := NotificationChannelFor(db, notification.UserID, notification.Type)
+	if err != nil {
+		return err
+	}
+	if channel == models.ChannelOff {
+		return nil
+	}
+
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	if err := db.Create(notification).Error; err != nil {
+		return err
+	}
+	PublishNotification(notification)
+
+	if err := pruneNotifications(db, notification.UserID, NotificationRetentionLimit); err != nil {
+		log.Printf("Failed to prune notifications for user %d: %v", notification.UserID, err)
+	}
+	return nil
+}
+
+// ListNotifications returns a page of a user's notifications, newest first, and the cursor of the next page
+func ListNotifications(db *gorm.DB, userID int, unreadOnly bool, cursor string, limit int) ([]models.Notification, string, error) {
+	query := db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read = ?", false)
+	}
+
+	if cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", createdAt, createdAt, id)
+	}
+
+	var notifications []models.Notification
+	err := query.Order("created_at desc, id desc").
+		Limit(limit + 1).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[limit-1]
+		next = utils.EncodeCursor(last.CreatedAt, uint(last.ID))
+	}
+
+	return notifications, next, nil
+}
+
+// CountUnreadNotifications returns how many unread notifications a user has
+func CountUnreadNotifications(db *gorm.DB, userID int) (int64, error) {
+	var count int64
+	err := db.Model(&models.Notification{}).
+		Where("user_id = ? AND read = ?", userID, false).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationRead marks one of a user's notifications as read
+func MarkNotificationRead(db *gorm.DB, userID, id int) error {
+	result := db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		UpdateColumn("read", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Postgres counts matched rows, so this only happens when the notification is missing
+		return utils.ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks all of a user's notifications as read and returns how many changed
+func MarkAllNotificationsRead(db *gorm.DB, userID int) (int64, error) {
+	result := db.Model(&models.Notification{}).
+		Where("user_id = ? AND read = ?", userID, false).
+		UpdateColumn("read", true)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteNotification deletes one of a user's notifications
+func DeleteNotification(db *gorm.DB, userID, id int) error {
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Notification{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrNotFound
+	}
+	return nil
+}
+
+// pruneNotifications deletes a user's notifications beyond the newest keep
+func pruneNotifications(db *gorm.DB, userID, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	newest := db.Model(&models.Notification{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("created_at desc, id desc").
+		Limit(keep)
+
+	return db.Where("user_id = ? AND id NOT IN (?)", userID, newest).
+		Delete(&models.Notification{}).Error
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+)
+
+// predictionsQuery selects predictions with their author's name and result joined in
+func predictionsQuery(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Prediction{}).
+		Select("predictions.*, users.username AS user_name, results.outcome AS outcome").
+		Joins("LEFT JOIN users ON users.id = predictions.user_id").
+		Joins("LEFT JOIN results ON results.prediction_id = predictions.id")
+}
+
+// filteredPredictions selects the predictions matching filter
+func filteredPredictions(db *gorm.DB, filter models.PredictionFilter) *gorm.DB {
+	query := predictionsQuery(db)
+
+	if filter.UserID > 0 {
+		query = query.Where("predictions.user_id = ?", filter.UserID)
+	}
+	if filter.Category != "" {
+		query = query.Where("predictions.category = ?", filter.Category)
+	}
+
+	now := time.Now()
+	switch filter.Status {
+	case models.PredictionPending:
+		query = query.Where("results.id IS NULL AND predictions.end_date > ?", now)
+	case models.PredictionEnded:
+		query = query.Where("results.id IS NULL AND predictions.end_date <= ?", now)
+	case models.PredictionResolved:
+		query = query.Where("results.id IS NOT NULL")
+	}
+	return query
+}
+
+// ListPredictions returns a page of predictions matching filter, newest first, with the viewer's votes
+func ListPredictions(db *gorm.DB, filter models.PredictionFilter, viewerID, page, limit int) ([]models.Prediction, error) {
+	order := "predictions.created_at desc"
+	if filter.Trending {
+		order = "predictions.hot_score desc"
+	}
+
+	var predictions []models.Prediction
+	err := filteredPredictions(db, filter).Order(order).Order("predictions.id desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&predictions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decoratePredictions(db, viewerID, predictions); err != nil {
+		return nil, err
+	}
+	return predictions, nil
+}
+
+// CountPredictions returns how many predictions match filter
+func CountPredictions(db *gorm.DB, filter models.PredictionFilter) (int64, error) {
+	var total int64
+	err := filteredPredictions(db, filter).Count(&total).Error
+	return total, err
+}
+
+// GetPrediction returns a single prediction with the viewer's vote
+func GetPrediction(db *gorm.DB, id, viewerID int) (*models.Prediction, error) {
+	var predictions []models.Prediction
+	if err := predictionsQuery(db).Where("predictions.id = ?", id).Limit(1).Find(&predictions).Error; err != nil {
+		return nil, err
+	}
+	if len(predictions) == 0 {
+		return nil, utils.ErrNotFound
+	}
+
+	if err := decoratePredictions(db, viewerID, predictions); err != nil {
+		return nil, err
+	}
+	return &predictions[0], nil
+}
+
+// DeletePrediction removes a prediction together with its votes and result
+func DeletePrediction(db *gorm.DB, id int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("prediction_id = ?", id).Delete(&models.Vote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("prediction_id = ?", id).Delete(&models.Result{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Prediction{}, id).Error
+	})
+}
+
+// ResolvePrediction records the outcome of an ended prediction and notifies everyone who voted on it
+func ResolvePrediction(db *gorm.DB, prediction *models.Prediction, result *models.Result) error {
+	result.PredictionID = prediction.ID
+	result.CreatedAt = time.Now()
+
+	if err := db.Create(result).Error; err != nil {
+		return err
+	}
+
+	outcome := result.Outcome
+	prediction.Outcome = &outcome
+	prediction.Status = models.PredictionResolved
+
+	var voterIDs []int
+	if err := db.Model(&models.Vote{}).Where("prediction_id = ? AND removed_at IS NULL", prediction.ID).Pluck("user_id", &voterIDs).Error; err != nil {
+		log.Printf("Failed to load voters of prediction %d: %v", prediction.ID, err)
+		return nil
+	}
+
+	for _, voterID := range voterIDs {
+		notification := ResultNotification(prediction, voterID)
+		if err := CreateNotification(db, &notification); err != nil {
+			log.Printf("Failed to notify result of prediction %d: %v", prediction.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// ResultNotification tells a voter how the prediction they voted on turned out
+func ResultNotification(prediction *models.Prediction, voterID int) models.Notification {
+	verdict := "did not come true"
+	if prediction.Outcome != nil && *prediction.Outcome {
+		verdict = "came true"
+	}
+	return models.Notification{
+		UserID:  voterID,
+		Type:    models.NotificationResult,
+		Message: fmt.Sprintf("The prediction \"%s\" %s", prediction.Title, verdict),
+		Link:    fmt.Sprintf("/predictions/%d", prediction.ID),
+	}
+}
+
+// decoratePredictions fills in derived fields for the viewer
+func decoratePredictions(db *gorm.DB, viewerID int, predictions []models.Prediction) error {
+	now := time.Now()
+	for i := range predictions {
+		predictions[i].Status = predictions[i].StatusAt(now)
+	}
+
+	if viewerID <= 0 {
+		return nil
+	}
+	return ApplyUserVotes(db, viewerID, predictions)
+}
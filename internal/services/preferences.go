@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UnsubscribeDigests is the unsubscribe scope that turns off email digests entirely
+const UnsubscribeDigests = "digest"
+
+// ErrInvalidUnsubscribeScope is returned for unsubscribe links naming an unknown scope
+var ErrInvalidUnsubscribeScope = errors.New("invalid unsubscribe scope")
+
+// NotificationPreferences returns the user's channel for every notification type, with defaults filled in
+func NotificationPreferences(db *gorm.DB, userID int) (map[models.NotificationType]models.NotificationChannel, error) {
+	var stored []models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	channels := make(map[models.NotificationType]models.NotificationChannel, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		channels[t] = models.DefaultNotificationChannel(t)
+	}
+	for _, preference := range stored {
+		channels[preference.Type] = preference.Channel
+	}
+	return channels, nil
+}
+
+// NotificationChannelFor returns the user's channel for one notification type
+func NotificationChannelFor(db *gorm.DB, userID int, t models.NotificationType) (models.NotificationChannel, error) {
+	var preference models.NotificationPreference
+	result := db.Where("user_id = ? AND type = ?", userID, t).Limit(1).Find(&preference)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.DefaultNotificationChannel(t), nil
+	}
+	return preference.Channel, nil
+}
+
+// UpdateNotificationPreferences stores a user's channels and digest frequency
+func UpdateNotificationPreferences(db *gorm.DB, userID int, input models.NotificationPreferencesRequest) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for t, channel := range input.Channels {
+			if err := setNotificationChannel(tx, userID, t, channel); err != nil {
+				return err
+			}
+		}
+
+		if input.DigestFrequency != "" {
+			return tx.Model(&models.User{}).
+				Where("id = ?", userID).
+				UpdateColumn("digest_frequency", input.DigestFrequency).Error
+		}
+		return nil
+	})
+}
+
+// Unsubscribe applies a one-click unsubscribe link: a notification type scope stops that
+// type being emailed, and the digest scope turns digests off
+func Unsubscribe(db *gorm.DB, userID int, scope string) error {
+	if scope == UnsubscribeDigests {
+		return db.Model(&models.User{}).
+			Where("id = ?", userID).
+			UpdateColumn("digest_frequency", models.DigestOff).Error
+	}
+
+	t := models.NotificationType(scope)
+	if !t.IsValid() {
+		return ErrInvalidUnsubscribeScope
+	}
+
+	channel, err := NotificationChannelFor(db, userID, t)
+	if err != nil {
+		return err
+	}
+	if channel != models.ChannelEmail {
+		return nil
+	}
+	return setNotificationChannel(db, userID, t, models.ChannelInApp)
+}
+
+// setNotificationChannel upserts a single preference
+func setNotificationChannel(db *gorm.DB, userID int, t models.NotificationType, channel models.NotificationChannel) error {
+	preference := models.NotificationPreference{
+		UserID:    userID,
+		Type:      t,
+		Channel:   channel,
+		UpdatedAt: time.Now(),
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel", "updated_at"}),
+	}).Create(&preference).Error
+}
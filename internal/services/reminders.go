@@ -0,0 +1,312 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reminder settings
+var (
+	// EndingSoonLead is how long before a market closes or a prediction ends that participants are reminded
+	EndingSoonLead = time.Duration(utils.GetEnvInt("REMINDER_ENDING_SOON_HOURS", 24)) * time.Hour
+	// ResolutionNagInterval is how often a creator is reminded about an overdue resolution
+	ResolutionNagInterval = time.Duration(utils.GetEnvInt("REMINDER_RESOLUTION_NAG_HOURS", 24)) * time.Hour
+	// ResolutionGracePeriod is how long after the resolve date the moderators are asked to step in
+	ResolutionGracePeriod = time.Duration(utils.GetEnvInt("REMINDER_RESOLUTION_GRACE_HOURS", 72)) * time.Hour
+	// StreakReminderHour is the local hour from which users who have not forecast yet today are
+	// warned that their daily streak is at risk
+	StreakReminderHour = utils.GetEnvInt("REMINDER_STREAK_HOUR", 20)
+)
+
+// SendReminders sends every reminder that is due and returns how many notifications were created.
+// Each reminder is logged under a unique key, so running the job twice, or on several
+// instances at once, never sends a reminder twice. A reminder that fails is logged and
+// retried on the next run without holding up the others; the errors of kinds of reminder
+// that could not be looked up at all are returned together.
+func SendReminders(db *gorm.DB, now time.Time) (int, error) {
+	sent := 0
+	var errs []error
+	for _, remind := range []func(*gorm.DB, time.Time) (int, error){
+		remindMarketsEndingSoon,
+		remindPredictionsEndingSoon,
+		remindResolutionsDue,
+		remindStreaksAtRisk,
+	} {
+		n, err := remind(db, now)
+		sent += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// ResolutionOverdue reports whether a market is still unresolved a grace period after its
+// resolve date. From then on the moderators are asked to resolve it, and may do so.
+func ResolutionOverdue(market *models.Market, now time.Time) bool {
+	return market.Status != models.MarketResolved && !now.Before(market.ResolveDate.Add(ResolutionGracePeriod))
+}
+
+// ResolutionNagSequence numbers the nags about a resolution that was due at resolveDate:
+// 0 on the resolve date, 1 one nag interval later, and so on
+func ResolutionNagSequence(resolveDate, now time.Time, interval time.Duration) int {
+	if interval <= 0 || now.Before(resolveDate) {
+		return 0
+	}
+	return int(now.Sub(resolveDate) / interval)
+}
+
+// remindMarketsEndingSoon tells forecasters that a market they forecast on is about to close
+func remindMarketsEndingSoon(db *gorm.DB, now time.Time) (int, error) {
+	var markets []models.Market
+	err := db.Where("status = ? AND close_date > ? AND close_date <= ?", models.MarketOpen, now, now.Add(EndingSoonLead)).
+		Find(&markets).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, market := range markets {
+		var userIDs []uint
+		if err := db.Model(&models.Forecast{}).Where("market_id = ? AND withdrawn_at IS NULL", market.ID).Pluck("user_id", &userIDs).Error; err != nil {
+			log.Printf("Failed to load forecasters of market %d for reminders: %v", market.ID, err)
+			continue
+		}
+
+		for _, userID := range userIDs {
+			notification := models.Notification{
+				UserID:  int(userID),
+				Type:    models.NotificationEndingSoon,
+				Message: fmt.Sprintf("Market \"%s\" closes %s; update your forecast while you can", market.Title, untilText(market.CloseDate, now)),
+				Link:    fmt.Sprintf("/markets/%d", market.ID),
+			}
+			ok, err := sendReminder(db, models.ReminderMarketEndingSoon, market.ID, userID, 0, &notification)
+			if err != nil {
+				log.Printf("Failed to send reminder: %v", err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// remindPredictionsEndingSoon tells a prediction's author and voters that it is about to end
+func remindPredictionsEndingSoon(db *gorm.DB, now time.Time) (int, error) {
+	var predictions []models.Prediction
+	err := db.Where("end_date > ? AND end_date <= ?", now, now.Add(EndingSoonLead)).Find(&predictions).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, prediction := range predictions {
+		var userIDs []int
+		if err := db.Model(&models.Vote{}).Where("prediction_id = ? AND removed_at IS NULL", prediction.ID).Pluck("user_id", &userIDs).Error; err != nil {
+			log.Printf("Failed to load voters of prediction %d for reminders: %v", prediction.ID, err)
+			continue
+		}
+		userIDs = append(userIDs, prediction.UserID)
+
+		for _, userID := range userIDs {
+			notification := models.Notification{
+				UserID:  userID,
+				Type:    models.NotificationEndingSoon,
+				Message: fmt.Sprintf("Prediction \"%s\" ends %s", prediction.Title, untilText(prediction.EndDate, now)),
+				Link:    fmt.Sprintf("/predictions/%d", prediction.ID),
+			}
+			ok, err := sendReminder(db, models.ReminderPredictionEndingSoon, uint(prediction.ID), uint(userID), 0, &notification)
+			if err != nil {
+				log.Printf("Failed to send reminder: %v", err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// remindResolutionsDue nags creators of markets past their resolve date and, after the
+// grace period, asks the moderators to step in
+func remindResolutionsDue(db *gorm.DB, now time.Time) (int, error) {
+	var markets []models.Market
+	err := db.Where("status <> ? AND resolve_date <= ?", models.MarketResolved, now).Find(&markets).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(markets) == 0 {
+		return 0, nil
+	}
+
+	// Admins act as moderators
+	var moderatorIDs []uint
+	if err := db.Model(&models.User{}).Where("is_admin = ?", true).Pluck("id", &moderatorIDs).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, market := range markets {
+		link := fmt.Sprintf("/markets/%d", market.ID)
+
+		notification := models.Notification{
+			UserID:  int(market.CreatorID),
+			Type:    models.NotificationResolutionDue,
+			Message: fmt.Sprintf("Your market \"%s\" was due to be resolved %s; please resolve it", market.Title, sinceText(market.ResolveDate, now)),
+			Link:    link,
+		}
+		sequence := ResolutionNagSequence(market.ResolveDate, now, ResolutionNagInterval)
+		ok, err := sendReminder(db, models.ReminderResolutionDue, market.ID, market.CreatorID, sequence, &notification)
+		if err != nil {
+			// Still escalate below; the moderators don't depend on the creator's nag
+			log.Printf("Failed to send reminder: %v", err)
+		} else if ok {
+			sent++
+		}
+
+		if !ResolutionOverdue(&market, now) {
+			continue
+		}
+		for _, moderatorID := range moderatorIDs {
+			notification := models.Notification{
+				UserID:  int(moderatorID),
+				Type:    models.NotificationResolutionDue,
+				Message: fmt.Sprintf("Market \"%s\" is still unresolved %s after its resolve date; as an admin you can resolve it", market.Title, durationText(now.Sub(market.ResolveDate))),
+				Link:    link,
+			}
+			ok, err := sendReminder(db, models.ReminderResolutionEscalated, market.ID, moderatorID, 0, &notification)
+			if err != nil {
+				log.Printf("Failed to send reminder: %v", err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// remindStreaksAtRisk warns users who forecast yesterday but not yet today, late in their own day,
+// that their daily streak is about to end
+func remindStreaksAtRisk(db *gorm.DB, now time.Time) (int, error) {
+	// Time zones put "yesterday" up to two UTC days back; the exact check happens per user below
+	var rows []struct {
+		models.UserStats
+		TimeZone string
+	}
+	err := db.Model(&models.UserStats{}).
+		Select("user_stats.*, users.time_zone").
+		Joins("JOIN users ON users.id = user_stats.user_id").
+		Where("user_stats.day_streak > 0 AND user_stats.last_forecast_day >= ?", models.LocalDay(now.Add(-72*time.Hour), time.UTC)).
+		Scan(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, row := range rows {
+		user := models.User{ID: row.UserID, TimeZone: row.TimeZone}
+		local := now.In(user.Location())
+		today := models.LocalDay(now, user.Location())
+		if row.LastForecastDay != models.PreviousDay(today) || local.Hour() < StreakReminderHour {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:  int(row.UserID),
+			Type:    models.NotificationStreakAtRisk,
+			Message: fmt.Sprintf("Your %d-day forecasting streak ends at midnight; make or update a forecast today to keep it going", row.DayStreak),
+			Link:    "/challenges/today",
+		}
+		// One warning per user per local day
+		ok, err := sendReminder(db, models.ReminderStreakAtRisk, 0, row.UserID, dayNumber(today), &notification)
+		if err != nil {
+			log.Printf("Failed to send reminder: %v", err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// dayNumber numbers a calendar day (YYYY-MM-DD) as days since the Unix epoch
+func dayNumber(day string) int {
+	t, err := time.Parse(models.DayLayout, day)
+	if err != nil {
+		return 0
+	}
+	return int(t.Unix() / 86400)
+}
+
+// sendReminder logs the reminder and creates its notification in one transaction.
+// It reports false without notifying when the reminder was already sent.
+func sendReminder(db *gorm.DB, kind models.ReminderKind, subjectID, userID uint, sequence int, notification *models.Notification) (bool, error) {
+	sent := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		reminder := models.Reminder{
+			Kind:      kind,
+			SubjectID: subjectID,
+			UserID:    userID,
+			Sequence:  sequence,
+			SentAt:    time.Now(),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		sent = true
+		return CreateNotification(tx, notification)
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s reminder for %d to user %d: %w", kind, subjectID, userID, err)
+	}
+	return sent, nil
+}
+
+// untilText describes when a future time is, such as "in 5 hours"
+func untilText(t, now time.Time) string {
+	return "in " + durationText(t.Sub(now))
+}
+
+// sinceText describes when a past time was, such as "2 days ago"
+func sinceText(t, now time.Time) string {
+	return durationText(now.Sub(t)) + " ago"
+}
+
+// durationText rounds a duration to whole days, hours or minutes for messages
+func durationText(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d >= 48*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d >= time.Minute:
+		return plural(int(d/time.Minute), "minute")
+	}
+	return "less than a minute"
+}
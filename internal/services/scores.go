@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMarketAlreadyResolved is returned when resolving a market that has already been resolved
+var ErrMarketAlreadyResolved = errors.New("market is already resolved")
+
+// ForecastScore is the score a forecast earns when its market resolves to outcome
+func ForecastScore(forecast *models.Forecast, outcome bool) float64 {
+	if forecast.Prediction == outcome {
+		// Correct prediction: higher confidence = higher score
+		return forecast.Confidence / 50.0 // Normalized to 0-2
+	}
+	// Wrong prediction: higher confidence = larger penalty
+	return -forecast.Confidence / 100.0 // Normalized to 0-1
+}
+
+// NewScoreRecord builds the score record of a forecast on a resolved market
+func NewScoreRecord(forecast *models.Forecast, market *models.Market, outcome bool, resolvedAt time.Time) models.ScoreRecord {
+	return models.ScoreRecord{
+		UserID:     forecast.UserID,
+		MarketID:   market.ID,
+		ForecastID: forecast.ID,
+		Category:   market.Category,
+		Score:      ForecastScore(forecast, outcome),
+		Correct:    forecast.Prediction == outcome,
+		ResolvedAt: resolvedAt,
+	}
+}
+
+// ResolveMarket resolves a market with outcome at resolvedAt and scores its active forecasts:
+// each score is kept as a record and added to its forecaster's total and stats, all or nothing.
+// Withdrawn forecasts are not scored. It returns ErrMarketAlreadyResolved if the market was
+// resolved first, such as by its creator and an admin at the same time.
+func ResolveMarket(db *gorm.DB, market *models.Market, outcome bool, resolvedAt time.Time) ([]models.ScoreRecord, error) {
+	var records []models.ScoreRecord
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Market{}).
+			Where("id = ? AND status <> ?", market.ID, models.MarketResolved).
+			Updates(map[string]interface{}{
+				"status":      models.MarketResolved,
+				"outcome":     outcome,
+				"resolved_at": resolvedAt,
+				"updated_at":  resolvedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMarketAlreadyResolved
+		}
+
+		var forecasts []models.Forecast
+		if err := tx.Where("market_id = ? AND withdrawn_at IS NULL", market.ID).Find(&forecasts).Error; err != nil {
+			return err
+		}
+		if len(forecasts) == 0 {
+			return nil
+		}
+
+		records = make([]models.ScoreRecord, len(forecasts))
+		for i := range forecasts {
+			records[i] = NewScoreRecord(&forecasts[i], market, outcome, resolvedAt)
+			err := tx.Model(&models.User{}).
+				Where("id = ?", records[i].UserID).
+				UpdateColumn("prediction_score", gorm.Expr("prediction_score + ?", records[i].Score)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Keep each score so leaderboards can be computed per time window and category
+		if err := tx.Create(&records).Error; err != nil {
+			return err
+		}
+		return ApplyScoreRecords(tx, records)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	market.Status = models.MarketResolved
+	market.Outcome = &outcome
+	market.ResolvedAt = &resolvedAt
+	market.UpdatedAt = resolvedAt
+	return records, nil
+}
+
+// BackfillScoreRecords creates score records for markets resolved before scores were recorded
+// and returns how many were created. Existing records are left alone, so it is safe to rerun.
+func BackfillScoreRecords(db *gorm.DB) (int, error) {
+	var markets []models.Market
+	err := db.Where("status = ? AND outcome IS NOT NULL", models.MarketResolved).
+		Where("NOT EXISTS (SELECT 1 FROM score_records WHERE score_records.market_id = markets.id)").
+		Find(&markets).Error
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, market := range markets {
+		var forecasts []models.Forecast
+		if err := db.Where("market_id = ? AND withdrawn_at IS NULL", market.ID).Find(&forecasts).Error; err != nil {
+			return created, err
+		}
+		if len(forecasts) == 0 {
+			continue
+		}
+
+		// Older markets did not record when they resolved; their last update is the best estimate
+		resolvedAt := market.UpdatedAt
+		if market.ResolvedAt != nil {
+			resolvedAt = *market.ResolvedAt
+		}
+
+		records := make([]models.ScoreRecord, len(forecasts))
+		for i := range forecasts {
+			records[i] = NewScoreRecord(&forecasts[i], &market, *market.Outcome, resolvedAt)
+		}
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&records)
+		if result.Error != nil {
+			return created, result.Error
+		}
+		created += int(result.RowsAffected)
+	}
+
+	return created, nil
+}
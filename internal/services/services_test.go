@@ -0,0 +1,1150 @@
+// services_test.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domolitom/reThink/internal/database"
+	"github.com/domolitom/reThink/internal/mailer"
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB returns a migrated SQLite database that lives for the test
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Open("sqlite:" + filepath.Join(t.TempDir(), "rethink.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	db.Logger = db.Logger.LogMode(logger.Silent)
+	return db
+}
+
+func TestParseResolution(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"1h", time.Hour, false},
+		{"15m", 15 * time.Minute, false},
+		{"1d", 24 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"30s", 0, true},
+		{"xd", 0, true},
+		{"abc", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			resolution, err := ParseResolution(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidResolution)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, resolution)
+			}
+		})
+	}
+}
+
+func TestDownsampleSnapshots(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	snapshots := []models.MarketSnapshot{
+		{Probability: 0.50, PredictionCount: 1, CreatedAt: start.Add(5 * time.Minute)},
+		{Probability: 0.60, PredictionCount: 2, CreatedAt: start.Add(40 * time.Minute)},
+		{Probability: 0.70, PredictionCount: 3, CreatedAt: start.Add(70 * time.Minute)},
+		{Probability: 0.65, PredictionCount: 4, CreatedAt: start.Add(3*time.Hour + time.Minute)},
+	}
+
+	points := DownsampleSnapshots(snapshots, time.Hour)
+
+	assert.Len(t, points, 3)
+	assert.Equal(t, start, points[0].Time)
+	assert.Equal(t, 0.60, points[0].Probability)
+	assert.Equal(t, 2, points[0].PredictionCount)
+	assert.Equal(t, start.Add(time.Hour), points[1].Time)
+	assert.Equal(t, 0.70, points[1].Probability)
+	assert.Equal(t, start.Add(3*time.Hour), points[2].Time)
+	assert.Equal(t, 4, points[2].PredictionCount)
+
+	// No snapshots still yields an empty, non-nil series for JSON
+	assert.NotNil(t, DownsampleSnapshots(nil, time.Hour))
+}
+
+func TestMarketTimeseries(t *testing.T) {
+	db := openTestDB(t)
+	market := models.Market{Title: "Will it rain?", Category: "Weather"}
+	assert.NoError(t, db.Create(&market).Error)
+
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	snapshots := []models.MarketSnapshot{
+		{MarketID: market.ID, Probability: 0.50, PredictionCount: 1, CreatedAt: start.Add(5 * time.Minute)},
+		{MarketID: market.ID, Probability: 0.60, PredictionCount: 2, CreatedAt: start.Add(40 * time.Minute)},
+		{MarketID: market.ID, Probability: 0.70, PredictionCount: 3, CreatedAt: start.Add(70 * time.Minute)},
+		{MarketID: market.ID, Probability: 0.65, PredictionCount: 4, CreatedAt: start.Add(3*time.Hour + time.Minute)},
+	}
+	assert.NoError(t, db.Create(&snapshots).Error)
+
+	// The database buckets the same way as DownsampleSnapshots
+	for _, resolution := range []time.Duration{15 * time.Minute, time.Hour, 24 * time.Hour} {
+		points, err := GetMarketTimeseries(db, market.ID, time.Time{}, time.Time{}, resolution)
+		assert.NoError(t, err)
+		assert.Equal(t, DownsampleSnapshots(snapshots, resolution), points, resolution)
+	}
+
+	points, err := GetMarketTimeseries(db, market.ID, start.Add(time.Hour), time.Time{}, time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, points, 2)
+
+	// Periodic snapshots are only recorded when the aggregate moved
+	assert.NoError(t, db.Create(&models.Forecast{UserID: 1, MarketID: market.ID, Prediction: true, Confidence: 80}).Error)
+	snapshot, err := RecordChangedMarketSnapshot(db, market.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, snapshot) {
+		assert.InDelta(t, 0.8, snapshot.Probability, 1e-9)
+	}
+	snapshot, err = RecordChangedMarketSnapshot(db, market.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+}
+
+func TestRecentForecasts(t *testing.T) {
+	db := openTestDB(t)
+	hide := true
+	future := time.Now().Add(24 * time.Hour)
+	hidden := models.Market{Title: "Hidden", Status: models.MarketOpen, CloseDate: future, HideForecasts: &hide}
+	open := models.Market{Title: "Open", Status: models.MarketOpen, CloseDate: future}
+	withdrawn := models.Market{Title: "Withdrawn", Status: models.MarketOpen, CloseDate: future}
+	assert.NoError(t, db.Create(&[]*models.Market{&hidden, &open, &withdrawn}).Error)
+
+	now := time.Now()
+	assert.NoError(t, db.Create(&[]models.Forecast{
+		{UserID: 1, MarketID: open.ID, Prediction: true, Confidence: 70, CreatedAt: now.Add(-2 * time.Minute)},
+		{UserID: 1, MarketID: hidden.ID, Prediction: true, Confidence: 60, CreatedAt: now.Add(-time.Minute)},
+		{UserID: 1, MarketID: withdrawn.ID, Prediction: true, Confidence: 50, CreatedAt: now, WithdrawnAt: &now},
+	}).Error)
+
+	titles := func(viewerID uint) []string {
+		forecasts, err := RecentForecasts(db, 1, viewerID, 5)
+		assert.NoError(t, err)
+		titles := []string{}
+		for _, forecast := range forecasts {
+			titles = append(titles, forecast.Market.Title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"Hidden", "Open"}, titles(1))
+	assert.Equal(t, []string{"Open"}, titles(2))
+
+	// Forecasting reveals the hidden market to the viewer
+	assert.NoError(t, db.Create(&models.Forecast{UserID: 2, MarketID: hidden.ID, Prediction: false, Confidence: 60}).Error)
+	assert.Equal(t, []string{"Hidden", "Open"}, titles(2))
+}
+
+func TestGetFeedHidesForecastsBeforePaging(t *testing.T) {
+	db := openTestDB(t)
+	hide := true
+	hidden := models.Market{Title: "Hidden", Status: models.MarketOpen, CloseDate: time.Now().Add(time.Hour), HideForecasts: &hide}
+	closed := models.Market{Title: "Closed", Status: models.MarketOpen, CloseDate: time.Now().Add(-time.Hour), HideForecasts: &hide}
+	assert.NoError(t, db.Create(&[]*models.Market{&hidden, &closed}).Error)
+	assert.NoError(t, FollowUser(db, 2, 1))
+
+	// Newest first: hidden forecasts sit on top of the visible activities
+	start := time.Now().Add(-time.Hour)
+	activities := []models.Activity{
+		{ActorID: 1, Type: models.ActivityMarketCreated, MarketID: hidden.ID, CreatedAt: start},
+		{ActorID: 1, Type: models.ActivityForecast, MarketID: closed.ID, CreatedAt: start.Add(time.Minute)},
+		{ActorID: 1, Type: models.ActivityMarketCreated, MarketID: closed.ID, CreatedAt: start.Add(2 * time.Minute)},
+		{ActorID: 3, Type: models.ActivityMarketCreated, MarketID: closed.ID, CreatedAt: start.Add(3 * time.Minute)},
+	}
+	for i := 0; i < 3; i++ {
+		activities = append(activities, models.Activity{ActorID: 1, Type: models.ActivityForecast, MarketID: hidden.ID, CreatedAt: start.Add(time.Duration(4+i) * time.Minute)})
+	}
+	assert.NoError(t, db.Create(&activities).Error)
+
+	first, next, err := GetFeed(db, 2, "", 2)
+	assert.NoError(t, err)
+	if assert.Len(t, first, 2) {
+		assert.Equal(t, activities[2].ID, first[0].ID)
+		assert.Equal(t, activities[1].ID, first[1].ID)
+	}
+	assert.NotEmpty(t, next)
+
+	rest, next, err := GetFeed(db, 2, next, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, rest, 1) {
+		assert.Equal(t, activities[0].ID, rest[0].ID)
+	}
+	assert.Empty(t, next)
+
+	// Forecasting on the hidden market reveals the forecasts there
+	assert.NoError(t, db.Create(&models.Forecast{UserID: 2, MarketID: hidden.ID, Prediction: true, Confidence: 60}).Error)
+	all, _, err := GetFeed(db, 2, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, all, 6)
+}
+
+func TestVotePredictionNotifiesOnce(t *testing.T) {
+	db := openTestDB(t)
+	author := models.User{Name: "Author", Username: "author", Email: "author@example.com"}
+	voter := models.User{Name: "Voter", Username: "voter", Email: "voter@example.com"}
+	assert.NoError(t, db.Create(&[]*models.User{&author, &voter}).Error)
+	prediction := models.Prediction{UserID: int(author.ID), Title: "Rates fall", Description: "Rates fall", Category: "Finance", EndDate: time.Now().Add(time.Hour)}
+	assert.NoError(t, db.Create(&prediction).Error)
+
+	state := func() (models.Prediction, int64) {
+		var current models.Prediction
+		db.First(&current, prediction.ID)
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ?", author.ID).Count(&notifications)
+		return current, notifications
+	}
+
+	_, err := VotePrediction(db, &models.Vote{UserID: int(voter.ID), PredictionID: prediction.ID, Value: true})
+	assert.NoError(t, err)
+	voted, notifications := state()
+	assert.Equal(t, 1, voted.AgreeCount)
+	assert.Equal(t, int64(1), notifications)
+	assert.Greater(t, voted.HotScore, 0.0)
+
+	// Changing sides, removing and voting again only move the counters
+	_, err = VotePrediction(db, &models.Vote{UserID: int(voter.ID), PredictionID: prediction.ID, Value: false})
+	assert.NoError(t, err)
+	_, err = RemoveVote(db, int(voter.ID), prediction.ID)
+	assert.NoError(t, err)
+	_, err = RemoveVote(db, int(voter.ID), prediction.ID)
+	assert.ErrorIs(t, err, utils.ErrNotFound)
+
+	removed := []models.Prediction{{ID: prediction.ID}}
+	assert.NoError(t, ApplyUserVotes(db, int(voter.ID), removed))
+	assert.Nil(t, removed[0].UserVote)
+
+	_, err = VotePrediction(db, &models.Vote{UserID: int(voter.ID), PredictionID: prediction.ID, Value: true})
+	assert.NoError(t, err)
+	revoted, notifications := state()
+	assert.Equal(t, 1, revoted.AgreeCount)
+	assert.Equal(t, 0, revoted.DisagreeCount)
+	assert.Equal(t, int64(1), notifications)
+	assert.Equal(t, voted.HotScore, revoted.HotScore)
+}
+
+func TestComputeCalibration(t *testing.T) {
+	samples := []models.CalibrationSample{
+		{Probability: 0.9, Outcome: true},
+		{Probability: 0.9, Outcome: true},
+		{Probability: 0.9, Outcome: false},
+		{Probability: 0.2, Outcome: false},
+		{Probability: 0.2, Outcome: true},
+		{Probability: 1.0, Outcome: true},
+	}
+
+	report := ComputeCalibration(samples, 10)
+
+	assert.Equal(t, 6, report.Count)
+	assert.Len(t, report.Buckets, 10)
+	assert.Equal(t, 2, report.Buckets[2].Count)
+	assert.InDelta(t, 0.5, report.Buckets[2].ObservedFrequency, 1e-9)
+
+	// 0.9 and 1.0 share the last bucket
+	last := report.Buckets[9]
+	assert.Equal(t, 4, last.Count)
+	assert.InDelta(t, 0.925, last.MeanProbability, 1e-9)
+	assert.InDelta(t, 0.75, last.ObservedFrequency, 1e-9)
+
+	assert.InDelta(t, (2*0.3+4*0.175)/6, report.CalibrationError, 1e-9)
+	assert.InDelta(t, (0.01+0.01+0.81+0.04+0.64+0)/6, report.BrierScore, 1e-9)
+	assert.InDelta(t, 4.0/6*(2.0/6), report.Uncertainty, 1e-9)
+
+	// With one forecast value per bucket the decomposition is exact
+	exact := ComputeCalibration([]models.CalibrationSample{
+		{Probability: 0.85, Outcome: true},
+		{Probability: 0.85, Outcome: false},
+		{Probability: 0.15, Outcome: false},
+	}, 10)
+	assert.InDelta(t, exact.BrierScore, exact.Reliability-exact.Resolution+exact.Uncertainty, 1e-9)
+}
+
+func TestComputeCalibrationEmpty(t *testing.T) {
+	report := ComputeCalibration(nil, 0)
+
+	assert.Equal(t, 0, report.Count)
+	assert.Len(t, report.Buckets, DefaultCalibrationBuckets)
+	assert.Zero(t, report.BrierScore)
+}
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"@alice what do you think?", []string{"alice"}},
+		{"cc @alice and @bob_2, also @alice again", []string{"alice", "bob_2"}},
+		{"mail me at someone@example.com", nil},
+		{"too short @ab", nil},
+		{"(@carol) agreed", []string{"carol"}},
+		{"no mentions here", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExtractMentions(tt.text))
+		})
+	}
+}
+
+func TestHotScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Newer activity outranks older activity of the same weight
+	assert.Greater(t, HotScore(TrendingWeightVote, now), HotScore(TrendingWeightVote, now.Add(-time.Hour)))
+
+	// An activity one half-life old counts half as much as a fresh one
+	assert.InDelta(t, HotScore(1, now.Add(-TrendingHalfLife)), HotScore(0.5, now), 1e-9)
+
+	// Two activities at the same time add up to their combined weight
+	combined := AddHotScore(HotScore(TrendingWeightForecast, now), TrendingWeightForecast, now)
+	assert.InDelta(t, HotScore(2*TrendingWeightForecast, now), combined, 1e-9)
+
+	// A busy but stale item falls behind a single fresh activity
+	stale := HotScore(TrendingWeightCreated, now.Add(-7*24*time.Hour))
+	for i := 0; i < 20; i++ {
+		stale = AddHotScore(stale, TrendingWeightVote, now.Add(-6*24*time.Hour))
+	}
+	assert.Greater(t, HotScore(TrendingWeightForecast, now), stale)
+}
+
+func TestStreamHubDelivery(t *testing.T) {
+	hub := NewStreamHub(10, 10)
+	subscriber, replay := hub.Subscribe(1, []uint{7}, "")
+	assert.Empty(t, replay)
+
+	hub.Publish(StreamEvent{Type: StreamEventNotification, UserID: 1})
+	hub.Publish(StreamEvent{Type: StreamEventNotification, UserID: 2})
+	hub.Publish(StreamEvent{Type: StreamEventMarket, MarketID: 7})
+	hub.Publish(StreamEvent{Type: StreamEventMarket, MarketID: 8})
+
+	// Only the user's own notifications and subscribed markets arrive
+	first := <-subscriber.Events()
+	second := <-subscriber.Events()
+	assert.Equal(t, uint64(1), first.ID)
+	assert.Equal(t, uint64(3), second.ID)
+	assert.Len(t, subscriber.Events(), 0)
+
+	hub.Unsubscribe(subscriber)
+	_, open := <-subscriber.Events()
+	assert.False(t, open)
+}
+
+func TestStreamHubResume(t *testing.T) {
+	hub := NewStreamHub(3, 10)
+	for i := 0; i < 5; i++ {
+		hub.Publish(StreamEvent{Type: StreamEventNotification, UserID: 1})
+	}
+
+	// Events 3-5 are still buffered
+	subscriber, replay := hub.Subscribe(1, nil, hub.EventID(StreamEvent{ID: 3}))
+	if assert.Len(t, replay, 2) {
+		assert.Equal(t, uint64(4), replay[0].ID)
+		assert.Equal(t, uint64(5), replay[1].ID)
+	}
+	hub.Unsubscribe(subscriber)
+
+	// A client that has seen everything gets nothing
+	subscriber, replay = hub.Subscribe(1, nil, hub.EventID(StreamEvent{ID: 5}))
+	assert.Empty(t, replay)
+	hub.Unsubscribe(subscriber)
+
+	// Event 2 has left the buffer, so the client must refetch
+	subscriber, replay = hub.Subscribe(1, nil, hub.EventID(StreamEvent{ID: 1}))
+	if assert.Len(t, replay, 1) {
+		assert.Equal(t, StreamEventReset, replay[0].Type)
+		assert.Equal(t, uint64(5), replay[0].ID)
+	}
+	hub.Unsubscribe(subscriber)
+
+	// IDs issued by another instance cannot be resumed either
+	other := NewStreamHub(3, 10)
+	subscriber, replay = hub.Subscribe(1, nil, other.EventID(StreamEvent{ID: 4}))
+	if assert.Len(t, replay, 1) {
+		assert.Equal(t, StreamEventReset, replay[0].Type)
+	}
+	hub.Unsubscribe(subscriber)
+}
+
+func TestStreamHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewStreamHub(10, 2)
+	slow, _ := hub.Subscribe(1, nil, "")
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(StreamEvent{Type: StreamEventNotification, UserID: 1})
+	}
+
+	// The queued events are still delivered before the channel closes
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	assert.Equal(t, 2, received)
+
+	// Unsubscribing an already dropped subscriber is a no-op
+	hub.Unsubscribe(slow)
+}
+
+func TestDigestDue(t *testing.T) {
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+	recently := now.Add(-time.Hour)
+
+	assert.True(t, DigestDue(models.DigestDaily, nil, now))
+	assert.True(t, DigestDue(models.DigestDaily, &yesterday, now))
+	assert.False(t, DigestDue(models.DigestDaily, &recently, now))
+	assert.False(t, DigestDue(models.DigestWeekly, &yesterday, now))
+	assert.False(t, DigestDue(models.DigestOff, nil, now))
+}
+
+func TestBuildDigest(t *testing.T) {
+	user := models.User{ID: 3, Name: "Ada", Email: "ada@example.com"}
+	notifications := []models.Notification{
+		{Type: models.NotificationMention, Message: "bob mentioned you", Link: "/markets/1"},
+		{Type: models.NotificationResult, Message: "A prediction you voted on was resolved"},
+	}
+
+	message := BuildDigest(user, notifications)
+
+	assert.Equal(t, "ada@example.com", message.To)
+	assert.Equal(t, "Your reThink digest: 2 new notifications", message.Subject)
+	assert.Contains(t, message.Body, "- bob mentioned you\n  "+AppURL+"/markets/1")
+	assert.Contains(t, message.Body, "Stop emailing mention notifications: "+UnsubscribeURL(3, "mention"))
+	assert.NotContains(t, message.Body, "Stop emailing vote notifications")
+	assert.Equal(t, "<"+UnsubscribeURL(3, UnsubscribeDigests)+">", message.Headers["List-Unsubscribe"])
+}
+
+// recordingMailer keeps the messages it is asked to send, failing them all if err is set
+type recordingMailer struct {
+	messages []mailer.Message
+	err      error
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func TestSendDigestsOncePerUser(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+
+	newUser := func(name string) models.User {
+		user := models.User{Username: name, Email: name + "@example.com", Password: "x", DigestFrequency: models.DigestDaily}
+		assert.NoError(t, db.Create(&user).Error)
+		assert.NoError(t, UpdateNotificationPreferences(db, int(user.ID), models.NotificationPreferencesRequest{
+			Channels: map[models.NotificationType]models.NotificationChannel{models.NotificationMention: models.ChannelEmail},
+		}))
+		assert.NoError(t, db.Create(&models.Notification{
+			UserID: int(user.ID), Type: models.NotificationMention, Message: "bob mentioned you", CreatedAt: now.Add(-time.Hour),
+		}).Error)
+		return user
+	}
+
+	// Two instances that both found the user due send one digest between them
+	ada := newUser("ada")
+	m := &recordingMailer{}
+	sent, err := sendDigest(context.Background(), db, m, ada, now)
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	sent, err = sendDigest(context.Background(), db, m, ada, now)
+	assert.NoError(t, err)
+	assert.False(t, sent)
+	assert.Len(t, m.messages, 1)
+
+	// A digest that could not be sent is tried again on the next run
+	bob := newUser("bob")
+	_, err = sendDigest(context.Background(), db, &recordingMailer{err: errors.New("mail server down")}, bob, now)
+	assert.Error(t, err)
+	assert.NoError(t, db.First(&bob, bob.ID).Error)
+	assert.Nil(t, bob.LastDigestAt)
+
+	count, err := SendDigests(context.Background(), db, m, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "bob@example.com", m.messages[1].To)
+}
+
+func TestSendWebhook(t *testing.T) {
+	webhook := &models.Webhook{Secret: "whsec_test"}
+	delivery := &models.WebhookDelivery{
+		ID:      12,
+		Event:   models.WebhookMarketCreated,
+		Payload: `{"event":"market.created","data":{"id":1}}`,
+	}
+
+	var received *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	webhook.URL = receiver.URL
+
+	// The receiver listens on loopback, which deliveries may only reach when allowed
+	WebhookAllowPrivateNetworks = true
+	defer func() { WebhookAllowPrivateNetworks = false }()
+
+	code, err := SendWebhook(context.Background(), NewWebhookClient(time.Second), webhook, delivery)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	// The receiver can verify the signature from the headers and raw body
+	assert.Equal(t, delivery.Payload, string(body))
+	assert.Equal(t, "market.created", received.Header.Get("X-Rethink-Event"))
+	assert.Equal(t, "12", received.Header.Get("X-Rethink-Delivery"))
+	timestamp, err := strconv.ParseInt(received.Header.Get("X-Rethink-Timestamp"), 10, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, SignWebhookPayload("whsec_test", timestamp, body), received.Header.Get("X-Rethink-Signature"))
+	assert.NotEqual(t, SignWebhookPayload("other", timestamp, body), received.Header.Get("X-Rethink-Signature"))
+}
+
+func TestSendWebhookFailure(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	webhook := &models.Webhook{URL: receiver.URL, Secret: "whsec_test"}
+	delivery := &models.WebhookDelivery{Event: models.WebhookMarketClosed, Payload: "{}"}
+
+	code, err := SendWebhook(context.Background(), receiver.Client(), webhook, delivery)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	// A receiver that is down reports no status code
+	receiver.Close()
+	code, err = SendWebhook(context.Background(), receiver.Client(), webhook, delivery)
+	assert.Error(t, err)
+	assert.Zero(t, code)
+}
+
+func TestWebhookPrivateAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	webhook := &models.Webhook{URL: receiver.URL, Secret: "whsec_test"}
+	delivery := &models.WebhookDelivery{Event: models.WebhookMarketClosed, Payload: "{}"}
+
+	// The dialer refuses the loopback receiver, so nothing is sent
+	code, err := SendWebhook(context.Background(), NewWebhookClient(time.Second), webhook, delivery)
+	assert.ErrorIs(t, err, ErrWebhookAddressNotAllowed)
+	assert.Zero(t, code)
+
+	// Hostnames resolving to loopback are refused when connecting, not just literal addresses
+	webhook.URL = strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+	_, err = SendWebhook(context.Background(), NewWebhookClient(time.Second), webhook, delivery)
+	assert.ErrorIs(t, err, ErrWebhookAddressNotAllowed)
+
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		assert.False(t, IsPublicAddress(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "93.184.216.34", "2606:4700::1111"} {
+		assert.True(t, IsPublicAddress(net.ParseIP(addr)), addr)
+	}
+
+	assert.ErrorIs(t, CheckWebhookURL("http://localhost:8080/hook"), ErrWebhookAddressNotAllowed)
+	assert.ErrorIs(t, CheckWebhookURL("http://169.254.169.254/latest/meta-data"), ErrWebhookAddressNotAllowed)
+	assert.ErrorIs(t, CheckWebhookURL("http://[::1]/hook"), ErrWebhookAddressNotAllowed)
+	assert.NoError(t, CheckWebhookURL("https://hooks.example.com/rethink"))
+
+	// Local development can opt in to private receivers
+	WebhookAllowPrivateNetworks = true
+	defer func() { WebhookAllowPrivateNetworks = false }()
+	webhook.URL = receiver.URL
+	code, err = SendWebhook(context.Background(), NewWebhookClient(time.Second), webhook, delivery)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.NoError(t, CheckWebhookURL("http://localhost:8080/hook"))
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, WebhookRetryBase, WebhookBackoff(1))
+	assert.Equal(t, 2*WebhookRetryBase, WebhookBackoff(2))
+	assert.Equal(t, 8*WebhookRetryBase, WebhookBackoff(4))
+	assert.Equal(t, WebhookMaxBackoff, WebhookBackoff(50))
+}
+
+func TestResolutionNagSequence(t *testing.T) {
+	due := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	assert.Equal(t, 0, ResolutionNagSequence(due, due.Add(-time.Hour), day))
+	assert.Equal(t, 0, ResolutionNagSequence(due, due, day))
+	assert.Equal(t, 0, ResolutionNagSequence(due, due.Add(23*time.Hour), day))
+	assert.Equal(t, 1, ResolutionNagSequence(due, due.Add(day), day))
+	assert.Equal(t, 3, ResolutionNagSequence(due, due.Add(3*day+time.Minute), day))
+}
+
+func TestDurationText(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "in 5 hours", untilText(now.Add(5*time.Hour+10*time.Minute), now))
+	assert.Equal(t, "in 1 hour", untilText(now.Add(time.Hour), now))
+	assert.Equal(t, "in 30 minutes", untilText(now.Add(30*time.Minute), now))
+	assert.Equal(t, "3 days ago", sinceText(now.Add(-72*time.Hour), now))
+	assert.Equal(t, "less than a minute", durationText(10*time.Second))
+}
+
+func TestParseLeaderboardWindow(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	from, to, err := ParseLeaderboardWindow("", "", now)
+	assert.NoError(t, err)
+	assert.True(t, from.IsZero())
+	assert.True(t, to.IsZero())
+
+	from, to, err = ParseLeaderboardWindow("7d", "", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -7), from)
+	assert.True(t, to.IsZero())
+
+	from, to, err = ParseLeaderboardWindow("month", "2024-12", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = ParseLeaderboardWindow("month", "", now)
+	assert.ErrorIs(t, err, ErrInvalidLeaderboardWindow)
+	_, _, err = ParseLeaderboardWindow("1y", "", now)
+	assert.ErrorIs(t, err, ErrInvalidLeaderboardWindow)
+}
+
+func TestNewScoreRecord(t *testing.T) {
+	resolvedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	market := models.Market{ID: 7, Category: "politics"}
+	forecast := models.Forecast{ID: 3, UserID: 2, MarketID: 7, Prediction: true, Confidence: 80}
+
+	record := NewScoreRecord(&forecast, &market, true, resolvedAt)
+	assert.Equal(t, uint(2), record.UserID)
+	assert.Equal(t, uint(7), record.MarketID)
+	assert.Equal(t, "politics", record.Category)
+	assert.InDelta(t, 1.6, record.Score, 1e-9)
+	assert.True(t, record.Correct)
+	assert.Equal(t, resolvedAt, record.ResolvedAt)
+
+	record = NewScoreRecord(&forecast, &market, false, resolvedAt)
+	assert.InDelta(t, -0.8, record.Score, 1e-9)
+	assert.False(t, record.Correct)
+}
+
+func TestComputeUserStats(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	records := []models.ScoreRecord{
+		{ID: 4, Correct: false, Score: -0.5, ResolvedAt: day.AddDate(0, 0, 3)},
+		{ID: 1, Correct: true, Score: 1, ResolvedAt: day},
+		{ID: 5, Correct: true, Score: 1.5, ResolvedAt: day.AddDate(0, 0, 4)},
+		{ID: 2, Correct: true, Score: 2, ResolvedAt: day.AddDate(0, 0, 1)},
+		{ID: 3, Correct: true, Score: 0.5, ResolvedAt: day.AddDate(0, 0, 2)},
+	}
+
+	stats := ComputeUserStats(9, 7, records)
+	assert.Equal(t, uint(9), stats.UserID)
+	assert.Equal(t, 7, stats.TotalPredictions)
+	assert.Equal(t, 5, stats.ResolvedPredictions)
+	assert.Equal(t, 4, stats.CorrectPredictions)
+	assert.InDelta(t, 4.5, stats.Score, 1e-9)
+	assert.Equal(t, 1, stats.CurrentStreak)
+	assert.Equal(t, 3, stats.LongestStreak)
+	assert.InDelta(t, 80, stats.Accuracy(), 1e-9)
+
+	empty := ComputeUserStats(9, 0, nil)
+	assert.Zero(t, empty.Accuracy())
+	assert.Zero(t, empty.LongestStreak)
+}
+
+func TestForecastsVisibleNeedsActiveForecast(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+
+	viewer := models.User{Username: "viewer", Email: "viewer@example.com", Password: "x"}
+	other := models.User{Username: "other", Email: "other@example.com", Password: "x"}
+	assert.NoError(t, db.Create(&viewer).Error)
+	assert.NoError(t, db.Create(&other).Error)
+
+	hide := true
+	market := models.Market{Title: "Will it rain?", CreatorID: other.ID, Status: models.MarketOpen, HideForecasts: &hide, CloseDate: now.AddDate(0, 0, 1)}
+	assert.NoError(t, db.Create(&market).Error)
+	assert.NoError(t, db.Create(&models.Forecast{MarketID: market.ID, UserID: other.ID, Prediction: true, Confidence: 70}).Error)
+
+	visible := func() (bool, int) {
+		ok, err := ForecastsVisible(db, &market, viewer.ID)
+		assert.NoError(t, err)
+		recent, err := RecentForecasts(db, other.ID, viewer.ID, 10)
+		assert.NoError(t, err)
+		return ok, len(recent)
+	}
+
+	ok, recent := visible()
+	assert.False(t, ok)
+	assert.Zero(t, recent)
+
+	forecast := models.Forecast{MarketID: market.ID, UserID: viewer.ID, Prediction: false, Confidence: 60}
+	assert.NoError(t, db.Create(&forecast).Error)
+	ok, recent = visible()
+	assert.True(t, ok)
+	assert.Equal(t, 1, recent)
+
+	// Withdrawing hides the crowd again
+	assert.NoError(t, db.Model(&forecast).Update("withdrawn_at", now).Error)
+	ok, recent = visible()
+	assert.False(t, ok)
+	assert.Zero(t, recent)
+}
+
+func TestResolveMarketOnlyOnce(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+
+	creator := models.User{Username: "creator", Email: "creator@example.com", Password: "x"}
+	forecaster := models.User{Username: "forecaster", Email: "forecaster@example.com", Password: "x"}
+	assert.NoError(t, db.Create(&creator).Error)
+	assert.NoError(t, db.Create(&forecaster).Error)
+
+	for _, withForecast := range []bool{false, true} {
+		market := models.Market{Title: "Will it rain?", CreatorID: creator.ID, Status: models.MarketClosed}
+		assert.NoError(t, db.Create(&market).Error)
+		if withForecast {
+			assert.NoError(t, db.Create(&models.Forecast{MarketID: market.ID, UserID: forecaster.ID, Prediction: true, Confidence: 80}).Error)
+		}
+
+		// The creator and an admin both loaded the market before either resolved it
+		byCreator, byAdmin := market, market
+		_, err := ResolveMarket(db, &byCreator, true, now)
+		assert.NoError(t, err)
+		_, err = ResolveMarket(db, &byAdmin, false, now)
+		assert.ErrorIs(t, err, ErrMarketAlreadyResolved)
+
+		var stored models.Market
+		assert.NoError(t, db.First(&stored, market.ID).Error)
+		if assert.NotNil(t, stored.Outcome) {
+			assert.True(t, *stored.Outcome)
+		}
+	}
+
+	assert.NoError(t, db.First(&forecaster, forecaster.ID).Error)
+	assert.InDelta(t, 1.6, forecaster.PredictionScore, 1e-9)
+}
+
+func TestTournamentScore(t *testing.T) {
+	forecast := models.Forecast{Prediction: true, Confidence: 80}
+
+	assert.InDelta(t, 1.6, TournamentScore(models.ScoringConfidence, &forecast, true), 1e-9)
+	assert.InDelta(t, 0.96, TournamentScore(models.ScoringBrier, &forecast, true), 1e-9)
+	assert.InDelta(t, 0.36, TournamentScore(models.ScoringBrier, &forecast, false), 1e-9)
+	assert.Equal(t, 1.0, TournamentScore(models.ScoringAccuracy, &forecast, true))
+	assert.Equal(t, 0.0, TournamentScore(models.ScoringAccuracy, &forecast, false))
+}
+
+func TestRankTournament(t *testing.T) {
+	forecasts := []TournamentForecast{
+		{UserID: 3, Prediction: true, Confidence: 90, Outcome: true},
+		{UserID: 1, Prediction: true, Confidence: 60, Outcome: true},
+		{UserID: 2, Prediction: false, Confidence: 70, Outcome: false},
+		{UserID: 2, Prediction: true, Confidence: 50, Outcome: false},
+		{UserID: 1, Prediction: true, Confidence: 90, Outcome: false},
+	}
+
+	entries := RankTournament(models.ScoringAccuracy, forecasts)
+	if assert.Len(t, entries, 3) {
+		// Every user has one correct prediction, so all three tie for first, listed by user ID
+		for i, entry := range entries {
+			assert.Equal(t, 1, entry.Rank)
+			assert.Equal(t, uint(i+1), entry.UserID)
+			assert.Equal(t, 1.0, entry.Score)
+		}
+		assert.Equal(t, 2, entries[0].Resolved)
+		assert.Equal(t, 1, entries[2].Resolved)
+	}
+
+	entries = RankTournament(models.ScoringConfidence, forecasts)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, uint(3), entries[0].UserID)
+		assert.Equal(t, uint(2), entries[1].UserID)
+		assert.Equal(t, uint(1), entries[2].UserID)
+		assert.Equal(t, []int{1, 2, 3}, []int{entries[0].Rank, entries[1].Rank, entries[2].Rank})
+	}
+}
+
+func TestTournamentBadge(t *testing.T) {
+	assert.Equal(t, "gold", TournamentBadge(1))
+	assert.Equal(t, "bronze", TournamentBadge(3))
+	assert.Equal(t, "finalist", TournamentBadge(4))
+}
+
+func TestEvaluateAchievements(t *testing.T) {
+	keys := func(rules []AchievementRule) []models.AchievementKey {
+		var keys []models.AchievementKey
+		for _, rule := range rules {
+			keys = append(keys, rule.Key)
+		}
+		return keys
+	}
+
+	facts := &AchievementFacts{Stats: models.UserStats{TotalPredictions: 1}}
+	assert.Equal(t, []models.AchievementKey{models.AchievementFirstForecast}, keys(EvaluateAchievements(models.AchievementForecastCreated, facts)))
+	assert.Empty(t, EvaluateAchievements(models.AchievementMarketResolved, facts))
+
+	facts = &AchievementFacts{
+		Stats:       models.UserStats{TotalPredictions: 60, LongestStreak: 10},
+		Calibration: CalibrationReport{Count: 50, CalibrationError: 0.04},
+		MonthlyRank: 10,
+	}
+	assert.Equal(t, []models.AchievementKey{
+		models.AchievementHotStreak,
+		models.AchievementWellCalibrated,
+	}, keys(EvaluateAchievements(models.AchievementMarketResolved, facts)))
+	// Monthly ranks only count once the month is over
+	assert.Equal(t, []models.AchievementKey{models.AchievementMonthlyTop10}, keys(EvaluateAchievements(models.AchievementMonthClosed, facts)))
+
+	facts = &AchievementFacts{
+		Stats:       models.UserStats{LongestStreak: 9},
+		Calibration: CalibrationReport{Count: 49, CalibrationError: 0.01},
+		MonthlyRank: 11,
+	}
+	assert.Empty(t, EvaluateAchievements(models.AchievementMarketResolved, facts))
+	assert.Empty(t, EvaluateAchievements(models.AchievementMonthClosed, facts))
+}
+
+func TestAchievementRulesAreUnique(t *testing.T) {
+	seen := map[models.AchievementKey]bool{}
+	for _, rule := range AchievementRules {
+		assert.False(t, seen[rule.Key], "duplicate achievement %s", rule.Key)
+		seen[rule.Key] = true
+		assert.NotEmpty(t, rule.Name)
+		assert.NotEmpty(t, rule.Events)
+
+		found, ok := AchievementRuleFor(rule.Key)
+		assert.True(t, ok)
+		assert.Equal(t, rule.Name, found.Name)
+	}
+}
+
+func TestAdjustedScore(t *testing.T) {
+	// One lucky forecast barely moves a user off the mean...
+	lucky := AdjustedScore(2, 1, 0.2, 10)
+	// ...while a long record of good forecasts keeps most of its average
+	steady := AdjustedScore(60, 50, 0.2, 10)
+	assert.InDelta(t, 0.3636, lucky, 1e-4)
+	assert.InDelta(t, 1.0333, steady, 1e-4)
+	assert.Greater(t, steady, lucky)
+
+	assert.Equal(t, 0.2, AdjustedScore(0, 0, 0.2, 10))
+	assert.Equal(t, 1.5, AdjustedScore(3, 2, 0.2, 0))
+}
+
+func TestParseLeaderboardMode(t *testing.T) {
+	mode, err := ParseLeaderboardMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LeaderboardRaw, mode)
+
+	mode, err = ParseLeaderboardMode("adjusted")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LeaderboardAdjusted, mode)
+
+	_, err = ParseLeaderboardMode("elo")
+	assert.ErrorIs(t, err, ErrInvalidLeaderboardMode)
+}
+
+func TestParseTeamRanking(t *testing.T) {
+	ranking, err := ParseTeamRanking("")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TeamRankTotal, ranking)
+
+	ranking, err = ParseTeamRanking("average")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TeamRankAverage, ranking)
+
+	_, err = ParseTeamRanking("median")
+	assert.ErrorIs(t, err, ErrInvalidTeamRank)
+}
+
+func TestPairedTTest(t *testing.T) {
+	test := PairedTTest([]float64{-0.1, -0.2, -0.15, -0.05, -0.12})
+	assert.Equal(t, 5, test.N)
+	assert.InDelta(t, -0.124, test.MeanDifference, 1e-9)
+	if assert.NotNil(t, test.TStatistic) && assert.NotNil(t, test.PValue) {
+		assert.InDelta(t, -4.956, *test.TStatistic, 0.001)
+		// Two-sided p-value of t = -4.956 with 4 degrees of freedom
+		assert.InDelta(t, 0.0077, *test.PValue, 0.0005)
+	}
+	assert.True(t, test.Significant)
+
+	test = PairedTTest([]float64{0.1, -0.1, 0.05, -0.05})
+	assert.False(t, test.Significant)
+	if assert.NotNil(t, test.PValue) {
+		assert.InDelta(t, 1, *test.PValue, 1e-9)
+	}
+
+	test = PairedTTest([]float64{0.2})
+	assert.Nil(t, test.PValue)
+	assert.Equal(t, 0.2, test.MeanDifference)
+}
+
+func TestCompareForecasts(t *testing.T) {
+	yes, no := true, false
+	pairs := []ForecastPair{
+		{
+			Market: models.Market{ID: 1, Status: models.MarketResolved, Outcome: &yes},
+			A:      models.Forecast{Prediction: true, Confidence: 90},
+			B:      models.Forecast{Prediction: true, Confidence: 60},
+		},
+		{
+			Market: models.Market{ID: 2, Status: models.MarketResolved, Outcome: &no},
+			A:      models.Forecast{Prediction: true, Confidence: 70},
+			B:      models.Forecast{Prediction: false, Confidence: 80},
+		},
+		{
+			Market: models.Market{ID: 3, Status: models.MarketOpen},
+			A:      models.Forecast{Prediction: true, Confidence: 50},
+			B:      models.Forecast{Prediction: false, Confidence: 50},
+		},
+	}
+
+	result := CompareForecasts(pairs)
+	assert.Equal(t, 3, result.Shared)
+	assert.Equal(t, 2, result.Resolved)
+	assert.Equal(t, 1, result.Wins)
+	assert.Equal(t, 1, result.Losses)
+	assert.InDelta(t, (1.8-1.2)+(-0.7-1.6), result.ScoreDiff, 1e-9)
+	assert.InDelta(t, (0.01+0.49)/2, result.UserMeanBrier, 1e-9)
+	assert.InDelta(t, (0.16+0.04)/2, result.OtherMeanBrier, 1e-9)
+	assert.Equal(t, 2, result.BrierTest.N)
+
+	assert.Nil(t, result.Markets[2].ScoreDiff)
+	assert.Nil(t, result.Markets[2].User.Score)
+	assert.InDelta(t, 0.5, result.Markets[2].Other.Probability, 1e-9)
+}
+
+func TestAdvanceDayStreak(t *testing.T) {
+	var stats models.UserStats
+
+	assert.True(t, AdvanceDayStreak(&stats, "2025-06-01"))
+	assert.False(t, AdvanceDayStreak(&stats, "2025-06-01"))
+	assert.True(t, AdvanceDayStreak(&stats, "2025-06-02"))
+	assert.True(t, AdvanceDayStreak(&stats, "2025-06-03"))
+	assert.Equal(t, 3, stats.DayStreak)
+
+	// A missed day starts over, but the longest streak is kept
+	assert.True(t, AdvanceDayStreak(&stats, "2025-06-05"))
+	assert.Equal(t, 1, stats.DayStreak)
+	assert.Equal(t, 3, stats.LongestDayStreak)
+	assert.Equal(t, "2025-06-05", stats.LastForecastDay)
+
+	// Earlier days, such as after moving to a time zone further west, change nothing
+	assert.False(t, AdvanceDayStreak(&stats, "2025-06-04"))
+}
+
+func TestComputeDayStreakTimeZones(t *testing.T) {
+	// 23:30 UTC on consecutive days is the next morning in Tokyo but the same evening in New York
+	times := []time.Time{
+		time.Date(2025, 6, 2, 23, 30, 0, 0, time.UTC),
+		time.Date(2025, 6, 1, 23, 30, 0, 0, time.UTC),
+		time.Date(2025, 6, 2, 1, 0, 0, 0, time.UTC),
+	}
+
+	var utc models.UserStats
+	ComputeDayStreak(&utc, times, time.UTC)
+	assert.Equal(t, 2, utc.DayStreak)
+	assert.Equal(t, "2025-06-02", utc.LastForecastDay)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if assert.NoError(t, err) {
+		var stats models.UserStats
+		ComputeDayStreak(&stats, times, tokyo)
+		// 06-02 09:30, 06-02 10:00 and 06-03 08:30 in Tokyo
+		assert.Equal(t, 2, stats.DayStreak)
+		assert.Equal(t, "2025-06-03", stats.LastForecastDay)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if assert.NoError(t, err) {
+		var stats models.UserStats
+		ComputeDayStreak(&stats, times, newYork)
+		// 06-01 19:30, 06-01 21:00 and 06-02 19:30 in New York
+		assert.Equal(t, 2, stats.DayStreak)
+		assert.Equal(t, "2025-06-02", stats.LastForecastDay)
+	}
+}
+
+func TestSetAdmin(t *testing.T) {
+	db := openTestDB(t)
+	user := models.User{Username: "root", Email: "root@example.com", Password: "x"}
+	assert.NoError(t, db.Create(&user).Error)
+
+	_, err := SetAdmin(db, "root@example.com", true)
+	assert.NoError(t, err)
+	assert.NoError(t, db.First(&user, user.ID).Error)
+	assert.True(t, user.IsAdmin)
+
+	_, err = SetAdmin(db, "root@example.com", false)
+	assert.NoError(t, err)
+	assert.NoError(t, db.First(&user, user.ID).Error)
+	assert.False(t, user.IsAdmin)
+
+	_, err = SetAdmin(db, "nobody@example.com", true)
+	assert.ErrorIs(t, err, utils.ErrNotFound)
+}
+
+func TestSendRemindersContinuesAfterFailure(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+
+	var users []models.User
+	for _, name := range []string{"first", "broken", "last"} {
+		user := models.User{Username: name, Email: name + "@example.com", Password: "x"}
+		assert.NoError(t, db.Create(&user).Error)
+		users = append(users, user)
+	}
+	broken := users[1]
+
+	// Each user forecast on a market closing soon
+	for _, user := range users {
+		market := models.Market{Title: "Closes soon", CreatorID: user.ID, Status: models.MarketOpen,
+			CloseDate: now.Add(time.Hour), ResolveDate: now.Add(48 * time.Hour)}
+		assert.NoError(t, db.Create(&market).Error)
+		assert.NoError(t, db.Create(&models.Forecast{UserID: user.ID, MarketID: market.ID, Prediction: true, Confidence: 70}).Error)
+	}
+
+	// Notifying the broken user fails
+	err := db.Callback().Create().Before("gorm:create").Register("test:fail_notification", func(tx *gorm.DB) {
+		if n, ok := tx.Statement.Dest.(*models.Notification); ok && n.UserID == int(broken.ID) {
+			tx.AddError(errors.New("mailbox unavailable"))
+		}
+	})
+	assert.NoError(t, err)
+
+	sent, err := SendReminders(db, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	var notified []int
+	db.Model(&models.Notification{}).Where("type = ?", models.NotificationEndingSoon).Order("user_id").Pluck("user_id", &notified)
+	assert.Equal(t, []int{int(users[0].ID), int(users[2].ID)}, notified)
+
+	// The failed reminder was not logged as sent, so the next run tries it again
+	var logged int64
+	db.Model(&models.Reminder{}).Where("user_id = ?", broken.ID).Count(&logged)
+	assert.Zero(t, logged)
+}
+
+func TestTournamentStandingsWindow(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	start, end := now.AddDate(0, 0, -10), now.AddDate(0, 0, -2)
+
+	yes := true
+	market := models.Market{Title: "Will it ship?", Status: models.MarketResolved, Outcome: &yes,
+		CloseDate: now.AddDate(0, 0, -1), ResolveDate: now.AddDate(0, 0, -1)}
+	assert.NoError(t, db.Create(&market).Error)
+
+	// Listing a market twice still makes a tournament over that one market
+	tournament, err := CreateTournament(db, &models.TournamentRequest{
+		Name:      "Spring Cup",
+		StartDate: start,
+		EndDate:   end,
+		MarketIDs: []uint{market.ID, market.ID},
+	}, 1)
+	assert.NoError(t, err)
+	assert.Len(t, tournament.Markets, 1)
+
+	users := make(map[string]uint)
+	for _, name := range []string{"early", "steady", "revised", "late"} {
+		user := models.User{Username: name, Email: name + "@example.com", Password: "x"}
+		assert.NoError(t, db.Create(&user).Error)
+		users[name] = user.ID
+	}
+	forecast := func(name string, at time.Time, prediction bool, confidence float64) *models.Forecast {
+		f := &models.Forecast{UserID: users[name], MarketID: market.ID, Prediction: prediction, Confidence: confidence, CreatedAt: at, UpdatedAt: at}
+		assert.NoError(t, SaveForecast(db, f))
+		return f
+	}
+
+	// Made before the tournament started and never touched again: not counted
+	forecast("early", start.AddDate(0, 0, -5), true, 95)
+	// Made during the tournament
+	forecast("steady", start.AddDate(0, 0, 3), true, 80)
+	// Made during the tournament and changed after it ended: scored at its value at the end
+	revised := forecast("revised", start.AddDate(0, 0, 4), true, 90)
+	revised.Prediction, revised.UpdatedAt = false, now
+	assert.NoError(t, SaveForecast(db, revised))
+	// Made after the tournament ended: not counted
+	forecast("late", now, true, 99)
+
+	standings, err := TournamentStandings(db, tournament)
+	assert.NoError(t, err)
+	if assert.Len(t, standings, 2) {
+		assert.Equal(t, users["revised"], standings[0].UserID)
+		assert.Equal(t, users["steady"], standings[1].UserID)
+		assert.Equal(t, "revised", standings[0].Username)
+	}
+}
+
+func TestAwardMonthlyAchievements(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Date(2025, 7, 1, 0, 5, 0, 0, time.UTC)
+
+	// Twelve users scored in June; the last two ranked 11th and 12th
+	var june []models.User
+	for i := 0; i < 12; i++ {
+		user := models.User{Username: fmt.Sprintf("june%d", i), Email: fmt.Sprintf("june%d@example.com", i), Password: "x"}
+		assert.NoError(t, db.Create(&user).Error)
+		june = append(june, user)
+		record := models.ScoreRecord{UserID: user.ID, MarketID: 1, Score: float64(12 - i), ResolvedAt: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)}
+		assert.NoError(t, db.Create(&record).Error)
+	}
+	// A user leading July so far has not finished a month yet
+	july := models.User{Username: "july", Email: "july@example.com", Password: "x"}
+	assert.NoError(t, db.Create(&july).Error)
+	assert.NoError(t, db.Create(&models.ScoreRecord{UserID: july.ID, MarketID: 2, Score: 50, ResolvedAt: now}).Error)
+
+	checked, err := AwardMonthlyAchievements(db, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, checked)
+
+	var awarded []uint
+	db.Model(&models.UserAchievement{}).Where("key = ?", models.AchievementMonthlyTop10).Order("user_id").Pluck("user_id", &awarded)
+	expected := make([]uint, 0, 10)
+	for _, user := range june[:10] {
+		expected = append(expected, user.ID)
+	}
+	assert.Equal(t, expected, awarded)
+
+	// Running again for the same month awards nothing new
+	_, err = AwardMonthlyAchievements(db, now.Add(time.Hour))
+	assert.NoError(t, err)
+	var count int64
+	db.Model(&models.UserAchievement{}).Count(&count)
+	assert.Equal(t, int64(10), count)
+}
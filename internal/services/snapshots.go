@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+)
+
+// MinResolution is the finest bucket size accepted for market timeseries
+const MinResolution = time.Minute
+
+// ErrInvalidResolution is returned when a timeseries resolution cannot be parsed
+var ErrInvalidResolution = errors.New("resolution must be a duration of at least 1m, e.g. 15m, 1h or 1d")
+
+// RecordMarketSnapshot aggregates the current forecasts on a market and stores them as a snapshot
+func RecordMarketSnapshot(db *gorm.DB, marketID uint) (*models.MarketSnapshot, error) {
+	snapshot, err := aggregateMarket(db, marketID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Create(snapshot).Error; err != nil {
+		return nil, err
+	}
+	PublishMarketUpdate(snapshot)
+
+	return snapshot, nil
+}
+
+// RecordChangedMarketSnapshot records a snapshot only if the market's aggregate differs from its
+// latest snapshot, so idle markets do not grow their history. It returns nil when nothing changed.
+func RecordChangedMarketSnapshot(db *gorm.DB, marketID uint) (*models.MarketSnapshot, error) {
+	snapshot, err := aggregateMarket(db, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest []models.MarketSnapshot
+	if err := db.Where("market_id = ?", marketID).Order("created_at desc").Order("id desc").Limit(1).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	if len(latest) == 1 && latest[0].PredictionCount == snapshot.PredictionCount &&
+		math.Abs(latest[0].Probability-snapshot.Probability) < 1e-9 {
+		return nil, nil
+	}
+
+	if err := db.Create(snapshot).Error; err != nil {
+		return nil, err
+	}
+	PublishMarketUpdate(snapshot)
+
+	return snapshot, nil
+}
+
+// aggregateMarket builds an unsaved snapshot of the current forecasts on a market
+func aggregateMarket(db *gorm.DB, marketID uint) (*models.MarketSnapshot, error) {
+	var aggregate struct {
+		Count       int
+		Probability float64
+	}
+
+	// Confidence is stated for the chosen side, so flip it for "false" forecasts
+	err := db.Model(&models.Forecast{}).
+		Select("COUNT(*) AS count, COALESCE(AVG(CASE WHEN prediction THEN confidence ELSE 100 - confidence END), 0) / 100 AS probability").
+		Where("market_id = ? AND withdrawn_at IS NULL", marketID).
+		Scan(&aggregate).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MarketSnapshot{
+		MarketID:        marketID,
+		Probability:     aggregate.Probability,
+		PredictionCount: aggregate.Count,
+		CreatedAt:       time.Now(),
+	}, nil
+}
+
+// GetMarketTimeseries returns the market's snapshot history between from and to, downsampled to resolution.
+// The database buckets the snapshots and keeps the last one in each, so only one row per point is loaded.
+func GetMarketTimeseries(db *gorm.DB, marketID uint, from, to time.Time, resolution time.Duration) ([]models.TimeseriesPoint, error) {
+	seconds := int64(resolution / time.Second)
+	bucket := timeBucket(db, "created_at")
+
+	latest := db.Model(&models.MarketSnapshot{}).
+		Select("probability, prediction_count, "+bucket+" AS bucket, "+
+			"ROW_NUMBER() OVER (PARTITION BY "+bucket+" ORDER BY created_at DESC, id DESC) AS position", seconds, seconds).
+		Where("market_id = ?", marketID)
+
+	if !from.IsZero() {
+		latest = latest.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		latest = latest.Where("created_at <= ?", to)
+	}
+
+	var rows []struct {
+		Bucket          int64
+		Probability     float64
+		PredictionCount int
+	}
+	err := db.Table("(?) AS buckets", latest).
+		Select("bucket, probability, prediction_count").
+		Where("position = 1").
+		Order("bucket asc").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]models.TimeseriesPoint, len(rows))
+	for i, row := range rows {
+		points[i] = models.TimeseriesPoint{
+			Time:            time.Unix(row.Bucket*seconds, 0).UTC(),
+			Probability:     row.Probability,
+			PredictionCount: row.PredictionCount,
+		}
+	}
+	return points, nil
+}
+
+// timeBucket returns a SQL expression numbering the bucket a time column falls into, counted in
+// buckets of a bound number of seconds since the Unix epoch
+func timeBucket(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "(CAST(strftime('%s', " + column + ") AS INTEGER) / ?)"
+	}
+	return "CAST(FLOOR(EXTRACT(EPOCH FROM " + column + ") / ?) AS BIGINT)"
+}
+
+// ParseResolution parses a timeseries resolution such as "15m", "1h" or "1d"
+func ParseResolution(value string) (time.Duration, error) {
+	var resolution time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, ErrInvalidResolution
+		}
+		resolution = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, ErrInvalidResolution
+		}
+		resolution = d
+	}
+
+	if resolution < MinResolution {
+		return 0, ErrInvalidResolution
+	}
+	return resolution, nil
+}
+
+// DownsampleSnapshots buckets time-ordered snapshots by resolution, keeping the last value in each bucket.
+// Buckets are aligned to the Unix epoch, as in GetMarketTimeseries.
+func DownsampleSnapshots(snapshots []models.MarketSnapshot, resolution time.Duration) []models.TimeseriesPoint {
+	points := []models.TimeseriesPoint{}
+	seconds := int64(resolution / time.Second)
+
+	for _, snapshot := range snapshots {
+		bucket := time.Unix(snapshot.CreatedAt.Unix()/seconds*seconds, 0).UTC()
+		point := models.TimeseriesPoint{
+			Time:            bucket,
+			Probability:     snapshot.Probability,
+			PredictionCount: snapshot.PredictionCount,
+		}
+
+		// A later snapshot in the same bucket replaces the earlier one
+		if n := len(points); n > 0 && points[n-1].Time.Equal(bucket) {
+			points[n-1] = point
+			continue
+		}
+		points = append(points, point)
+	}
+
+	return points
+}
+
+// RunSnapshotJob periodically records a snapshot for every open market whose forecasts changed
+// since its last snapshot, until ctx is cancelled
+func RunSnapshotJob(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var marketIDs []uint
+			if err := db.Model(&models.Market{}).Where("status = ?", models.MarketOpen).Pluck("id", &marketIDs).Error; err != nil {
+				log.Printf("Failed to load open markets for snapshots: %v", err)
+				continue
+			}
+
+			for _, id := range marketIDs {
+				if _, err := RecordChangedMarketSnapshot(db, id); err != nil {
+					log.Printf("Failed to record snapshot for market %d: %v", id, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AdjustForecastCount changes a user's count of active forecasts by delta
+func AdjustForecastCount(db *gorm.DB, userID uint, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+
+	stats := models.UserStats{UserID: userID, TotalPredictions: delta, UpdatedAt: time.Now()}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"total_predictions": gorm.Expr("user_stats.total_predictions + ?", delta),
+			"updated_at":        stats.UpdatedAt,
+		}),
+	}).Create(&stats).Error
+}
+
+// ApplyScoreRecords adds the scores of a resolved market to its forecasters' stats.
+// Call it in the transaction that creates the records so the stats never drift from them.
+func ApplyScoreRecords(db *gorm.DB, records []models.ScoreRecord) error {
+	now := time.Now()
+	for _, record := range records {
+		stats := models.UserStats{
+			UserID:              record.UserID,
+			ResolvedPredictions: 1,
+			Score:               record.Score,
+			UpdatedAt:           now,
+		}
+		if record.Correct {
+			stats.CorrectPredictions = 1
+			stats.CurrentStreak = 1
+			stats.LongestStreak = 1
+		}
+
+		// A correct prediction extends the streak and a wrong one ends it
+		currentStreak := gorm.Expr("0")
+		longestStreak := gorm.Expr("user_stats.longest_streak")
+		if record.Correct {
+			currentStreak = gorm.Expr("user_stats.current_streak + 1")
+			longestStreak = gorm.Expr("CASE WHEN user_stats.current_streak + 1 > user_stats.longest_streak " +
+				"THEN user_stats.current_streak + 1 ELSE user_stats.longest_streak END")
+		}
+
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"resolved_predictions": gorm.Expr("user_stats.resolved_predictions + 1"),
+				"correct_predictions":  gorm.Expr("user_stats.correct_predictions + ?", stats.CorrectPredictions),
+				"score":                gorm.Expr("user_stats.score + ?", record.Score),
+				"current_streak":       currentStreak,
+				"longest_streak":       longestStreak,
+				"updated_at":           now,
+			}),
+		}).Create(&stats).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ComputeUserStats derives a user's stats from their active forecast count and score records
+func ComputeUserStats(userID uint, totalPredictions int, records []models.ScoreRecord) models.UserStats {
+	sorted := make([]models.ScoreRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].ResolvedAt.Equal(sorted[j].ResolvedAt) {
+			return sorted[i].ResolvedAt.Before(sorted[j].ResolvedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	stats := models.UserStats{UserID: userID, TotalPredictions: totalPredictions}
+	for _, record := range sorted {
+		stats.ResolvedPredictions++
+		stats.Score += record.Score
+		if !record.Correct {
+			stats.CurrentStreak = 0
+			continue
+		}
+		stats.CorrectPredictions++
+		stats.CurrentStreak++
+		if stats.CurrentStreak > stats.LongestStreak {
+			stats.LongestStreak = stats.CurrentStreak
+		}
+	}
+	return stats
+}
+
+// RebuildUserStatsFor recomputes one user's stats from their forecasts, forecast history and score records
+func RebuildUserStatsFor(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Hold the stats row so concurrent updates wait for the rebuilt values
+		var existing []models.UserStats
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		var total int64
+		if err := tx.Model(&models.Forecast{}).Where("user_id = ? AND withdrawn_at IS NULL", userID).Count(&total).Error; err != nil {
+			return err
+		}
+
+		var records []models.ScoreRecord
+		if err := tx.Where("user_id = ?", userID).Find(&records).Error; err != nil {
+			return err
+		}
+
+		stats := ComputeUserStats(userID, int(total), records)
+		if err := loadDayStreak(tx, &stats); err != nil {
+			return err
+		}
+		stats.UpdatedAt = time.Now()
+		return tx.Save(&stats).Error
+	})
+}
+
+// RebuildUserStats recomputes every user's stats and returns how many users were rebuilt
+func RebuildUserStats(db *gorm.DB) (int, error) {
+	var userIDs []uint
+	if err := db.Model(&models.User{}).Order("id").Pluck("id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+
+	for i, userID := range userIDs {
+		if err := RebuildUserStatsFor(db, userID); err != nil {
+			return i, err
+		}
+	}
+	return len(userIDs), nil
+}
+
+// EnsureUserStats builds the stats table the first time it is deployed, when it is still empty
+func EnsureUserStats(db *gorm.DB) (int, error) {
+	var count int64
+	if err := db.Model(&models.UserStats{}).Count(&count).Error; err != nil || count > 0 {
+		return 0, err
+	}
+	return RebuildUserStats(db)
+}
+
+// GetUserStats returns a user's stats, which are all zero until they first predict
+func GetUserStats(db *gorm.DB, userID uint) (models.UserStats, error) {
+	stats := models.UserStats{UserID: userID}
+	err := db.Where("user_id = ?", userID).Limit(1).Find(&stats).Error
+	return stats, err
+}
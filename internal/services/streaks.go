@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AdvanceDayStreak counts a forecast made on day (YYYY-MM-DD) towards the daily streak and
+// reports whether the stats changed. Days at or before the last counted day change nothing.
+func AdvanceDayStreak(stats *models.UserStats, day string) bool {
+	if day <= stats.LastForecastDay {
+		return false
+	}
+
+	if stats.LastForecastDay != "" && stats.LastForecastDay == models.PreviousDay(day) {
+		stats.DayStreak++
+	} else {
+		stats.DayStreak = 1
+	}
+	if stats.DayStreak > stats.LongestDayStreak {
+		stats.LongestDayStreak = stats.DayStreak
+	}
+	stats.LastForecastDay = day
+	return true
+}
+
+// ComputeDayStreak replays forecast times, in any order, into the stats' daily streak
+func ComputeDayStreak(stats *models.UserStats, times []time.Time, loc *time.Location) {
+	sorted := make([]time.Time, len(times))
+	copy(sorted, times)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	stats.DayStreak, stats.LongestDayStreak, stats.LastForecastDay = 0, 0, ""
+	for _, t := range sorted {
+		AdvanceDayStreak(stats, models.LocalDay(t, loc))
+	}
+}
+
+// RecordForecastDay counts a new or updated forecast towards the user's daily streak.
+// Call it in the transaction that saves the forecast.
+func RecordForecastDay(db *gorm.DB, userID uint, at time.Time) error {
+	var user models.User
+	if err := db.Select("id", "time_zone").First(&user, userID).Error; err != nil {
+		return err
+	}
+	day := models.LocalDay(at, user.Location())
+
+	// Make sure the stats row exists so it can be locked
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserStats{UserID: userID, UpdatedAt: at}).Error; err != nil {
+		return err
+	}
+
+	var stats models.UserStats
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&stats).Error; err != nil {
+		return err
+	}
+	if !AdvanceDayStreak(&stats, day) {
+		return nil
+	}
+
+	return db.Model(&models.UserStats{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"day_streak":         stats.DayStreak,
+		"longest_day_streak": stats.LongestDayStreak,
+		"last_forecast_day":  stats.LastForecastDay,
+		"updated_at":         at,
+	}).Error
+}
+
+// loadDayStreak recomputes a user's daily streak from their forecast history
+func loadDayStreak(db *gorm.DB, stats *models.UserStats) error {
+	var user models.User
+	if err := db.Select("id", "time_zone").First(&user, stats.UserID).Error; err != nil {
+		return err
+	}
+
+	var times []time.Time
+	err := db.Model(&models.ForecastRevision{}).
+		Where("user_id = ? AND withdrawn = ?", stats.UserID, false).
+		Pluck("created_at", &times).Error
+	if err != nil {
+		return err
+	}
+
+	ComputeDayStreak(stats, times, user.Location())
+	return nil
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/pubsub"
+	"github.com/domolitom/reThink/utils"
+)
+
+// Stream event types
+const (
+	StreamEventNotification = "notification"
+	StreamEventMarket       = "market"
+	// StreamEventReset tells a resuming client that events were missed and it should refetch
+	StreamEventReset = "reset"
+)
+
+// StreamBufferSize is how many recent events are kept for clients resuming with Last-Event-ID
+var StreamBufferSize = utils.GetEnvInt("STREAM_BUFFER_SIZE", 1000)
+
+// StreamSubscriberBuffer is how many events may queue for a client before it is disconnected as too slow
+var StreamSubscriberBuffer = utils.GetEnvInt("STREAM_SUBSCRIBER_BUFFER", 64)
+
+// StreamTopic is the pub/sub topic stream events travel on between server instances
+const StreamTopic = "rethink_stream"
+
+// Stream is the hub that fans real-time events out to this instance's connected clients
+var Stream = NewStreamHub(StreamBufferSize, StreamSubscriberBuffer)
+
+var (
+	bus             pubsub.PubSub
+	unsubscribeFrom func()
+)
+
+func init() {
+	UseBus(pubsub.NewMemory())
+}
+
+// UseBus routes real-time events through the given pub/sub, so events produced on any
+// instance reach clients connected to every instance
+func UseBus(ps pubsub.PubSub) {
+	if unsubscribeFrom != nil {
+		unsubscribeFrom()
+	}
+	bus = ps
+	unsubscribeFrom = ps.Subscribe(StreamTopic, func(payload []byte) {
+		var event StreamEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Printf("Failed to decode stream event: %v", err)
+			return
+		}
+		Stream.Publish(event)
+	})
+}
+
+// StreamEvent is a real-time event sent to clients.
+// Events target either a single user (notifications) or everyone subscribed to a market.
+// IDs are assigned by each instance's hub when the event is delivered locally.
+type StreamEvent struct {
+	ID       uint64          `json:"-"`
+	Type     string          `json:"type"`
+	UserID   int             `json:"user_id,omitempty"`
+	MarketID uint            `json:"market_id,omitempty"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// MarketUpdate is the payload of a market event
+type MarketUpdate struct {
+	MarketID        uint      `json:"market_id"`
+	Probability     float64   `json:"probability"`
+	PredictionCount int       `json:"prediction_count"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// StreamSubscriber receives the events for one connected client
+type StreamSubscriber struct {
+	UserID  int
+	markets map[uint]bool
+	events  chan StreamEvent
+}
+
+// Events returns the subscriber's event channel. It is closed when the hub drops the
+// subscriber, either on Unsubscribe or because the client fell too far behind.
+func (s *StreamSubscriber) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// wants reports whether the event is addressed to the subscriber
+func (s *StreamSubscriber) wants(event StreamEvent) bool {
+	switch event.Type {
+	case StreamEventNotification:
+		return event.UserID == s.UserID
+	case StreamEventMarket:
+		return s.markets[event.MarketID]
+	}
+	return false
+}
+
+// StreamHub delivers published events to matching subscribers and keeps a ring buffer
+// of recent events so reconnecting clients can resume where they left off
+type StreamHub struct {
+	mu          sync.Mutex
+	instance    string
+	lastID      uint64
+	recent      []StreamEvent
+	bufferSize  int
+	queueSize   int
+	subscribers map[*StreamSubscriber]struct{}
+}
+
+// NewStreamHub creates a hub keeping bufferSize recent events and queueing up to
+// queueSize events per subscriber
+func NewStreamHub(bufferSize, queueSize int) *StreamHub {
+	return &StreamHub{
+		instance:    newInstanceID(),
+		bufferSize:  bufferSize,
+		queueSize:   queueSize,
+		subscribers: make(map[*StreamSubscriber]struct{}),
+	}
+}
+
+// Publish assigns the event an ID and delivers it to every matching subscriber.
+// It never blocks: a subscriber whose queue is full is disconnected and can resume
+// from the buffer with Last-Event-ID.
+func (h *StreamHub) Publish(event StreamEvent) StreamEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event.ID = h.lastID
+
+	h.recent = append(h.recent, event)
+	if len(h.recent) > h.bufferSize {
+		h.recent = h.recent[len(h.recent)-h.bufferSize:]
+	}
+
+	for subscriber := range h.subscribers {
+		if !subscriber.wants(event) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			h.drop(subscriber)
+		}
+	}
+
+	return event
+}
+
+// EventID returns the ID clients see for an event. It names the hub's instance as well,
+// since sequence numbers from one server mean nothing to another.
+func (h *StreamHub) EventID(event StreamEvent) string {
+	return fmt.Sprintf("%s-%d", h.instance, event.ID)
+}
+
+// Subscribe registers a client for its own notifications and the given markets.
+// When lastEventID is set, the buffered events the client missed are returned for replay;
+// if some of them are unknown to this hub, a single reset event is returned instead.
+func (h *StreamHub) Subscribe(userID int, marketIDs []uint, lastEventID string) (*StreamSubscriber, []StreamEvent) {
+	subscriber := &StreamSubscriber{
+		UserID:  userID,
+		markets: make(map[uint]bool, len(marketIDs)),
+		events:  make(chan StreamEvent, h.queueSize),
+	}
+	for _, id := range marketIDs {
+		subscriber.markets[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Registering under the lock means nothing is missed or sent twice between replay and live events
+	h.subscribers[subscriber] = struct{}{}
+
+	if lastEventID == "" {
+		return subscriber, nil
+	}
+
+	// IDs from another instance, or older than the buffer, cannot be resumed
+	lastSeq, ok := h.parseEventID(lastEventID)
+	evicted := lastSeq < h.lastID && (len(h.recent) == 0 || h.recent[0].ID > lastSeq+1)
+	if !ok || lastSeq > h.lastID || evicted {
+		return subscriber, []StreamEvent{{ID: h.lastID, Type: StreamEventReset, Data: json.RawMessage("{}")}}
+	}
+
+	var replay []StreamEvent
+	for _, event := range h.recent {
+		if event.ID > lastSeq && subscriber.wants(event) {
+			replay = append(replay, event)
+		}
+	}
+	return subscriber, replay
+}
+
+// Unsubscribe removes a subscriber and closes its event channel
+func (h *StreamHub) Unsubscribe(subscriber *StreamSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(subscriber)
+}
+
+// parseEventID extracts the sequence number from an ID issued by this hub
+func (h *StreamHub) parseEventID(id string) (uint64, bool) {
+	instance, seq, found := strings.Cut(id, "-")
+	if !found || instance != h.instance {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// drop removes a subscriber; the caller must hold h.mu
+func (h *StreamHub) drop(subscriber *StreamSubscriber) {
+	if _, ok := h.subscribers[subscriber]; ok {
+		delete(h.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+// newInstanceID returns a random ID distinguishing this server instance's event IDs
+func newInstanceID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// publishStreamEvent sends an event to every instance's hub.
+// Real-time delivery is best effort, so failures are logged rather than returned.
+func publishStreamEvent(event StreamEvent, data interface{}) {
+	var err error
+	if event.Data, err = json.Marshal(data); err != nil {
+		log.Printf("Failed to encode %s stream event: %v", event.Type, err)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s stream event: %v", event.Type, err)
+		return
+	}
+	if err := bus.Publish(context.Background(), StreamTopic, payload); err != nil {
+		log.Printf("Failed to publish %s stream event: %v", event.Type, err)
+	}
+}
+
+// PublishNotification pushes a stored notification to its user's open streams
+func PublishNotification(notification *models.Notification) {
+	publishStreamEvent(StreamEvent{
+		Type:   StreamEventNotification,
+		UserID: notification.UserID,
+	}, notification)
+}
+
+// PublishMarketUpdate pushes a market's latest aggregate to its subscribers
+func PublishMarketUpdate(snapshot *models.MarketSnapshot) {
+	publishStreamEvent(StreamEvent{
+		Type:     StreamEventMarket,
+		MarketID: snapshot.MarketID,
+	}, MarketUpdate{
+		MarketID:        snapshot.MarketID,
+		Probability:     snapshot.Probability,
+		PredictionCount: snapshot.PredictionCount,
+		UpdatedAt:       snapshot.CreatedAt,
+	})
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Team errors
+var (
+	ErrTeamNameTaken        = errors.New("team name is already taken")
+	ErrAlreadyTeamMember    = errors.New("user is already a member of this team")
+	ErrTeamOwnerCannotLeave = errors.New("the team owner cannot leave or be removed; transfer ownership first")
+	ErrInvalidTeamRank      = errors.New("rank must be total or average")
+)
+
+// ParseTeamRanking parses the rank query parameter, defaulting to total
+func ParseTeamRanking(value string) (models.TeamRanking, error) {
+	switch models.TeamRanking(value) {
+	case "", models.TeamRankTotal:
+		return models.TeamRankTotal, nil
+	case models.TeamRankAverage:
+		return models.TeamRankAverage, nil
+	}
+	return "", ErrInvalidTeamRank
+}
+
+// CreateTeam creates a team with its creator as owner
+func CreateTeam(db *gorm.DB, input *models.TeamRequest, creatorID uint) (*models.Team, error) {
+	now := time.Now()
+	team := models.Team{
+		Name:        input.Name,
+		Description: input.Description,
+		CreatorID:   creatorID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Model(&models.Team{}).Where("LOWER(name) = LOWER(?)", input.Name).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrTeamNameTaken
+		}
+
+		if err := tx.Create(&team).Error; err != nil {
+			return err
+		}
+		owner := models.TeamMember{TeamID: team.ID, UserID: creatorID, Role: models.TeamOwner, JoinedAt: now}
+		return tx.Create(&owner).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// TeamRoleOf returns the user's role in the team, or "" if they are not a member
+func TeamRoleOf(db *gorm.DB, teamID, userID uint) (models.TeamRole, error) {
+	var members []models.TeamMember
+	if err := db.Where("team_id = ? AND user_id = ?", teamID, userID).Limit(1).Find(&members).Error; err != nil {
+		return "", err
+	}
+	if len(members) == 0 {
+		return "", nil
+	}
+	return members[0].Role, nil
+}
+
+// InviteToTeam invites a user to the team and notifies them, reopening any earlier invitation
+func InviteToTeam(db *gorm.DB, team *models.Team, inviterID, userID uint) (*models.TeamInvite, error) {
+	role, err := TeamRoleOf(db, team.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != "" {
+		return nil, ErrAlreadyTeamMember
+	}
+
+	invite := models.TeamInvite{
+		TeamID:    team.ID,
+		UserID:    userID,
+		InviterID: inviterID,
+		Status:    models.InvitePending,
+		CreatedAt: time.Now(),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "team_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"inviter_id", "status", "created_at", "responded_at"}),
+		}).Create(&invite).Error
+		if err != nil {
+			return err
+		}
+		// The upsert does not report the existing row's ID, so read it back
+		if err := tx.Where("team_id = ? AND user_id = ?", team.ID, userID).First(&invite).Error; err != nil {
+			return err
+		}
+
+		notification := TeamInviteNotification(team, userID)
+		return CreateNotification(tx, &notification)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// TeamInviteNotification tells a user they were invited to join the team
+func TeamInviteNotification(team *models.Team, userID uint) models.Notification {
+	return models.Notification{
+		UserID:  int(userID),
+		Type:    models.NotificationTeamInvite,
+		Message: fmt.Sprintf("You have been invited to join the team \"%s\"", team.Name),
+		Link:    fmt.Sprintf("/teams/%d", team.ID),
+	}
+}
+
+// RespondToTeamInvite accepts or declines one of the user's pending invitations.
+// It returns utils.ErrNotFound if the user has no such pending invitation.
+func RespondToTeamInvite(db *gorm.DB, inviteID, userID uint, accept bool) (*models.TeamInvite, error) {
+	var invite models.TeamInvite
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ? AND status = ?", inviteID, userID, models.InvitePending).
+			Limit(1).Find(&invite)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.ErrNotFound
+		}
+
+		now := time.Now()
+		invite.Status = models.InviteDeclined
+		if accept {
+			invite.Status = models.InviteAccepted
+			member := models.TeamMember{TeamID: invite.TeamID, UserID: userID, Role: models.TeamMemberRole, JoinedAt: now}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		invite.RespondedAt = &now
+		return tx.Save(&invite).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// RemoveTeamMember removes a member from a team. The owner cannot be removed.
+func RemoveTeamMember(db *gorm.DB, teamID, userID uint) error {
+	role, err := TeamRoleOf(db, teamID, userID)
+	if err != nil {
+		return err
+	}
+	switch role {
+	case "":
+		return utils.ErrNotFound
+	case models.TeamOwner:
+		return ErrTeamOwnerCannotLeave
+	}
+	return db.Where("team_id = ? AND user_id = ?", teamID, userID).Delete(&models.TeamMember{}).Error
+}
+
+// SetTeamRole changes a member's role. Making someone owner hands ownership over to them.
+func SetTeamRole(db *gorm.DB, teamID, ownerID, userID uint, role models.TeamRole) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		current, err := TeamRoleOf(tx, teamID, userID)
+		if err != nil {
+			return err
+		}
+		if current == "" {
+			return utils.ErrNotFound
+		}
+		if current == models.TeamOwner {
+			return ErrTeamOwnerCannotLeave
+		}
+
+		if role == models.TeamOwner {
+			err := tx.Model(&models.TeamMember{}).
+				Where("team_id = ? AND user_id = ?", teamID, ownerID).
+				Update("role", models.TeamAdmin).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND user_id = ?", teamID, userID).
+			Update("role", role).Error
+	})
+}
+
+// teamScores selects the score records that count for teams: each member's forecasts
+// resolved while they were on the team
+func teamScores(db *gorm.DB) *gorm.DB {
+	return db.Table("score_records").
+		Joins("JOIN team_members ON team_members.user_id = score_records.user_id AND score_records.resolved_at >= team_members.joined_at")
+}
+
+// GetTeamLeaderboard returns a page of teams ranked by their members' scores and the number of ranked teams.
+// Tied teams share a rank, as on the user leaderboard.
+func GetTeamLeaderboard(db *gorm.DB, ranking models.TeamRanking, page, limit int) ([]models.TeamEntry, int64, error) {
+	totals := teamScores(db).
+		Select("team_members.team_id, " +
+			"ROUND(CAST(SUM(score_records.score) AS NUMERIC), 6) AS score, " +
+			"ROUND(CAST(SUM(score_records.score) / COUNT(*) AS NUMERIC), 6) AS average_score, " +
+			"COUNT(*) AS resolved, SUM(CASE WHEN score_records.correct THEN 1 ELSE 0 END) AS correct").
+		Group("team_members.team_id")
+
+	order := "score DESC"
+	if ranking == models.TeamRankAverage {
+		order = "average_score DESC"
+	}
+	ranked := db.Table("(?) AS totals", totals).
+		Select("team_id, score, average_score, resolved, correct, " +
+			"RANK() OVER (ORDER BY " + order + ") AS rank, " +
+			"ROW_NUMBER() OVER (ORDER BY " + order + ", team_id ASC) AS position")
+
+	var total int64
+	if err := db.Table("(?) AS ranked", ranked).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	members := db.Model(&models.TeamMember{}).Select("team_id, COUNT(*) AS members").Group("team_id")
+
+	offset := (page - 1) * limit
+	entries := []models.TeamEntry{}
+	err := db.Table("(?) AS ranked", ranked).
+		Select("ranked.rank, ranked.team_id, teams.name, member_counts.members, ranked.score, ranked.average_score, ranked.resolved, ranked.correct").
+		Joins("JOIN teams ON teams.id = ranked.team_id").
+		Joins("JOIN (?) AS member_counts ON member_counts.team_id = ranked.team_id", members).
+		Where("ranked.position > ? AND ranked.position <= ?", offset, offset+limit).
+		Order("ranked.position").
+		Scan(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// GetTeamStats returns a team's score totals and each member's contribution
+func GetTeamStats(db *gorm.DB, teamID uint) (models.TeamStats, error) {
+	stats := models.TeamStats{Contributions: []models.TeamContribution{}}
+
+	var members int64
+	if err := db.Model(&models.TeamMember{}).Where("team_id = ?", teamID).Count(&members).Error; err != nil {
+		return stats, err
+	}
+	stats.Members = int(members)
+
+	err := teamScores(db).
+		Select("score_records.user_id, users.username, SUM(score_records.score) AS score, "+
+			"COUNT(*) AS resolved, SUM(CASE WHEN score_records.correct THEN 1 ELSE 0 END) AS correct").
+		Joins("JOIN users ON users.id = score_records.user_id").
+		Where("team_members.team_id = ?", teamID).
+		Group("score_records.user_id, users.username").
+		Order("score DESC").
+		Scan(&stats.Contributions).Error
+	if err != nil {
+		return stats, err
+	}
+
+	for _, contribution := range stats.Contributions {
+		stats.Score += contribution.Score
+		stats.Resolved += contribution.Resolved
+		stats.Correct += contribution.Correct
+	}
+	if stats.Resolved > 0 {
+		stats.Accuracy = float64(stats.Correct) / float64(stats.Resolved) * 100
+	}
+	return stats, nil
+}
+
+// GetTeamForecast averages the team members' active forecasts on a market
+func GetTeamForecast(db *gorm.DB, teamID, marketID uint) (models.TeamForecast, error) {
+	forecast := models.TeamForecast{MarketID: marketID}
+
+	// Confidence is stated for the chosen side, so flip it for "false" forecasts
+	err := db.Model(&models.Forecast{}).
+		Select("COUNT(*) AS count, COALESCE(AVG(CASE WHEN prediction THEN confidence ELSE 100 - confidence END), 0) / 100 AS probability").
+		Joins("JOIN team_members ON team_members.user_id = forecasts.user_id").
+		Where("team_members.team_id = ? AND forecasts.market_id = ? AND forecasts.withdrawn_at IS NULL", teamID, marketID).
+		Scan(&forecast).Error
+	return forecast, err
+}
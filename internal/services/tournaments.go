@@ -0,0 +1,308 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tournament errors
+var (
+	ErrUnknownTournamentMarkets = errors.New("market_ids: some markets do not exist")
+	ErrTournamentFinished       = errors.New("tournament has already finished")
+	ErrSignupNotRequired        = errors.New("tournament is open to everyone and does not require sign-up")
+)
+
+// minAwardedRanks is how many places receive a badge when a tournament offers fewer prizes
+const minAwardedRanks = 3
+
+// TournamentForecast is a resolved forecast on one of a tournament's markets
+type TournamentForecast struct {
+	UserID     uint
+	Prediction bool
+	Confidence float64
+	Outcome    bool
+}
+
+// TournamentScore scores a resolved forecast under the tournament's scoring rule
+func TournamentScore(rule models.TournamentScoringRule, forecast *models.Forecast, outcome bool) float64 {
+	switch rule {
+	case models.ScoringBrier:
+		target := 0.0
+		if outcome {
+			target = 1
+		}
+		p := forecast.Probability()
+		return 1 - (p-target)*(p-target)
+	case models.ScoringAccuracy:
+		if forecast.Prediction == outcome {
+			return 1
+		}
+		return 0
+	}
+	return ForecastScore(forecast, outcome)
+}
+
+// RankTournament totals each user's forecasts under the scoring rule and ranks them like the
+// global leaderboard: tied scores share a rank, and ties are listed by user ID
+func RankTournament(rule models.TournamentScoringRule, forecasts []TournamentForecast) []models.LeaderboardEntry {
+	byUser := make(map[uint]*models.LeaderboardEntry)
+	for _, f := range forecasts {
+		entry, ok := byUser[f.UserID]
+		if !ok {
+			entry = &models.LeaderboardEntry{UserID: f.UserID}
+			byUser[f.UserID] = entry
+		}
+
+		forecast := models.Forecast{Prediction: f.Prediction, Confidence: f.Confidence}
+		entry.Score += TournamentScore(rule, &forecast, f.Outcome)
+		entry.Resolved++
+		if f.Prediction == f.Outcome {
+			entry.Correct++
+		}
+	}
+
+	entries := make([]models.LeaderboardEntry, 0, len(byUser))
+	for _, entry := range byUser {
+		// Rounding stops floating point noise in the sums from splitting ties
+		entry.Score = math.Round(entry.Score*1e6) / 1e6
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+
+	for i := range entries {
+		if i > 0 && entries[i].Score == entries[i-1].Score {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries
+}
+
+// TournamentStandings returns the full ranking of a tournament from its resolved markets.
+// Only forecasts made or updated while the tournament ran count, each at the last value it was
+// given before the tournament ended; changes after the end don't move the standings. Forecasts
+// withdrawn since are not scored, as when the market resolves.
+// Tournaments requiring sign-up only rank users who joined.
+func TournamentStandings(db *gorm.DB, tournament *models.Tournament) ([]models.LeaderboardEntry, error) {
+	inWindow := db.Model(&models.ForecastRevision{}).
+		Select("forecast_id, market_id, user_id, prediction, confidence, withdrawn, "+
+			"ROW_NUMBER() OVER (PARTITION BY forecast_id ORDER BY created_at DESC, id DESC) AS position").
+		Where("created_at >= ? AND created_at <= ?", tournament.StartDate, tournament.EndDate)
+
+	query := db.Table("(?) AS revisions", inWindow).
+		Select("revisions.user_id, revisions.prediction, revisions.confidence, markets.outcome").
+		Joins("JOIN forecasts ON forecasts.id = revisions.forecast_id").
+		Joins("JOIN tournament_markets ON tournament_markets.market_id = revisions.market_id").
+		Joins("JOIN markets ON markets.id = revisions.market_id").
+		Where("revisions.position = 1 AND NOT revisions.withdrawn").
+		Where("tournament_markets.tournament_id = ?", tournament.ID).
+		Where("forecasts.withdrawn_at IS NULL AND markets.status = ? AND markets.outcome IS NOT NULL", models.MarketResolved)
+	if tournament.RequiresSignup {
+		query = query.Joins("JOIN tournament_entrants ON tournament_entrants.tournament_id = tournament_markets.tournament_id AND tournament_entrants.user_id = revisions.user_id")
+	}
+
+	var forecasts []TournamentForecast
+	if err := query.Scan(&forecasts).Error; err != nil {
+		return nil, err
+	}
+
+	entries := RankTournament(tournament.ScoringRule, forecasts)
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	userIDs := make([]uint, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+	}
+	var users []models.User
+	if err := db.Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+	for i := range entries {
+		entries[i].Username = usernames[entries[i].UserID]
+	}
+
+	return entries, nil
+}
+
+// CreateTournament creates a tournament over existing markets, with its prizes
+func CreateTournament(db *gorm.DB, input *models.TournamentRequest, creatorID uint) (*models.Tournament, error) {
+	marketIDs := input.UniqueMarketIDs()
+	var markets []models.Market
+	if err := db.Where("id IN ?", marketIDs).Find(&markets).Error; err != nil {
+		return nil, err
+	}
+	if len(markets) != len(marketIDs) {
+		return nil, ErrUnknownTournamentMarkets
+	}
+
+	rule := input.ScoringRule
+	if rule == "" {
+		rule = models.ScoringConfidence
+	}
+
+	now := time.Now()
+	tournament := models.Tournament{
+		Name:           input.Name,
+		Description:    input.Description,
+		CreatorID:      creatorID,
+		StartDate:      input.StartDate,
+		EndDate:        input.EndDate,
+		RequiresSignup: input.RequiresSignup,
+		ScoringRule:    rule,
+		Markets:        markets,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	for i, prize := range input.Prizes {
+		tournament.Prizes = append(tournament.Prizes, models.TournamentPrize{Rank: i + 1, Description: prize})
+	}
+
+	// The markets already exist; only link them
+	if err := db.Omit("Markets.*").Create(&tournament).Error; err != nil {
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// JoinTournament signs a user up for a tournament. Joining twice is not an error.
+func JoinTournament(db *gorm.DB, tournament *models.Tournament, userID uint, now time.Time) error {
+	if !tournament.RequiresSignup {
+		return ErrSignupNotRequired
+	}
+	if tournament.Status(now) == models.TournamentFinished {
+		return ErrTournamentFinished
+	}
+
+	entrant := models.TournamentEntrant{TournamentID: tournament.ID, UserID: userID, JoinedAt: now}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entrant).Error
+}
+
+// TournamentBadge names the badge awarded for finishing at rank
+func TournamentBadge(rank int) string {
+	switch rank {
+	case 1:
+		return "gold"
+	case 2:
+		return "silver"
+	case 3:
+		return "bronze"
+	}
+	return "finalist"
+}
+
+// AwardFinishedTournaments hands out badges and prizes for tournaments that have ended and
+// whose markets have all resolved, and returns how many tournaments were awarded.
+// Each tournament is claimed before awarding, so several instances never award it twice.
+func AwardFinishedTournaments(db *gorm.DB, now time.Time) (int, error) {
+	unresolved := db.Table("tournament_markets").
+		Select("1").
+		Joins("JOIN markets ON markets.id = tournament_markets.market_id").
+		Where("tournament_markets.tournament_id = tournaments.id AND markets.status <> ?", models.MarketResolved)
+
+	var tournaments []models.Tournament
+	err := db.Preload("Prizes").
+		Where("awarded_at IS NULL AND end_date <= ?", now).
+		Where("NOT EXISTS (?)", unresolved).
+		Find(&tournaments).Error
+	if err != nil {
+		return 0, err
+	}
+
+	awarded := 0
+	for i := range tournaments {
+		ok, err := awardTournament(db, &tournaments[i], now)
+		if err != nil {
+			return awarded, fmt.Errorf("awarding tournament %d: %w", tournaments[i].ID, err)
+		}
+		if ok {
+			awarded++
+		}
+	}
+	return awarded, nil
+}
+
+// awardTournament claims a tournament and creates its awards and notifications in one transaction.
+// It reports false when another instance claimed the tournament first.
+func awardTournament(db *gorm.DB, tournament *models.Tournament, now time.Time) (bool, error) {
+	claimed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Tournament{}).
+			Where("id = ? AND awarded_at IS NULL", tournament.ID).
+			Updates(map[string]interface{}{"awarded_at": now, "updated_at": now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		claimed = true
+
+		standings, err := TournamentStandings(tx, tournament)
+		if err != nil {
+			return err
+		}
+
+		prizes := make(map[int]string, len(tournament.Prizes))
+		for _, prize := range tournament.Prizes {
+			prizes[prize.Rank] = prize.Description
+		}
+		places := len(tournament.Prizes)
+		if places < minAwardedRanks {
+			places = minAwardedRanks
+		}
+
+		for _, entry := range standings {
+			if entry.Rank > places {
+				break
+			}
+
+			award := models.TournamentAward{
+				TournamentID: tournament.ID,
+				UserID:       entry.UserID,
+				Rank:         entry.Rank,
+				Score:        entry.Score,
+				Badge:        TournamentBadge(entry.Rank),
+				Prize:        prizes[entry.Rank],
+				CreatedAt:    now,
+			}
+			if err := tx.Create(&award).Error; err != nil {
+				return err
+			}
+
+			message := fmt.Sprintf("You finished #%d in the tournament \"%s\" and earned a %s badge", entry.Rank, tournament.Name, award.Badge)
+			if award.Prize != "" {
+				message += fmt.Sprintf(" and %s", award.Prize)
+			}
+			notification := models.Notification{
+				UserID:  int(entry.UserID),
+				Type:    models.NotificationTournamentAward,
+				Message: message,
+				Link:    fmt.Sprintf("/tournaments/%d", tournament.ID),
+			}
+			if err := CreateNotification(tx, &notification); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Activity weights for the trending ranking
+const (
+	TrendingWeightCreated  = 2.0
+	TrendingWeightForecast = 1.0
+	TrendingWeightComment  = 0.75
+	TrendingWeightVote     = 0.5
+)
+
+// TrendingHalfLife is how long it takes an activity's contribution to the hot score to halve
+var TrendingHalfLife = 24 * time.Hour
+
+// trendingEpoch anchors hot scores; only differences between scores matter
+var trendingEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// HotScore returns the hot score of a single activity of the given weight at time at.
+//
+// A hot score is log(sum(weight * 2^((t - epoch) / halfLife))) over an item's activities.
+// Dividing every item's sum by the same 2^(now / halfLife) gives its exponentially decayed
+// activity, so ordering by hot score ranks items by recent activity without ever rewriting
+// scores as time passes; each new activity only has to be added to its own item's score.
+func HotScore(weight float64, at time.Time) float64 {
+	return math.Log(weight) + at.Sub(trendingEpoch).Hours()/TrendingHalfLife.Hours()*math.Ln2
+}
+
+// AddHotScore adds an activity of the given weight at time at to an existing hot score
+func AddHotScore(score, weight float64, at time.Time) float64 {
+	return logAddExp(score, HotScore(weight, at))
+}
+
+// logAddExp computes log(exp(a) + exp(b)) without overflow
+func logAddExp(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
+
+// BumpMarketTrending adds an activity to a market's hot score
+func BumpMarketTrending(db *gorm.DB, marketID uint, weight float64) {
+	if err := bumpHotScore(db, &models.Market{}, marketID, weight); err != nil {
+		log.Printf("Failed to update trending score for market %d: %v", marketID, err)
+	}
+}
+
+// BumpPredictionTrending adds an activity to a prediction's hot score
+func BumpPredictionTrending(db *gorm.DB, predictionID int, weight float64) {
+	if err := bumpHotScore(db, &models.Prediction{}, predictionID, weight); err != nil {
+		log.Printf("Failed to update trending score for prediction %d: %v", predictionID, err)
+	}
+}
+
+// bumpHotScore locks the row so concurrent activities are all counted
+func bumpHotScore(db *gorm.DB, model interface{}, id interface{}, weight float64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var row struct {
+			HotScore float64
+		}
+		err := tx.Model(model).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("hot_score").
+			Where("id = ?", id).
+			Take(&row).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(model).
+			Where("id = ?", id).
+			UpdateColumn("hot_score", AddHotScore(row.HotScore, weight, time.Now())).Error
+	})
+}
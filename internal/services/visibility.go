@@ -0,0 +1,54 @@
+package services
+
+import (
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HideForecastsByDefault is the site-wide anti-herding setting for markets without their own
+var HideForecastsByDefault = utils.GetEnvBool("HIDE_FORECASTS_BY_DEFAULT", false)
+
+// ForecastsVisible reports whether a user may see other users' forecasts and the crowd aggregate.
+// Hidden forecasts are revealed while the user has an active forecast, or once the market has closed.
+func ForecastsVisible(db *gorm.DB, market *models.Market, userID uint) (bool, error) {
+	if !market.HidesForecasts(HideForecastsByDefault) || market.IsClosed(time.Now()) {
+		return true, nil
+	}
+
+	var count int64
+	err := db.Model(&models.Forecast{}).
+		Where("market_id = ? AND user_id = ? AND withdrawn_at IS NULL", market.ID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// forecastsVisibleSQL is a condition on a query joined with markets that holds where the market's
+// forecasts are visible to viewerID, matching ForecastsVisible
+func forecastsVisibleSQL(viewerID uint, now time.Time) clause.Expr {
+	return gorm.Expr("NOT COALESCE(markets.hide_forecasts, ?) "+
+		"OR markets.status <> ? "+
+		"OR (markets.close_date > ? AND markets.close_date < ?) "+
+		"OR EXISTS (SELECT 1 FROM forecasts AS own WHERE own.market_id = markets.id AND own.user_id = ? AND own.withdrawn_at IS NULL)",
+		HideForecastsByDefault, models.MarketOpen, time.Time{}, now, viewerID)
+}
+
+// RecentForecasts returns up to limit of a user's latest active forecasts that viewerID may see.
+// Forecasts on markets that still hide them from the viewer are skipped, as are withdrawn ones.
+func RecentForecasts(db *gorm.DB, userID, viewerID uint, limit int) ([]models.Forecast, error) {
+	query := db.Joins("JOIN markets ON markets.id = forecasts.market_id").
+		Where("forecasts.user_id = ? AND forecasts.withdrawn_at IS NULL", userID)
+	if userID != viewerID {
+		query = query.Where(forecastsVisibleSQL(viewerID, time.Now()))
+	}
+
+	recent := []models.Forecast{}
+	err := query.Preload("Market").
+		Order("forecasts.created_at desc, forecasts.id desc").
+		Limit(limit).
+		Find(&recent).Error
+	return recent, err
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VotePrediction records a user's agree/disagree vote on a prediction, replacing any earlier vote.
+// The prediction row is locked so vote counters stay consistent under concurrent votes.
+// Only a user's first vote on a prediction notifies its author and counts towards trending,
+// so changing sides or removing and re-adding a vote cannot be used to spam or game either.
+func VotePrediction(db *gorm.DB, vote *models.Vote) (*models.Prediction, error) {
+	if err := vote.Validate(); err != nil {
+		return nil, err
+	}
+
+	var prediction models.Prediction
+	var first bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPrediction(tx, vote.PredictionID, &prediction); err != nil {
+			return err
+		}
+
+		var existing models.Vote
+		result := tx.Where("user_id = ? AND prediction_id = ?", vote.UserID, vote.PredictionID).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		now := time.Now()
+		switch {
+		case result.RowsAffected == 0:
+			vote.CreatedAt = now
+			vote.UpdatedAt = now
+			if err := tx.Create(vote).Error; err != nil {
+				return err
+			}
+			first = true
+			return adjustVoteCounts(tx, &prediction, vote.Value, 1)
+
+		case existing.RemovedAt != nil:
+			// Voting again after removing a vote restores it
+			existing.Value = vote.Value
+			existing.RemovedAt = nil
+			existing.UpdatedAt = now
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			*vote = existing
+			return adjustVoteCounts(tx, &prediction, vote.Value, 1)
+
+		case existing.Value != vote.Value:
+			// Changing sides moves the vote from one counter to the other
+			existing.Value = vote.Value
+			existing.UpdatedAt = now
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			*vote = existing
+			if err := adjustVoteCounts(tx, &prediction, !vote.Value, -1); err != nil {
+				return err
+			}
+			return adjustVoteCounts(tx, &prediction, vote.Value, 1)
+
+		default:
+			*vote = existing
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if first {
+		BumpPredictionTrending(db, prediction.ID, TrendingWeightVote)
+		if prediction.UserID != vote.UserID {
+			notifyVote(db, vote, &prediction)
+		}
+	}
+
+	value := vote.Value
+	prediction.UserVote = &value
+	return &prediction, nil
+}
+
+// RemoveVote withdraws a user's vote on a prediction
+func RemoveVote(db *gorm.DB, userID, predictionID int) (*models.Prediction, error) {
+	var prediction models.Prediction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPrediction(tx, predictionID, &prediction); err != nil {
+			return err
+		}
+
+		var existing models.Vote
+		result := tx.Where("user_id = ? AND prediction_id = ? AND removed_at IS NULL", userID, predictionID).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.ErrNotFound
+		}
+
+		now := time.Now()
+		existing.RemovedAt = &now
+		existing.UpdatedAt = now
+		if err := tx.Save(&existing).Error; err != nil {
+			return err
+		}
+		return adjustVoteCounts(tx, &prediction, existing.Value, -1)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &prediction, nil
+}
+
+// ApplyUserVotes fills in UserVote on each prediction with the given user's vote, if any
+func ApplyUserVotes(db *gorm.DB, userID int, predictions []models.Prediction) error {
+	if len(predictions) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(predictions))
+	for i, prediction := range predictions {
+		ids[i] = prediction.ID
+	}
+
+	var votes []models.Vote
+	if err := db.Where("user_id = ? AND prediction_id IN ? AND removed_at IS NULL", userID, ids).Find(&votes).Error; err != nil {
+		return err
+	}
+
+	values := make(map[int]bool, len(votes))
+	for _, vote := range votes {
+		values[vote.PredictionID] = vote.Value
+	}
+	for i := range predictions {
+		if value, ok := values[predictions[i].ID]; ok {
+			predictions[i].UserVote = &value
+		}
+	}
+
+	return nil
+}
+
+// lockPrediction loads a prediction for update within a transaction
+func lockPrediction(tx *gorm.DB, predictionID int, prediction *models.Prediction) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(prediction, predictionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrNotFound
+	}
+	return err
+}
+
+// adjustVoteCounts atomically changes the agree or disagree counter by delta
+func adjustVoteCounts(tx *gorm.DB, prediction *models.Prediction, agree bool, delta int) error {
+	column := "disagree_count"
+	if agree {
+		column = "agree_count"
+	}
+
+	err := tx.Model(&models.Prediction{}).
+		Where("id = ?", prediction.ID).
+		UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
+	if err != nil {
+		return err
+	}
+
+	if agree {
+		prediction.AgreeCount += delta
+	} else {
+		prediction.DisagreeCount += delta
+	}
+	return nil
+}
+
+// notifyVote tells a prediction's author about a new vote
+func notifyVote(db *gorm.DB, vote *models.Vote, prediction *models.Prediction) {
+	var voter models.User
+	if err := db.First(&voter, vote.UserID).Error; err != nil {
+		log.Printf("Failed to load voter %d: %v", vote.UserID, err)
+		return
+	}
+
+	notification := VoteNotification(&voter, vote, prediction)
+	if err := CreateNotification(db, &notification); err != nil {
+		log.Printf("Failed to notify vote on prediction %d: %v", prediction.ID, err)
+	}
+}
+
+// VoteNotification tells a prediction's author that voter voted on it
+func VoteNotification(voter *models.User, vote *models.Vote, prediction *models.Prediction) models.Notification {
+	verb := "disagreed with"
+	if vote.Value {
+		verb = "agreed with"
+	}
+	return models.Notification{
+		UserID:  prediction.UserID,
+		Type:    models.NotificationVote,
+		Message: fmt.Sprintf("%s %s your prediction \"%s\"", voter.Username, verb, prediction.Title),
+		Link:    fmt.Sprintf("/predictions/%d", prediction.ID),
+	}
+}
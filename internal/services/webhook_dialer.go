@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WebhookAllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses.
+// It is off in production so users can't point deliveries at internal services; tests and
+// local development turn it on to deliver to receivers on the same machine.
+var WebhookAllowPrivateNetworks = false
+
+// ErrWebhookAddressNotAllowed is returned when a webhook URL points at a non-public address
+var ErrWebhookAddressNotAllowed = errors.New("webhook address is not a public internet address")
+
+// carrierNAT is the shared address space ISPs use between their customers (RFC 6598)
+var carrierNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublicAddress reports whether ip is routable on the public internet
+func IsPublicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		carrierNAT.Contains(ip) ||
+		ip.To4() != nil && ip.To4()[0] == 0)
+}
+
+// CheckWebhookURL rejects webhook URLs whose host is obviously not public, so users find out
+// when they register the webhook. Hostnames are checked again when a delivery connects.
+func CheckWebhookURL(rawURL string) error {
+	if WebhookAllowPrivateNetworks {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookAddressNotAllowed
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicAddress(ip) {
+		return ErrWebhookAddressNotAllowed
+	}
+	return nil
+}
+
+// NewWebhookClient returns an HTTP client for deliveries. Its dialer checks the address it
+// actually connects to, after DNS resolution and on every redirect, so a hostname that
+// resolves to an internal address is refused even if it resolved elsewhere when it was checked.
+// Deliveries never go through a proxy, since the dialer would then only see the proxy.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: controlWebhookDial,
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// controlWebhookDial runs just before a delivery connects, with the resolved address
+func controlWebhookDial(network, address string, _ syscall.RawConn) error {
+	if WebhookAllowPrivateNetworks {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, host)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Webhook delivery settings
+var (
+	// WebhookMaxAttempts is how many times a delivery is tried before it is marked failed
+	WebhookMaxAttempts = 10
+	// WebhookRetryBase is the wait before the first retry; each later retry waits twice as long
+	WebhookRetryBase = 30 * time.Second
+	// WebhookMaxBackoff caps the wait between retries
+	WebhookMaxBackoff = 6 * time.Hour
+	// WebhookClaimLease is how long a worker owns the deliveries it picked up
+	WebhookClaimLease = time.Minute
+	// WebhookClient sends deliveries, refusing internal addresses unless WebhookAllowPrivateNetworks is set
+	WebhookClient = NewWebhookClient(10 * time.Second)
+)
+
+// webhookBatchSize is how many due deliveries a worker claims at once
+const webhookBatchSize = 20
+
+// webhookWake prompts the worker to send newly queued deliveries without waiting for its next tick
+var webhookWake = make(chan struct{}, 1)
+
+// webhookPayload is the JSON body POSTed to webhooks
+type webhookPayload struct {
+	Event     models.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      interface{}         `json:"data"`
+}
+
+// EncodeWebhookPayload returns the JSON body delivering an event that happened at createdAt
+func EncodeWebhookPayload(event models.WebhookEvent, data interface{}, createdAt time.Time) ([]byte, error) {
+	return json.Marshal(webhookPayload{Event: event, CreatedAt: createdAt, Data: data})
+}
+
+// NewWebhookSecret returns a random secret for signing a webhook's deliveries
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// SignWebhookPayload returns the HMAC-SHA256 signature of a delivery body sent at timestamp.
+// Receivers recompute it from the X-Rethink-Timestamp header and the raw body to verify a delivery.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookBackoff returns how long to wait after the given number of failed attempts
+func WebhookBackoff(attempts int) time.Duration {
+	backoff := WebhookRetryBase
+	for i := 1; i < attempts && backoff < WebhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > WebhookMaxBackoff {
+		backoff = WebhookMaxBackoff
+	}
+	return backoff
+}
+
+// DispatchWebhookEvent queues a delivery of the event to every active webhook subscribed to it:
+// global webhooks and the webhooks of ownerID, the user the event is about.
+// Webhooks are a side channel, so failures are logged rather than returned.
+func DispatchWebhookEvent(db *gorm.DB, event models.WebhookEvent, ownerID uint, data interface{}) {
+	var webhooks []models.Webhook
+	if err := db.Where("active = ? AND (global = ? OR user_id = ?)", true, true, ownerID).Find(&webhooks).Error; err != nil {
+		log.Printf("Failed to load webhooks for %s: %v", event, err)
+		return
+	}
+
+	now := time.Now()
+	payload, err := EncodeWebhookPayload(event, data, now)
+	if err != nil {
+		log.Printf("Failed to encode %s webhook payload: %v", event, err)
+		return
+	}
+
+	queued := false
+	for _, webhook := range webhooks {
+		if !webhook.Events.Contains(event) {
+			continue
+		}
+
+		delivery := models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			log.Printf("Failed to queue %s delivery for webhook %d: %v", event, webhook.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		wakeWebhookWorker()
+	}
+}
+
+// ReplayWebhookDelivery queues a delivery to be sent again from its first attempt
+func ReplayWebhookDelivery(db *gorm.DB, delivery *models.WebhookDelivery) error {
+	now := time.Now()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.Error = ""
+	delivery.NextAttemptAt = &now
+	delivery.UpdatedAt = now
+
+	if err := db.Save(delivery).Error; err != nil {
+		return err
+	}
+	wakeWebhookWorker()
+	return nil
+}
+
+// SendWebhook POSTs a signed delivery and returns the response status code.
+// Any status outside 2xx is returned as an error along with the code.
+func SendWebhook(ctx context.Context, client *http.Client, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "reThink-Webhooks/1.0")
+	req.Header.Set("X-Rethink-Event", string(delivery.Event))
+	req.Header.Set("X-Rethink-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Rethink-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Rethink-Signature", SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// ProcessWebhookDeliveries sends every delivery that is due and returns how many were attempted.
+// Deliveries are claimed with SKIP LOCKED so several instances can run workers side by side.
+func ProcessWebhookDeliveries(ctx context.Context, db *gorm.DB, client *http.Client) (int, error) {
+	now := time.Now()
+
+	var deliveries []models.WebhookDelivery
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Order("next_attempt_at asc").
+			Limit(webhookBatchSize).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		// Push the claimed deliveries out of other workers' view until this one is done with them
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			UpdateColumn("next_attempt_at", now.Add(WebhookClaimLease)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		if err := attemptDelivery(ctx, db, client, &deliveries[i]); err != nil {
+			log.Printf("Failed to record webhook delivery %d: %v", deliveries[i].ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// attemptDelivery sends one delivery, logs the attempt and schedules a retry if it failed
+func attemptDelivery(ctx context.Context, db *gorm.DB, client *http.Client, delivery *models.WebhookDelivery) error {
+	var webhook models.Webhook
+	result := db.Where("id = ? AND active = ?", delivery.WebhookID, true).Limit(1).Find(&webhook)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	start := time.Now()
+	var code int
+	var sendErr error
+	if result.RowsAffected == 0 {
+		sendErr = fmt.Errorf("webhook %d was deleted or deactivated", delivery.WebhookID)
+	} else {
+		code, sendErr = SendWebhook(ctx, client, &webhook, delivery)
+	}
+	now := time.Now()
+
+	attempt := models.WebhookAttempt{
+		DeliveryID:   delivery.ID,
+		ResponseCode: code,
+		DurationMS:   now.Sub(start).Milliseconds(),
+		CreatedAt:    now,
+	}
+
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	delivery.UpdatedAt = now
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case result.RowsAffected == 0 || delivery.Attempts >= WebhookMaxAttempts:
+		attempt.Error = sendErr.Error()
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = sendErr.Error()
+		delivery.NextAttemptAt = nil
+	default:
+		attempt.Error = sendErr.Error()
+		delivery.Error = sendErr.Error()
+		next := now.Add(WebhookBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Save(delivery).Error
+	})
+}
+
+// RunWebhookWorker sends due deliveries at every interval, and straight away when new
+// deliveries are queued, until ctx is cancelled
+func RunWebhookWorker(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-webhookWake:
+		}
+
+		// Keep going while full batches come back so a backlog drains quickly
+		for {
+			n, err := ProcessWebhookDeliveries(ctx, db, WebhookClient)
+			if err != nil {
+				log.Printf("Failed to process webhook deliveries: %v", err)
+				break
+			}
+			if n < webhookBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// wakeWebhookWorker nudges the worker without blocking; one pending wake-up is enough
+func wakeWebhookWorker() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// DeleteWebhook deletes a webhook together with its delivery log
+func DeleteWebhook(db *gorm.DB, webhookID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&models.WebhookDelivery{}).Select("id").Where("webhook_id = ?", webhookID)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&models.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("webhook_id = ?", webhookID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Webhook{}, webhookID).Error
+	})
+}
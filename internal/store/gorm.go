@@ -0,0 +1,668 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore stores everything in the database behind a GORM connection
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore creates a store backed by db
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// CreateUser stores a new user, checking the email and username are free in the same transaction
+func (s *GormStore) CreateUser(user *models.User) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return utils.ErrDuplicate
+		}
+
+		if err := tx.Model(&models.User{}).Where("username = ?", user.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUsernameTaken
+		}
+
+		return tx.Create(user).Error
+	})
+}
+
+// GetUserByEmail returns the user with the email
+func (s *GormStore) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+// GetUser returns the user with the ID
+func (s *GormStore) GetUser(id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+// UpdateUser saves a user's profile, checking the username is free in the same transaction
+func (s *GormStore) UpdateUser(user *models.User) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ? AND id <> ?", user.Username, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUsernameTaken
+		}
+
+		return tx.Save(user).Error
+	})
+}
+
+// FollowUser makes follower follow followee
+func (s *GormStore) FollowUser(followerID, followeeID uint) error {
+	return services.FollowUser(s.db, followerID, followeeID)
+}
+
+// UnfollowUser removes a follow
+func (s *GormStore) UnfollowUser(followerID, followeeID uint) error {
+	return services.UnfollowUser(s.db, followerID, followeeID)
+}
+
+// GetFollowCounts returns the follower and following counts of a user
+func (s *GormStore) GetFollowCounts(userID uint) (models.FollowCounts, error) {
+	return services.GetFollowCounts(s.db, userID)
+}
+
+// IsFollowing reports whether follower follows followee
+func (s *GormStore) IsFollowing(followerID, followeeID uint) (bool, error) {
+	return services.IsFollowing(s.db, followerID, followeeID)
+}
+
+// ListFollowers returns a page of a user's followers
+func (s *GormStore) ListFollowers(userID uint, page, limit int) ([]models.User, int64, error) {
+	return services.ListFollowers(s.db, userID, page, limit)
+}
+
+// ListFollowing returns a page of the users a user follows
+func (s *GormStore) ListFollowing(userID uint, page, limit int) ([]models.User, int64, error) {
+	return services.ListFollowing(s.db, userID, page, limit)
+}
+
+// RecordActivity stores an activity for followers' feeds
+func (s *GormStore) RecordActivity(activity models.Activity) {
+	services.RecordActivity(s.db, activity)
+}
+
+// GetFeed returns a page of the viewer's feed
+func (s *GormStore) GetFeed(viewerID uint, cursor string, limit int) ([]models.Activity, string, error) {
+	return services.GetFeed(s.db, viewerID, cursor, limit)
+}
+
+// CreatePrediction stores a new prediction
+func (s *GormStore) CreatePrediction(prediction *models.Prediction) error {
+	return s.db.Create(prediction).Error
+}
+
+// GetPredictions returns a page of all predictions, newest first
+func (s *GormStore) GetPredictions(userID int, page int, limit int) ([]models.Prediction, error) {
+	return services.ListPredictions(s.db, models.PredictionFilter{}, userID, page, limit)
+}
+
+// FindPredictions returns a page of the predictions matching filter
+func (s *GormStore) FindPredictions(filter models.PredictionFilter, viewerID, page, limit int) ([]models.Prediction, error) {
+	return services.ListPredictions(s.db, filter, viewerID, page, limit)
+}
+
+// CountPredictions returns how many predictions match filter
+func (s *GormStore) CountPredictions(filter models.PredictionFilter) (int64, error) {
+	return services.CountPredictions(s.db, filter)
+}
+
+// GetPrediction returns a prediction with its result and the viewer's vote
+func (s *GormStore) GetPrediction(id, viewerID int) (*models.Prediction, error) {
+	return services.GetPrediction(s.db, id, viewerID)
+}
+
+// UpdatePrediction saves changes to a prediction
+func (s *GormStore) UpdatePrediction(prediction *models.Prediction) error {
+	return s.db.Save(prediction).Error
+}
+
+// DeletePrediction deletes a prediction with its votes and result
+func (s *GormStore) DeletePrediction(id int) error {
+	return services.DeletePrediction(s.db, id)
+}
+
+// ResolvePrediction stores a prediction's result and notifies its voters
+func (s *GormStore) ResolvePrediction(prediction *models.Prediction, result *models.Result) error {
+	return services.ResolvePrediction(s.db, prediction, result)
+}
+
+// VotePrediction records a vote; the prediction's author is notified and its trending score bumped
+func (s *GormStore) VotePrediction(vote *models.Vote) error {
+	_, err := services.VotePrediction(s.db, vote)
+	return err
+}
+
+// RemoveVote withdraws a user's vote on a prediction
+func (s *GormStore) RemoveVote(userID, predictionID int) (*models.Prediction, error) {
+	return services.RemoveVote(s.db, userID, predictionID)
+}
+
+// CreateMarket stores a new market
+func (s *GormStore) CreateMarket(market *models.Market) error {
+	return s.db.Create(market).Error
+}
+
+// GetMarket returns the market with its creator
+func (s *GormStore) GetMarket(id uint) (*models.Market, error) {
+	var market models.Market
+	if err := s.db.Preload("Creator").First(&market, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &market, nil
+}
+
+// ListMarkets returns a page of the markets matching filter and their number
+func (s *GormStore) ListMarkets(filter MarketFilter, page, limit int) ([]models.Market, int64, error) {
+	query := s.db.Model(&models.Market{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "created_at desc"
+	if filter.Trending {
+		order = "hot_score desc"
+	}
+
+	var markets []models.Market
+	err := query.Preload("Creator").Order(order).Order("id desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&markets).Error
+	return markets, total, err
+}
+
+// UpdateMarket saves changes to a market, leaving its creator alone
+func (s *GormStore) UpdateMarket(market *models.Market) error {
+	return s.db.Omit(clause.Associations).Save(market).Error
+}
+
+// ResolveMarket resolves a market and scores its forecasts in one transaction
+func (s *GormStore) ResolveMarket(market *models.Market, outcome bool, resolvedAt time.Time) ([]models.ScoreRecord, error) {
+	return services.ResolveMarket(s.db, market, outcome, resolvedAt)
+}
+
+// BumpMarketTrending adds an activity to a market's hot score
+func (s *GormStore) BumpMarketTrending(marketID uint, weight float64) {
+	services.BumpMarketTrending(s.db, marketID, weight)
+}
+
+// RecordMarketSnapshot stores a market's current aggregate forecast
+func (s *GormStore) RecordMarketSnapshot(marketID uint) (*models.MarketSnapshot, error) {
+	return services.RecordMarketSnapshot(s.db, marketID)
+}
+
+// GetMarketTimeseries returns a market's downsampled snapshot history
+func (s *GormStore) GetMarketTimeseries(marketID uint, from, to time.Time, resolution time.Duration) ([]models.TimeseriesPoint, error) {
+	return services.GetMarketTimeseries(s.db, marketID, from, to, resolution)
+}
+
+// GetForecast returns the forecast with the ID
+func (s *GormStore) GetForecast(id uint) (*models.Forecast, error) {
+	var forecast models.Forecast
+	if err := s.db.First(&forecast, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &forecast, nil
+}
+
+// GetUserForecast returns the user's forecast on the market, withdrawn or not
+func (s *GormStore) GetUserForecast(marketID, userID uint) (*models.Forecast, error) {
+	var forecast models.Forecast
+	if err := s.db.Where("market_id = ? AND user_id = ?", marketID, userID).First(&forecast).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &forecast, nil
+}
+
+// SaveForecast creates or updates a forecast with its history and the forecaster's stats
+func (s *GormStore) SaveForecast(forecast *models.Forecast) error {
+	return services.SaveForecast(s.db, forecast)
+}
+
+// ListMarketForecasts returns a page of a market's active forecasts, newest first
+func (s *GormStore) ListMarketForecasts(marketID uint, page, limit int) ([]models.Forecast, int64, error) {
+	query := s.db.Model(&models.Forecast{}).Where("market_id = ? AND withdrawn_at IS NULL", marketID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var forecasts []models.Forecast
+	err := query.Preload("User").
+		Order("created_at desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&forecasts).Error
+	return forecasts, total, err
+}
+
+// ForecastsVisible reports whether the user may see others' forecasts on the market
+func (s *GormStore) ForecastsVisible(market *models.Market, userID uint) (bool, error) {
+	return services.ForecastsVisible(s.db, market, userID)
+}
+
+// RecentForecasts returns a user's latest active forecasts that the viewer may see
+func (s *GormStore) RecentForecasts(userID, viewerID uint, limit int) ([]models.Forecast, error) {
+	return services.RecentForecasts(s.db, userID, viewerID, limit)
+}
+
+// ListMarketComments returns a page of a market's threads with their replies
+func (s *GormStore) ListMarketComments(marketID uint, page, limit int) ([]models.Comment, int64, error) {
+	// Pagination applies to threads; replies are returned with their thread
+	query := s.db.Model(&models.Comment{}).Where("market_id = ? AND thread_id IS NULL", marketID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var comments []models.Comment
+	err := query.Preload("User").
+		Order("created_at desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&comments).Error
+	if err != nil || len(comments) == 0 {
+		return comments, total, err
+	}
+
+	threadIDs := make([]uint, len(comments))
+	for i, comment := range comments {
+		threadIDs[i] = comment.ID
+	}
+
+	var replies []models.Comment
+	if err := s.db.Where("thread_id IN ?", threadIDs).Preload("User").Order("created_at asc").Find(&replies).Error; err != nil {
+		return nil, 0, err
+	}
+
+	byThread := make(map[uint][]models.Comment)
+	for _, reply := range replies {
+		byThread[*reply.ThreadID] = append(byThread[*reply.ThreadID], reply)
+	}
+	for i := range comments {
+		comments[i].Replies = byThread[comments[i].ID]
+	}
+	return comments, total, nil
+}
+
+// GetComment returns the comment with its author
+func (s *GormStore) GetComment(id uint) (*models.Comment, error) {
+	var comment models.Comment
+	if err := s.db.Preload("User").First(&comment, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &comment, nil
+}
+
+// CreateComment stores a new comment
+func (s *GormStore) CreateComment(comment *models.Comment) error {
+	return s.db.Omit(clause.Associations).Create(comment).Error
+}
+
+// UpdateComment saves changes to a comment, leaving its author alone
+func (s *GormStore) UpdateComment(comment *models.Comment) error {
+	return s.db.Omit(clause.Associations).Save(comment).Error
+}
+
+// NotifyMentions notifies the users newly mentioned in a comment
+func (s *GormStore) NotifyMentions(author models.User, market models.Market, comment models.Comment, alreadyNotified []string) error {
+	return services.NotifyMentions(s.db, author, market, comment, alreadyNotified)
+}
+
+// CreateNotification stores a notification and pushes it to the user's open streams
+func (s *GormStore) CreateNotification(notification *models.Notification) error {
+	return services.CreateNotification(s.db, notification)
+}
+
+// ListNotifications returns a page of a user's notifications
+func (s *GormStore) ListNotifications(userID int, unreadOnly bool, cursor string, limit int) ([]models.Notification, string, error) {
+	return services.ListNotifications(s.db, userID, unreadOnly, cursor, limit)
+}
+
+// CountUnreadNotifications returns how many unread notifications a user has
+func (s *GormStore) CountUnreadNotifications(userID int) (int64, error) {
+	return services.CountUnreadNotifications(s.db, userID)
+}
+
+// MarkNotificationRead marks one of a user's notifications as read
+func (s *GormStore) MarkNotificationRead(userID, id int) error {
+	return services.MarkNotificationRead(s.db, userID, id)
+}
+
+// MarkAllNotificationsRead marks all of a user's notifications as read
+func (s *GormStore) MarkAllNotificationsRead(userID int) (int64, error) {
+	return services.MarkAllNotificationsRead(s.db, userID)
+}
+
+// DeleteNotification deletes one of a user's notifications
+func (s *GormStore) DeleteNotification(userID, id int) error {
+	return services.DeleteNotification(s.db, userID, id)
+}
+
+// NotificationPreferences returns the user's channel for every notification type
+func (s *GormStore) NotificationPreferences(userID int) (map[models.NotificationType]models.NotificationChannel, error) {
+	return services.NotificationPreferences(s.db, userID)
+}
+
+// UpdateNotificationPreferences stores a user's channels and digest frequency
+func (s *GormStore) UpdateNotificationPreferences(userID int, input models.NotificationPreferencesRequest) error {
+	return services.UpdateNotificationPreferences(s.db, userID, input)
+}
+
+// Unsubscribe applies a one-click unsubscribe link
+func (s *GormStore) Unsubscribe(userID int, scope string) error {
+	return services.Unsubscribe(s.db, userID, scope)
+}
+
+// GetUserStats returns a user's stats
+func (s *GormStore) GetUserStats(userID uint) (models.UserStats, error) {
+	return services.GetUserStats(s.db, userID)
+}
+
+// LoadCalibrationSamples returns a user's forecasts on resolved markets matching filter
+func (s *GormStore) LoadCalibrationSamples(userID uint, filter models.CalibrationFilter) ([]models.CalibrationSample, error) {
+	return services.LoadCalibrationSamples(s.db, userID, filter)
+}
+
+// CompareForecasters compares two users over the markets they both forecast on
+func (s *GormStore) CompareForecasters(userID, otherID, viewerID uint) (models.HeadToHead, error) {
+	return services.CompareForecasters(s.db, userID, otherID, viewerID)
+}
+
+// GetLeaderboard returns a page of the leaderboard and the viewer's entry
+func (s *GormStore) GetLeaderboard(filter models.LeaderboardFilter, page, limit int, viewerID uint) ([]models.LeaderboardEntry, int64, *models.LeaderboardEntry, error) {
+	return services.GetLeaderboard(s.db, filter, page, limit, viewerID)
+}
+
+// UserAchievements returns the achievements a user has earned
+func (s *GormStore) UserAchievements(userID uint) ([]models.EarnedAchievement, error) {
+	return services.UserAchievements(s.db, userID)
+}
+
+// CheckAchievements awards the users any achievements the event earned them
+func (s *GormStore) CheckAchievements(event models.AchievementEvent, userIDs ...uint) {
+	services.CheckAchievements(s.db, event, userIDs...)
+}
+
+// ListTeams returns a page of teams by name
+func (s *GormStore) ListTeams(page, limit int) ([]models.Team, int64, error) {
+	var total int64
+	if err := s.db.Model(&models.Team{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var teams []models.Team
+	err := s.db.Order("name asc").Limit(limit).Offset((page - 1) * limit).Find(&teams).Error
+	return teams, total, err
+}
+
+// GetTeam returns the team with the ID
+func (s *GormStore) GetTeam(id uint) (*models.Team, error) {
+	var team models.Team
+	if err := s.db.First(&team, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &team, nil
+}
+
+// ListTeamMembers returns a team's members in the order they joined
+func (s *GormStore) ListTeamMembers(teamID uint) ([]models.TeamMember, error) {
+	var members []models.TeamMember
+	err := s.db.Preload("User").Where("team_id = ?", teamID).Order("joined_at asc").Find(&members).Error
+	return members, err
+}
+
+// CreateTeam creates a team owned by its creator
+func (s *GormStore) CreateTeam(input *models.TeamRequest, creatorID uint) (*models.Team, error) {
+	return services.CreateTeam(s.db, input, creatorID)
+}
+
+// TeamRoleOf returns the user's role in the team
+func (s *GormStore) TeamRoleOf(teamID, userID uint) (models.TeamRole, error) {
+	return services.TeamRoleOf(s.db, teamID, userID)
+}
+
+// InviteToTeam invites a user to a team and notifies them
+func (s *GormStore) InviteToTeam(team *models.Team, inviterID, userID uint) (*models.TeamInvite, error) {
+	return services.InviteToTeam(s.db, team, inviterID, userID)
+}
+
+// ListTeamInvites returns a user's pending invitations, newest first
+func (s *GormStore) ListTeamInvites(userID uint) ([]models.TeamInvite, error) {
+	var invites []models.TeamInvite
+	err := s.db.Preload("Team").
+		Where("user_id = ? AND status = ?", userID, models.InvitePending).
+		Order("created_at desc").
+		Find(&invites).Error
+	return invites, err
+}
+
+// RespondToTeamInvite accepts or declines a pending invitation
+func (s *GormStore) RespondToTeamInvite(inviteID, userID uint, accept bool) (*models.TeamInvite, error) {
+	return services.RespondToTeamInvite(s.db, inviteID, userID, accept)
+}
+
+// RemoveTeamMember removes a member other than the owner
+func (s *GormStore) RemoveTeamMember(teamID, userID uint) error {
+	return services.RemoveTeamMember(s.db, teamID, userID)
+}
+
+// SetTeamRole changes a member's role
+func (s *GormStore) SetTeamRole(teamID, ownerID, userID uint, role models.TeamRole) error {
+	return services.SetTeamRole(s.db, teamID, ownerID, userID, role)
+}
+
+// GetTeamStats returns a team's score totals and each member's contribution
+func (s *GormStore) GetTeamStats(teamID uint) (models.TeamStats, error) {
+	return services.GetTeamStats(s.db, teamID)
+}
+
+// GetTeamForecast averages the team members' active forecasts on a market
+func (s *GormStore) GetTeamForecast(teamID, marketID uint) (models.TeamForecast, error) {
+	return services.GetTeamForecast(s.db, teamID, marketID)
+}
+
+// GetTeamLeaderboard returns a page of ranked teams
+func (s *GormStore) GetTeamLeaderboard(ranking models.TeamRanking, page, limit int) ([]models.TeamEntry, int64, error) {
+	return services.GetTeamLeaderboard(s.db, ranking, page, limit)
+}
+
+// ListTournaments returns a page of the tournaments with status at now, latest first
+func (s *GormStore) ListTournaments(status models.TournamentStatus, now time.Time, page, limit int) ([]models.Tournament, int64, error) {
+	query := s.db.Model(&models.Tournament{})
+	switch status {
+	case models.TournamentUpcoming:
+		query = query.Where("start_date > ?", now)
+	case models.TournamentActive:
+		query = query.Where("start_date <= ? AND end_date > ?", now, now)
+	case models.TournamentFinished:
+		query = query.Where("end_date <= ?", now)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var tournaments []models.Tournament
+	err := query.Order("start_date desc").Order("id desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&tournaments).Error
+	return tournaments, total, err
+}
+
+// GetTournament returns the tournament with its markets and prizes
+func (s *GormStore) GetTournament(id uint) (*models.Tournament, error) {
+	var tournament models.Tournament
+	if err := s.db.Preload("Markets").Preload("Prizes").First(&tournament, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &tournament, nil
+}
+
+// CountTournamentEntrants returns how many users joined a tournament
+func (s *GormStore) CountTournamentEntrants(tournamentID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.TournamentEntrant{}).Where("tournament_id = ?", tournamentID).Count(&count).Error
+	return count, err
+}
+
+// HasJoinedTournament reports whether the user joined a tournament
+func (s *GormStore) HasJoinedTournament(tournamentID, userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.TournamentEntrant{}).Where("tournament_id = ? AND user_id = ?", tournamentID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// ListTournamentAwards returns a tournament's awards by rank
+func (s *GormStore) ListTournamentAwards(tournamentID uint) ([]models.TournamentAward, error) {
+	awards := []models.TournamentAward{}
+	err := s.db.Where("tournament_id = ?", tournamentID).Order("rank asc").Order("user_id asc").Find(&awards).Error
+	return awards, err
+}
+
+// CreateTournament creates a tournament over existing markets
+func (s *GormStore) CreateTournament(input *models.TournamentRequest, creatorID uint) (*models.Tournament, error) {
+	return services.CreateTournament(s.db, input, creatorID)
+}
+
+// JoinTournament signs a user up for a tournament
+func (s *GormStore) JoinTournament(tournament *models.Tournament, userID uint, now time.Time) error {
+	return services.JoinTournament(s.db, tournament, userID, now)
+}
+
+// TournamentStandings ranks a tournament's forecasters
+func (s *GormStore) TournamentStandings(tournament *models.Tournament) ([]models.LeaderboardEntry, error) {
+	return services.TournamentStandings(s.db, tournament)
+}
+
+// ScheduleDailyChallenge makes an open market the challenge of a day
+func (s *GormStore) ScheduleDailyChallenge(input *models.DailyChallengeRequest, creatorID uint) (*models.DailyChallenge, error) {
+	return services.ScheduleDailyChallenge(s.db, input, creatorID)
+}
+
+// DailyChallengeFor returns the challenge of a day, or nil
+func (s *GormStore) DailyChallengeFor(day string) (*models.DailyChallenge, error) {
+	return services.DailyChallengeFor(s.db, day)
+}
+
+// ListWebhooks returns a user's webhooks, newest first
+func (s *GormStore) ListWebhooks(userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := s.db.Where("user_id = ?", userID).Order("created_at desc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// CreateWebhook stores a new webhook
+func (s *GormStore) CreateWebhook(webhook *models.Webhook) error {
+	return s.db.Create(webhook).Error
+}
+
+// GetWebhook returns the webhook with the ID
+func (s *GormStore) GetWebhook(id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := s.db.First(&webhook, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook deletes a webhook with its delivery log
+func (s *GormStore) DeleteWebhook(id uint) error {
+	return services.DeleteWebhook(s.db, id)
+}
+
+// ListWebhookDeliveries returns a page of a webhook's deliveries, newest first
+func (s *GormStore) ListWebhookDeliveries(webhookID uint, status models.WebhookDeliveryStatus, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	query := s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at desc, id desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// GetWebhookDelivery returns one of a webhook's deliveries
+func (s *GormStore) GetWebhookDelivery(webhookID, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := s.db.Where("id = ? AND webhook_id = ?", id, webhookID).First(&delivery).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &delivery, nil
+}
+
+// ListWebhookAttempts returns a delivery's attempts, oldest first
+func (s *GormStore) ListWebhookAttempts(deliveryID uint) ([]models.WebhookAttempt, error) {
+	var attempts []models.WebhookAttempt
+	err := s.db.Where("delivery_id = ?", deliveryID).Order("created_at asc").Find(&attempts).Error
+	return attempts, err
+}
+
+// ReplayWebhookDelivery queues a delivery to be sent again
+func (s *GormStore) ReplayWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return services.ReplayWebhookDelivery(s.db, delivery)
+}
+
+// DispatchWebhookEvent queues deliveries of an event to the webhooks subscribed to it
+func (s *GormStore) DispatchWebhookEvent(event models.WebhookEvent, ownerID uint, data interface{}) {
+	services.DispatchWebhookEvent(s.db, event, ownerID, data)
+}
+
+// notFound translates GORM's missing-record error to utils.ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,2149 @@
+package store
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+)
+
+// MemoryStore keeps everything in memory, for tests and local development without a database.
+// It is safe for concurrent use. The background jobs only run against the database, so
+// webhook deliveries stay pending and finished tournaments are never awarded.
+type MemoryStore struct {
+	mu                sync.Mutex
+	users             map[uint]models.User
+	follows           map[pairKey]models.Follow
+	activities        map[uint]models.Activity
+	markets           map[uint]models.Market
+	forecasts         map[uint]models.Forecast
+	revisions         []models.ForecastRevision
+	snapshots         []models.MarketSnapshot
+	scores            []models.ScoreRecord
+	stats             map[uint]models.UserStats
+	achievements      map[uint][]models.UserAchievement
+	predictions       map[int]models.Prediction
+	results           map[int]models.Result
+	votes             map[voteKey]models.Vote
+	comments          map[uint]models.Comment
+	notifications     map[int]models.Notification
+	preferences       map[int]map[models.NotificationType]models.NotificationChannel
+	teams             map[uint]models.Team
+	members           map[pairKey]models.TeamMember
+	invites           map[pairKey]models.TeamInvite
+	tournaments       map[uint]models.Tournament
+	tournamentMarkets map[uint][]uint
+	entrants          map[pairKey]models.TournamentEntrant
+	awards            []models.TournamentAward
+	challenges        map[string]models.DailyChallenge
+	webhooks          map[uint]models.Webhook
+	deliveries        map[uint]models.WebhookDelivery
+	attempts          map[uint]models.WebhookAttempt
+	lastID            int
+}
+
+// voteKey identifies a user's vote on a prediction
+type voteKey struct {
+	userID       int
+	predictionID int
+}
+
+// pairKey identifies a record linking two others, such as a follow or a team membership
+type pairKey struct {
+	a, b uint
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:             map[uint]models.User{},
+		follows:           map[pairKey]models.Follow{},
+		activities:        map[uint]models.Activity{},
+		markets:           map[uint]models.Market{},
+		forecasts:         map[uint]models.Forecast{},
+		stats:             map[uint]models.UserStats{},
+		achievements:      map[uint][]models.UserAchievement{},
+		predictions:       map[int]models.Prediction{},
+		results:           map[int]models.Result{},
+		votes:             map[voteKey]models.Vote{},
+		comments:          map[uint]models.Comment{},
+		notifications:     map[int]models.Notification{},
+		preferences:       map[int]map[models.NotificationType]models.NotificationChannel{},
+		teams:             map[uint]models.Team{},
+		members:           map[pairKey]models.TeamMember{},
+		invites:           map[pairKey]models.TeamInvite{},
+		tournaments:       map[uint]models.Tournament{},
+		tournamentMarkets: map[uint][]uint{},
+		entrants:          map[pairKey]models.TournamentEntrant{},
+		challenges:        map[string]models.DailyChallenge{},
+		webhooks:          map[uint]models.Webhook{},
+		deliveries:        map[uint]models.WebhookDelivery{},
+		attempts:          map[uint]models.WebhookAttempt{},
+	}
+}
+
+// nextID returns a fresh ID; IDs are unique across all records. Callers hold the lock.
+func (s *MemoryStore) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+// CreateUser stores a new user unless its email or username is taken
+func (s *MemoryStore) CreateUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return utils.ErrDuplicate
+		}
+		if user.Username != "" && existing.Username == user.Username {
+			return ErrUsernameTaken
+		}
+	}
+
+	now := time.Now()
+	user.ID = uint(s.nextID())
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+	if user.TimeZone == "" {
+		user.TimeZone = "UTC"
+	}
+	if user.DigestFrequency == "" {
+		user.DigestFrequency = models.DigestOff
+	}
+	s.users[user.ID] = *user
+	return nil
+}
+
+// GetUser returns the user with the ID
+func (s *MemoryStore) GetUser(id uint) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	return &user, nil
+}
+
+// GetUserByEmail returns the user with the email
+func (s *MemoryStore) GetUserByEmail(email string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, utils.ErrNotFound
+}
+
+// UpdateUser saves a user's profile unless another user has its username
+func (s *MemoryStore) UpdateUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[user.ID]; !ok {
+		return utils.ErrNotFound
+	}
+	for _, existing := range s.users {
+		if existing.ID != user.ID && existing.Username == user.Username {
+			return ErrUsernameTaken
+		}
+	}
+
+	user.UpdatedAt = time.Now()
+	s.users[user.ID] = *user
+	return nil
+}
+
+// FollowUser makes follower follow followee
+func (s *MemoryStore) FollowUser(followerID, followeeID uint) error {
+	follow := models.Follow{FollowerID: followerID, FolloweeID: followeeID, CreatedAt: time.Now()}
+	if err := follow.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pairKey{followerID, followeeID}
+	if _, ok := s.follows[key]; !ok {
+		s.follows[key] = follow
+	}
+	return nil
+}
+
+// UnfollowUser removes a follow
+func (s *MemoryStore) UnfollowUser(followerID, followeeID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pairKey{followerID, followeeID}
+	if _, ok := s.follows[key]; !ok {
+		return utils.ErrNotFound
+	}
+	delete(s.follows, key)
+	return nil
+}
+
+// GetFollowCounts returns the follower and following counts of a user
+func (s *MemoryStore) GetFollowCounts(userID uint) (models.FollowCounts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var counts models.FollowCounts
+	for key := range s.follows {
+		if key.b == userID {
+			counts.Followers++
+		}
+		if key.a == userID {
+			counts.Following++
+		}
+	}
+	return counts, nil
+}
+
+// IsFollowing reports whether follower follows followee
+func (s *MemoryStore) IsFollowing(followerID, followeeID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.follows[pairKey{followerID, followeeID}]
+	return ok, nil
+}
+
+// ListFollowers returns a page of a user's followers, most recent first
+func (s *MemoryStore) ListFollowers(userID uint, page, limit int) ([]models.User, int64, error) {
+	return s.listFollowUsers(userID, page, limit, func(f models.Follow) (uint, uint) { return f.FolloweeID, f.FollowerID })
+}
+
+// ListFollowing returns a page of the users a user follows, most recent first
+func (s *MemoryStore) ListFollowing(userID uint, page, limit int) ([]models.User, int64, error) {
+	return s.listFollowUsers(userID, page, limit, func(f models.Follow) (uint, uint) { return f.FollowerID, f.FolloweeID })
+}
+
+// listFollowUsers lists the other side of the follows whose matched side is userID
+func (s *MemoryStore) listFollowUsers(userID uint, page, limit int, sides func(models.Follow) (uint, uint)) ([]models.User, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var follows []models.Follow
+	for _, follow := range s.follows {
+		if match, _ := sides(follow); match == userID {
+			follows = append(follows, follow)
+		}
+	}
+	sort.Slice(follows, func(i, j int) bool {
+		_, a := sides(follows[i])
+		_, b := sides(follows[j])
+		if !follows[i].CreatedAt.Equal(follows[j].CreatedAt) {
+			return follows[i].CreatedAt.After(follows[j].CreatedAt)
+		}
+		return a > b
+	})
+
+	total := int64(len(follows))
+	follows = paginate(follows, page, limit)
+	users := make([]models.User, len(follows))
+	for i, follow := range follows {
+		_, other := sides(follow)
+		users[i] = s.users[other]
+	}
+	return users, total, nil
+}
+
+// RecordActivity stores an activity for followers' feeds
+func (s *MemoryStore) RecordActivity(activity models.Activity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activity.ID = uint(s.nextID())
+	if activity.CreatedAt.IsZero() {
+		activity.CreatedAt = time.Now()
+	}
+	activity.Actor = models.User{}
+	activity.Market = models.Market{}
+	activity.Forecast = nil
+	s.activities[activity.ID] = activity
+}
+
+// GetFeed returns a page of activity by the users the viewer follows, newest first, and the cursor of the next page
+func (s *MemoryStore) GetFeed(viewerID uint, cursor string, limit int) ([]models.Activity, string, error) {
+	var afterTime time.Time
+	var afterID uint
+	if cursor != "" {
+		var err error
+		if afterTime, afterID, err = utils.DecodeCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	activities := []models.Activity{}
+	for _, activity := range s.activities {
+		if _, ok := s.follows[pairKey{viewerID, activity.ActorID}]; !ok {
+			continue
+		}
+		if activity.Type == models.ActivityForecast && !s.forecastsVisible(s.markets[activity.MarketID], viewerID, now) {
+			continue
+		}
+		if cursor != "" && !olderThan(activity.CreatedAt, activity.ID, afterTime, afterID) {
+			continue
+		}
+		activities = append(activities, activity)
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		return !olderThan(activities[i].CreatedAt, activities[i].ID, activities[j].CreatedAt, activities[j].ID)
+	})
+
+	next := ""
+	if len(activities) > limit {
+		activities = activities[:limit]
+		last := activities[limit-1]
+		next = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	for i := range activities {
+		activity := &activities[i]
+		activity.Actor = s.users[activity.ActorID]
+		activity.Market = s.markets[activity.MarketID]
+		if activity.ForecastID != nil {
+			if forecast, ok := s.forecasts[*activity.ForecastID]; ok {
+				activity.Forecast = &forecast
+			}
+		}
+	}
+	return activities, next, nil
+}
+
+// CreatePrediction stores a new prediction
+func (s *MemoryStore) CreatePrediction(prediction *models.Prediction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prediction.ID = s.nextID()
+	if prediction.CreatedAt.IsZero() {
+		prediction.CreatedAt = time.Now()
+	}
+	s.predictions[prediction.ID] = *prediction
+	return nil
+}
+
+// GetPredictions returns a page of all predictions, newest first
+func (s *MemoryStore) GetPredictions(userID int, page int, limit int) ([]models.Prediction, error) {
+	return s.FindPredictions(models.PredictionFilter{}, userID, page, limit)
+}
+
+// FindPredictions returns a page of the predictions matching filter
+func (s *MemoryStore) FindPredictions(filter models.PredictionFilter, viewerID, page, limit int) ([]models.Prediction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := s.matchingPredictions(filter)
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if filter.Trending && a.HotScore != b.HotScore {
+			return a.HotScore > b.HotScore
+		}
+		if !filter.Trending && !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	matches = paginate(matches, page, limit)
+	for i := range matches {
+		s.applyUserVote(&matches[i], viewerID)
+	}
+	return matches, nil
+}
+
+// CountPredictions returns how many predictions match filter
+func (s *MemoryStore) CountPredictions(filter models.PredictionFilter) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.matchingPredictions(filter))), nil
+}
+
+// GetPrediction returns a prediction with its result and the viewer's vote
+func (s *MemoryStore) GetPrediction(id, viewerID int) (*models.Prediction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prediction, ok := s.predictions[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	s.decoratePrediction(&prediction, time.Now())
+	s.applyUserVote(&prediction, viewerID)
+	return &prediction, nil
+}
+
+// UpdatePrediction saves changes to an existing prediction
+func (s *MemoryStore) UpdatePrediction(prediction *models.Prediction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.predictions[prediction.ID]; !ok {
+		return utils.ErrNotFound
+	}
+	stored := *prediction
+	stored.UserName, stored.UserVote, stored.Outcome, stored.Status = "", nil, nil, ""
+	s.predictions[prediction.ID] = stored
+	return nil
+}
+
+// DeletePrediction deletes a prediction with its votes and result
+func (s *MemoryStore) DeletePrediction(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.votes {
+		if key.predictionID == id {
+			delete(s.votes, key)
+		}
+	}
+	delete(s.results, id)
+	delete(s.predictions, id)
+	return nil
+}
+
+// ResolvePrediction stores a prediction's result and notifies its voters
+func (s *MemoryStore) ResolvePrediction(prediction *models.Prediction, result *models.Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.results[prediction.ID]; ok {
+		return utils.ErrDuplicate
+	}
+	result.ID = s.nextID()
+	result.PredictionID = prediction.ID
+	result.CreatedAt = time.Now()
+	s.results[prediction.ID] = *result
+
+	outcome := result.Outcome
+	prediction.Outcome = &outcome
+	prediction.Status = models.PredictionResolved
+
+	for key, vote := range s.votes {
+		if key.predictionID != prediction.ID || vote.RemovedAt != nil {
+			continue
+		}
+		notification := services.ResultNotification(prediction, vote.UserID)
+		if err := s.createNotification(&notification); err != nil {
+			log.Printf("Failed to notify result of prediction %d: %v", prediction.ID, err)
+		}
+	}
+	return nil
+}
+
+// VotePrediction records a vote and updates the prediction's vote counts. Only a user's
+// first vote on a prediction notifies its author and counts towards trending.
+func (s *MemoryStore) VotePrediction(vote *models.Vote) error {
+	if err := vote.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prediction, ok := s.predictions[vote.PredictionID]
+	if !ok {
+		return utils.ErrNotFound
+	}
+
+	now := time.Now()
+	key := voteKey{vote.UserID, vote.PredictionID}
+	existing, voted := s.votes[key]
+	switch {
+	case !voted:
+		vote.ID = s.nextID()
+		vote.CreatedAt = now
+		vote.UpdatedAt = now
+		adjustCounts(&prediction, vote.Value, 1)
+		prediction.HotScore = services.AddHotScore(prediction.HotScore, services.TrendingWeightVote, now)
+
+	case existing.RemovedAt != nil:
+		// Voting again after removing a vote restores it
+		existing.Value = vote.Value
+		existing.RemovedAt = nil
+		existing.UpdatedAt = now
+		*vote = existing
+		adjustCounts(&prediction, vote.Value, 1)
+
+	case existing.Value != vote.Value:
+		// Changing sides moves the vote from one counter to the other
+		adjustCounts(&prediction, existing.Value, -1)
+		adjustCounts(&prediction, vote.Value, 1)
+		existing.Value = vote.Value
+		existing.UpdatedAt = now
+		*vote = existing
+
+	default:
+		*vote = existing
+		return nil
+	}
+
+	s.votes[key] = *vote
+	s.predictions[prediction.ID] = prediction
+
+	if !voted && prediction.UserID != vote.UserID {
+		voter := s.users[uint(vote.UserID)]
+		notification := services.VoteNotification(&voter, vote, &prediction)
+		if err := s.createNotification(&notification); err != nil {
+			log.Printf("Failed to notify vote on prediction %d: %v", prediction.ID, err)
+		}
+	}
+	return nil
+}
+
+// RemoveVote withdraws a user's vote on a prediction
+func (s *MemoryStore) RemoveVote(userID, predictionID int) (*models.Prediction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prediction, ok := s.predictions[predictionID]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	key := voteKey{userID, predictionID}
+	vote, ok := s.votes[key]
+	if !ok || vote.RemovedAt != nil {
+		return nil, utils.ErrNotFound
+	}
+
+	now := time.Now()
+	vote.RemovedAt = &now
+	vote.UpdatedAt = now
+	s.votes[key] = vote
+	adjustCounts(&prediction, vote.Value, -1)
+	s.predictions[predictionID] = prediction
+	return &prediction, nil
+}
+
+// CreateMarket stores a new market
+func (s *MemoryStore) CreateMarket(market *models.Market) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	market.ID = uint(s.nextID())
+	if market.Status == "" {
+		market.Status = models.MarketOpen
+	}
+	if market.CreatedAt.IsZero() {
+		market.CreatedAt = now
+	}
+	market.UpdatedAt = now
+	s.markets[market.ID] = *market
+	return nil
+}
+
+// GetMarket returns the market with its creator
+func (s *MemoryStore) GetMarket(id uint) (*models.Market, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	market, ok := s.markets[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	market.Creator = s.users[market.CreatorID]
+	return &market, nil
+}
+
+// ListMarkets returns a page of the markets matching filter and their number
+func (s *MemoryStore) ListMarkets(filter MarketFilter, page, limit int) ([]models.Market, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := []models.Market{}
+	for _, market := range s.markets {
+		if filter.Status != "" && market.Status != filter.Status {
+			continue
+		}
+		if filter.Category != "" && market.Category != filter.Category {
+			continue
+		}
+		matches = append(matches, market)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if filter.Trending && a.HotScore != b.HotScore {
+			return a.HotScore > b.HotScore
+		}
+		if !filter.Trending && !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	total := int64(len(matches))
+	matches = paginate(matches, page, limit)
+	for i := range matches {
+		matches[i].Creator = s.users[matches[i].CreatorID]
+	}
+	return matches, total, nil
+}
+
+// UpdateMarket saves changes to an existing market
+func (s *MemoryStore) UpdateMarket(market *models.Market) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.markets[market.ID]; !ok {
+		return utils.ErrNotFound
+	}
+	market.UpdatedAt = time.Now()
+	stored := *market
+	stored.Creator = models.User{}
+	s.markets[market.ID] = stored
+	return nil
+}
+
+// ResolveMarket resolves a market and scores its active forecasts
+func (s *MemoryStore) ResolveMarket(market *models.Market, outcome bool, resolvedAt time.Time) ([]models.ScoreRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.markets[market.ID]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	if stored.Status == models.MarketResolved {
+		return nil, services.ErrMarketAlreadyResolved
+	}
+	market.Status = models.MarketResolved
+	market.Outcome = &outcome
+	market.ResolvedAt = &resolvedAt
+	market.UpdatedAt = resolvedAt
+	stored = *market
+	stored.Creator = models.User{}
+	s.markets[market.ID] = stored
+
+	var records []models.ScoreRecord
+	for _, forecast := range s.marketForecasts(market.ID) {
+		record := services.NewScoreRecord(&forecast, market, outcome, resolvedAt)
+		record.ID = uint(s.nextID())
+		records = append(records, record)
+
+		user := s.users[record.UserID]
+		user.PredictionScore += record.Score
+		s.users[record.UserID] = user
+		s.applyScoreRecord(record)
+	}
+	s.scores = append(s.scores, records...)
+	return records, nil
+}
+
+// BumpMarketTrending adds an activity to a market's hot score
+func (s *MemoryStore) BumpMarketTrending(marketID uint, weight float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	market, ok := s.markets[marketID]
+	if !ok {
+		log.Printf("Failed to update trending score for market %d: %v", marketID, utils.ErrNotFound)
+		return
+	}
+	market.HotScore = services.AddHotScore(market.HotScore, weight, time.Now())
+	s.markets[marketID] = market
+}
+
+// RecordMarketSnapshot stores a market's current aggregate forecast and pushes it to its subscribers
+func (s *MemoryStore) RecordMarketSnapshot(marketID uint) (*models.MarketSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forecasts := s.marketForecasts(marketID)
+	snapshot := models.MarketSnapshot{
+		ID:              uint(s.nextID()),
+		MarketID:        marketID,
+		Probability:     meanProbability(forecasts),
+		PredictionCount: len(forecasts),
+		CreatedAt:       time.Now(),
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+	services.PublishMarketUpdate(&snapshot)
+
+	return &snapshot, nil
+}
+
+// GetMarketTimeseries returns a market's snapshot history downsampled to resolution
+func (s *MemoryStore) GetMarketTimeseries(marketID uint, from, to time.Time, resolution time.Duration) ([]models.TimeseriesPoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var snapshots []models.MarketSnapshot
+	for _, snapshot := range s.snapshots {
+		if snapshot.MarketID != marketID {
+			continue
+		}
+		if (!from.IsZero() && snapshot.CreatedAt.Before(from)) || (!to.IsZero() && snapshot.CreatedAt.After(to)) {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+	return services.DownsampleSnapshots(snapshots, resolution), nil
+}
+
+// GetForecast returns the forecast with the ID
+func (s *MemoryStore) GetForecast(id uint) (*models.Forecast, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forecast, ok := s.forecasts[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	return &forecast, nil
+}
+
+// GetUserForecast returns the user's forecast on the market, withdrawn or not
+func (s *MemoryStore) GetUserForecast(marketID, userID uint) (*models.Forecast, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forecast, ok := s.userForecast(marketID, userID)
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	return &forecast, nil
+}
+
+// SaveForecast creates or updates a forecast, recording the change in its history and the forecaster's stats
+func (s *MemoryStore) SaveForecast(forecast *models.Forecast) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wasActive := false
+	if forecast.ID != 0 {
+		previous, ok := s.forecasts[forecast.ID]
+		if !ok {
+			return utils.ErrNotFound
+		}
+		wasActive = previous.IsActive()
+	} else {
+		forecast.ID = uint(s.nextID())
+	}
+	if forecast.CreatedAt.IsZero() {
+		forecast.CreatedAt = now
+	}
+	forecast.UpdatedAt = now
+
+	stored := *forecast
+	stored.Market, stored.User = models.Market{}, models.User{}
+	s.forecasts[forecast.ID] = stored
+
+	stats := s.userStats(forecast.UserID)
+	switch {
+	case forecast.IsActive() && !wasActive:
+		stats.TotalPredictions++
+	case !forecast.IsActive() && wasActive:
+		stats.TotalPredictions--
+	}
+	if forecast.IsActive() {
+		user := s.users[forecast.UserID]
+		services.AdvanceDayStreak(&stats, models.LocalDay(now, user.Location()))
+	}
+	stats.UpdatedAt = now
+	s.stats[forecast.UserID] = stats
+
+	revision := forecast.Revision()
+	revision.ID = uint(s.nextID())
+	s.revisions = append(s.revisions, revision)
+	return nil
+}
+
+// ListMarketForecasts returns a page of a market's active forecasts, newest first
+func (s *MemoryStore) ListMarketForecasts(marketID uint, page, limit int) ([]models.Forecast, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forecasts := s.marketForecasts(marketID)
+	sort.Slice(forecasts, func(i, j int) bool {
+		return !olderThan(forecasts[i].CreatedAt, forecasts[i].ID, forecasts[j].CreatedAt, forecasts[j].ID)
+	})
+
+	total := int64(len(forecasts))
+	forecasts = paginate(forecasts, page, limit)
+	for i := range forecasts {
+		forecasts[i].User = s.users[forecasts[i].UserID]
+	}
+	return forecasts, total, nil
+}
+
+// ForecastsVisible reports whether the user may see others' forecasts on the market
+func (s *MemoryStore) ForecastsVisible(market *models.Market, userID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.forecastsVisible(*market, userID, time.Now()), nil
+}
+
+// RecentForecasts returns a user's latest active forecasts that the viewer may see
+func (s *MemoryStore) RecentForecasts(userID, viewerID uint, limit int) ([]models.Forecast, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	recent := []models.Forecast{}
+	for _, forecast := range s.forecasts {
+		if forecast.UserID != userID || !forecast.IsActive() {
+			continue
+		}
+		market := s.markets[forecast.MarketID]
+		if userID != viewerID && !s.forecastsVisible(market, viewerID, now) {
+			continue
+		}
+		forecast.Market = market
+		recent = append(recent, forecast)
+	}
+
+	sort.Slice(recent, func(i, j int) bool {
+		return !olderThan(recent[i].CreatedAt, recent[i].ID, recent[j].CreatedAt, recent[j].ID)
+	})
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent, nil
+}
+
+// ListMarketComments returns a page of a market's threads with their replies
+func (s *MemoryStore) ListMarketComments(marketID uint, page, limit int) ([]models.Comment, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	threads := []models.Comment{}
+	replies := make(map[uint][]models.Comment)
+	for _, comment := range s.comments {
+		if comment.MarketID != marketID {
+			continue
+		}
+		comment.User = s.users[comment.UserID]
+		if comment.ThreadID == nil {
+			threads = append(threads, comment)
+		} else {
+			replies[*comment.ThreadID] = append(replies[*comment.ThreadID], comment)
+		}
+	}
+
+	sort.Slice(threads, func(i, j int) bool {
+		return !olderThan(threads[i].CreatedAt, threads[i].ID, threads[j].CreatedAt, threads[j].ID)
+	})
+	total := int64(len(threads))
+	threads = paginate(threads, page, limit)
+	for i := range threads {
+		thread := replies[threads[i].ID]
+		sort.Slice(thread, func(i, j int) bool {
+			return olderThan(thread[i].CreatedAt, thread[i].ID, thread[j].CreatedAt, thread[j].ID)
+		})
+		threads[i].Replies = thread
+	}
+	return threads, total, nil
+}
+
+// GetComment returns the comment with its author
+func (s *MemoryStore) GetComment(id uint) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	comment.User = s.users[comment.UserID]
+	return &comment, nil
+}
+
+// CreateComment stores a new comment
+func (s *MemoryStore) CreateComment(comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	comment.ID = uint(s.nextID())
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = now
+	}
+	comment.UpdatedAt = now
+	s.storeComment(*comment)
+	return nil
+}
+
+// UpdateComment saves changes to an existing comment
+func (s *MemoryStore) UpdateComment(comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.comments[comment.ID]; !ok {
+		return utils.ErrNotFound
+	}
+	comment.UpdatedAt = time.Now()
+	s.storeComment(*comment)
+	return nil
+}
+
+// NotifyMentions notifies the users newly mentioned in a comment
+func (s *MemoryStore) NotifyMentions(author models.User, market models.Market, comment models.Comment, alreadyNotified []string) error {
+	usernames := services.ExtractMentions(comment.Body)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	skip := make(map[string]bool)
+	for _, username := range alreadyNotified {
+		skip[strings.ToLower(username)] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, username := range usernames {
+		user, ok := s.userByUsername(username)
+		if !ok || user.ID == author.ID || skip[strings.ToLower(user.Username)] {
+			continue
+		}
+
+		notification := services.MentionNotification(author, market, comment, user.ID)
+		if err := s.createNotification(&notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateNotification stores a notification unless the user has turned its type off
+func (s *MemoryStore) CreateNotification(notification *models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createNotification(notification)
+}
+
+// ListNotifications returns a page of a user's notifications, newest first, and the cursor of the next page
+func (s *MemoryStore) ListNotifications(userID int, unreadOnly bool, cursor string, limit int) ([]models.Notification, string, error) {
+	var afterTime time.Time
+	var afterID uint
+	if cursor != "" {
+		var err error
+		if afterTime, afterID, err = utils.DecodeCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications := []models.Notification{}
+	for _, notification := range s.notifications {
+		if notification.UserID != userID || (unreadOnly && notification.Read) {
+			continue
+		}
+		if cursor != "" && !olderThan(notification.CreatedAt, uint(notification.ID), afterTime, afterID) {
+			continue
+		}
+		notifications = append(notifications, notification)
+	}
+
+	sortNewestFirst(notifications)
+
+	next := ""
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[limit-1]
+		next = utils.EncodeCursor(last.CreatedAt, uint(last.ID))
+	}
+	return notifications, next, nil
+}
+
+// CountUnreadNotifications returns how many unread notifications a user has
+func (s *MemoryStore) CountUnreadNotifications(userID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, notification := range s.notifications {
+		if notification.UserID == userID && !notification.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MarkNotificationRead marks one of a user's notifications as read
+func (s *MemoryStore) MarkNotificationRead(userID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := s.notifications[id]
+	if !ok || notification.UserID != userID {
+		return utils.ErrNotFound
+	}
+	notification.Read = true
+	s.notifications[id] = notification
+	return nil
+}
+
+// MarkAllNotificationsRead marks all of a user's notifications as read and returns how many changed
+func (s *MemoryStore) MarkAllNotificationsRead(userID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updated int64
+	for id, notification := range s.notifications {
+		if notification.UserID == userID && !notification.Read {
+			notification.Read = true
+			s.notifications[id] = notification
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// DeleteNotification deletes one of a user's notifications
+func (s *MemoryStore) DeleteNotification(userID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := s.notifications[id]
+	if !ok || notification.UserID != userID {
+		return utils.ErrNotFound
+	}
+	delete(s.notifications, id)
+	return nil
+}
+
+// NotificationPreferences returns the user's channel for every notification type
+func (s *MemoryStore) NotificationPreferences(userID int) (map[models.NotificationType]models.NotificationChannel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := make(map[models.NotificationType]models.NotificationChannel, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		channels[t] = s.channelFor(userID, t)
+	}
+	return channels, nil
+}
+
+// UpdateNotificationPreferences stores a user's channels and digest frequency
+func (s *MemoryStore) UpdateNotificationPreferences(userID int, input models.NotificationPreferencesRequest) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for t, channel := range input.Channels {
+		s.setChannel(userID, t, channel)
+	}
+	if input.DigestFrequency != "" {
+		s.setDigestFrequency(userID, input.DigestFrequency)
+	}
+	return nil
+}
+
+// Unsubscribe applies a one-click unsubscribe link
+func (s *MemoryStore) Unsubscribe(userID int, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if scope == services.UnsubscribeDigests {
+		s.setDigestFrequency(userID, models.DigestOff)
+		return nil
+	}
+
+	t := models.NotificationType(scope)
+	if !t.IsValid() {
+		return services.ErrInvalidUnsubscribeScope
+	}
+	if s.channelFor(userID, t) == models.ChannelEmail {
+		s.setChannel(userID, t, models.ChannelInApp)
+	}
+	return nil
+}
+
+// GetUserStats returns a user's stats
+func (s *MemoryStore) GetUserStats(userID uint) (models.UserStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.userStats(userID), nil
+}
+
+// LoadCalibrationSamples returns a user's forecasts on resolved markets matching filter
+func (s *MemoryStore) LoadCalibrationSamples(userID uint, filter models.CalibrationFilter) ([]models.CalibrationSample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calibrationSamples(userID, filter), nil
+}
+
+// CompareForecasters compares two users over the markets they both forecast on
+func (s *MemoryStore) CompareForecasters(userID, otherID, viewerID uint) (models.HeadToHead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var pairs []services.ForecastPair
+	for _, forecast := range s.forecasts {
+		if forecast.UserID != userID || !forecast.IsActive() {
+			continue
+		}
+		other, ok := s.userForecast(forecast.MarketID, otherID)
+		if !ok || !other.IsActive() {
+			continue
+		}
+
+		// Both users have forecast on the market, so only a third party can be hidden from
+		market := s.markets[forecast.MarketID]
+		if viewerID != userID && viewerID != otherID && !s.forecastsVisible(market, viewerID, now) {
+			continue
+		}
+		pairs = append(pairs, services.ForecastPair{Market: market, A: forecast, B: other})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Market.ID < pairs[j].Market.ID })
+	return services.CompareForecasts(pairs), nil
+}
+
+// GetLeaderboard returns a page of the leaderboard and the viewer's entry
+func (s *MemoryStore) GetLeaderboard(filter models.LeaderboardFilter, page, limit int, viewerID uint) ([]models.LeaderboardEntry, int64, *models.LeaderboardEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ranked := services.RankLeaderboard(filter, s.scores)
+	var mine *models.LeaderboardEntry
+	for i := range ranked {
+		ranked[i].Username = s.users[ranked[i].UserID].Username
+		if viewerID != 0 && ranked[i].UserID == viewerID {
+			entry := ranked[i]
+			mine = &entry
+		}
+	}
+	return paginate(ranked, page, limit), int64(len(ranked)), mine, nil
+}
+
+// UserAchievements returns the achievements a user has earned, earliest first
+func (s *MemoryStore) UserAchievements(userID uint) ([]models.EarnedAchievement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := append([]models.UserAchievement(nil), s.achievements[userID]...)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].EarnedAt.Before(rows[j].EarnedAt) })
+
+	earned := make([]models.EarnedAchievement, 0, len(rows))
+	for _, row := range rows {
+		rule, ok := services.AchievementRuleFor(row.Key)
+		if !ok {
+			continue
+		}
+		earned = append(earned, models.EarnedAchievement{
+			Key:         rule.Key,
+			Name:        rule.Name,
+			Description: rule.Description,
+			EarnedAt:    row.EarnedAt,
+		})
+	}
+	return earned, nil
+}
+
+// CheckAchievements awards the users any achievements the event earned them and notifies them
+func (s *MemoryStore) CheckAchievements(event models.AchievementEvent, userIDs ...uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, userID := range userIDs {
+		facts := &services.AchievementFacts{Stats: s.userStats(userID)}
+		if event == models.AchievementMarketResolved {
+			facts.Calibration = services.ComputeCalibration(s.calibrationSamples(userID, models.CalibrationFilter{}), services.DefaultCalibrationBuckets)
+		}
+
+		for _, rule := range services.EvaluateAchievements(event, facts) {
+			if s.hasAchievement(userID, rule.Key) {
+				continue
+			}
+			achievement := models.UserAchievement{ID: uint(s.nextID()), UserID: userID, Key: rule.Key, EarnedAt: time.Now()}
+			s.achievements[userID] = append(s.achievements[userID], achievement)
+
+			notification := services.AchievementNotification(userID, rule)
+			if err := s.createNotification(&notification); err != nil {
+				log.Printf("Failed to award %s to user %d: %v", rule.Key, userID, err)
+			}
+		}
+	}
+}
+
+// ListTeams returns a page of teams by name
+func (s *MemoryStore) ListTeams(page, limit int) ([]models.Team, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	teams := make([]models.Team, 0, len(s.teams))
+	for _, team := range s.teams {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if teams[i].Name != teams[j].Name {
+			return teams[i].Name < teams[j].Name
+		}
+		return teams[i].ID < teams[j].ID
+	})
+	return paginate(teams, page, limit), int64(len(teams)), nil
+}
+
+// GetTeam returns the team with the ID
+func (s *MemoryStore) GetTeam(id uint) (*models.Team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	team, ok := s.teams[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	return &team, nil
+}
+
+// ListTeamMembers returns a team's members in the order they joined
+func (s *MemoryStore) ListTeamMembers(teamID uint) ([]models.TeamMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := s.teamMembers(teamID)
+	for i := range members {
+		members[i].User = s.users[members[i].UserID]
+	}
+	return members, nil
+}
+
+// CreateTeam creates a team owned by its creator
+func (s *MemoryStore) CreateTeam(input *models.TeamRequest, creatorID uint) (*models.Team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, team := range s.teams {
+		if strings.EqualFold(team.Name, input.Name) {
+			return nil, services.ErrTeamNameTaken
+		}
+	}
+
+	now := time.Now()
+	team := models.Team{
+		ID:          uint(s.nextID()),
+		Name:        input.Name,
+		Description: input.Description,
+		CreatorID:   creatorID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	s.teams[team.ID] = team
+	s.members[pairKey{team.ID, creatorID}] = models.TeamMember{TeamID: team.ID, UserID: creatorID, Role: models.TeamOwner, JoinedAt: now}
+	return &team, nil
+}
+
+// TeamRoleOf returns the user's role in the team
+func (s *MemoryStore) TeamRoleOf(teamID, userID uint) (models.TeamRole, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.members[pairKey{teamID, userID}].Role, nil
+}
+
+// InviteToTeam invites a user to a team and notifies them, reopening any earlier invitation
+func (s *MemoryStore) InviteToTeam(team *models.Team, inviterID, userID uint) (*models.TeamInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pairKey{team.ID, userID}
+	if _, ok := s.members[key]; ok {
+		return nil, services.ErrAlreadyTeamMember
+	}
+
+	invite, ok := s.invites[key]
+	if !ok {
+		invite = models.TeamInvite{ID: uint(s.nextID()), TeamID: team.ID, UserID: userID}
+	}
+	invite.InviterID = inviterID
+	invite.Status = models.InvitePending
+	invite.CreatedAt = time.Now()
+	invite.RespondedAt = nil
+
+	notification := services.TeamInviteNotification(team, userID)
+	if err := s.createNotification(&notification); err != nil {
+		return nil, err
+	}
+	s.invites[key] = invite
+	return &invite, nil
+}
+
+// ListTeamInvites returns a user's pending invitations, newest first
+func (s *MemoryStore) ListTeamInvites(userID uint) ([]models.TeamInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invites := []models.TeamInvite{}
+	for _, invite := range s.invites {
+		if invite.UserID == userID && invite.Status == models.InvitePending {
+			invite.Team = s.teams[invite.TeamID]
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return !olderThan(invites[i].CreatedAt, invites[i].ID, invites[j].CreatedAt, invites[j].ID)
+	})
+	return invites, nil
+}
+
+// RespondToTeamInvite accepts or declines a pending invitation
+func (s *MemoryStore) RespondToTeamInvite(inviteID, userID uint, accept bool) (*models.TeamInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, invite := range s.invites {
+		if invite.ID != inviteID || invite.UserID != userID || invite.Status != models.InvitePending {
+			continue
+		}
+
+		now := time.Now()
+		invite.Status = models.InviteDeclined
+		if accept {
+			invite.Status = models.InviteAccepted
+			if _, ok := s.members[key]; !ok {
+				s.members[key] = models.TeamMember{TeamID: invite.TeamID, UserID: userID, Role: models.TeamMemberRole, JoinedAt: now}
+			}
+		}
+		invite.RespondedAt = &now
+		s.invites[key] = invite
+		return &invite, nil
+	}
+	return nil, utils.ErrNotFound
+}
+
+// RemoveTeamMember removes a member other than the owner
+func (s *MemoryStore) RemoveTeamMember(teamID, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pairKey{teamID, userID}
+	member, ok := s.members[key]
+	if !ok {
+		return utils.ErrNotFound
+	}
+	if member.Role == models.TeamOwner {
+		return services.ErrTeamOwnerCannotLeave
+	}
+	delete(s.members, key)
+	return nil
+}
+
+// SetTeamRole changes a member's role; making them owner demotes the current owner to admin
+func (s *MemoryStore) SetTeamRole(teamID, ownerID, userID uint, role models.TeamRole) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pairKey{teamID, userID}
+	member, ok := s.members[key]
+	if !ok {
+		return utils.ErrNotFound
+	}
+	if member.Role == models.TeamOwner {
+		return services.ErrTeamOwnerCannotLeave
+	}
+
+	if role == models.TeamOwner {
+		if owner, ok := s.members[pairKey{teamID, ownerID}]; ok {
+			owner.Role = models.TeamAdmin
+			s.members[pairKey{teamID, ownerID}] = owner
+		}
+	}
+	member.Role = role
+	s.members[key] = member
+	return nil
+}
+
+// GetTeamStats returns a team's score totals and each member's contribution
+func (s *MemoryStore) GetTeamStats(teamID uint) (models.TeamStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := s.teamMembers(teamID)
+	stats := models.TeamStats{Members: len(members), Contributions: []models.TeamContribution{}}
+	for _, member := range members {
+		records := s.teamScores(member)
+		if len(records) == 0 {
+			continue
+		}
+
+		contribution := models.TeamContribution{UserID: member.UserID, Username: s.users[member.UserID].Username}
+		for _, record := range records {
+			contribution.Score += record.Score
+			contribution.Resolved++
+			if record.Correct {
+				contribution.Correct++
+			}
+		}
+		stats.Contributions = append(stats.Contributions, contribution)
+		stats.Score += contribution.Score
+		stats.Resolved += contribution.Resolved
+		stats.Correct += contribution.Correct
+	}
+
+	sort.SliceStable(stats.Contributions, func(i, j int) bool {
+		return stats.Contributions[i].Score > stats.Contributions[j].Score
+	})
+	if stats.Resolved > 0 {
+		stats.Accuracy = float64(stats.Correct) / float64(stats.Resolved) * 100
+	}
+	return stats, nil
+}
+
+// GetTeamForecast averages the team members' active forecasts on a market
+func (s *MemoryStore) GetTeamForecast(teamID, marketID uint) (models.TeamForecast, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var forecasts []models.Forecast
+	for _, forecast := range s.marketForecasts(marketID) {
+		if _, ok := s.members[pairKey{teamID, forecast.UserID}]; ok {
+			forecasts = append(forecasts, forecast)
+		}
+	}
+	return models.TeamForecast{MarketID: marketID, Probability: meanProbability(forecasts), Count: len(forecasts)}, nil
+}
+
+// GetTeamLeaderboard returns a page of ranked teams
+func (s *MemoryStore) GetTeamLeaderboard(ranking models.TeamRanking, page, limit int) ([]models.TeamEntry, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []models.TeamEntry{}
+	for _, team := range s.teams {
+		members := s.teamMembers(team.ID)
+		entry := models.TeamEntry{TeamID: team.ID, Name: team.Name, Members: len(members)}
+		for _, member := range members {
+			for _, record := range s.teamScores(member) {
+				entry.Score += record.Score
+				entry.Resolved++
+				if record.Correct {
+					entry.Correct++
+				}
+			}
+		}
+		if entry.Resolved == 0 {
+			continue
+		}
+		entry.AverageScore = roundScore(entry.Score / float64(entry.Resolved))
+		entry.Score = roundScore(entry.Score)
+		entries = append(entries, entry)
+	}
+
+	key := func(entry models.TeamEntry) float64 {
+		if ranking == models.TeamRankAverage {
+			return entry.AverageScore
+		}
+		return entry.Score
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if key(entries[i]) != key(entries[j]) {
+			return key(entries[i]) > key(entries[j])
+		}
+		return entries[i].TeamID < entries[j].TeamID
+	})
+	for i := range entries {
+		if i > 0 && key(entries[i]) == key(entries[i-1]) {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return paginate(entries, page, limit), int64(len(entries)), nil
+}
+
+// ListTournaments returns a page of the tournaments with status at now, latest first
+func (s *MemoryStore) ListTournaments(status models.TournamentStatus, now time.Time, page, limit int) ([]models.Tournament, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tournaments := []models.Tournament{}
+	for _, tournament := range s.tournaments {
+		if status == "" || tournament.Status(now) == status {
+			tournaments = append(tournaments, tournament)
+		}
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return !olderThan(tournaments[i].StartDate, tournaments[i].ID, tournaments[j].StartDate, tournaments[j].ID)
+	})
+	return paginate(tournaments, page, limit), int64(len(tournaments)), nil
+}
+
+// GetTournament returns the tournament with its markets and prizes
+func (s *MemoryStore) GetTournament(id uint) (*models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tournament, ok := s.tournaments[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	tournament.Markets = make([]models.Market, 0, len(s.tournamentMarkets[id]))
+	for _, marketID := range s.tournamentMarkets[id] {
+		tournament.Markets = append(tournament.Markets, s.markets[marketID])
+	}
+	tournament.Prizes = append([]models.TournamentPrize(nil), tournament.Prizes...)
+	return &tournament, nil
+}
+
+// CountTournamentEntrants returns how many users joined a tournament
+func (s *MemoryStore) CountTournamentEntrants(tournamentID uint) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for key := range s.entrants {
+		if key.a == tournamentID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// HasJoinedTournament reports whether the user joined a tournament
+func (s *MemoryStore) HasJoinedTournament(tournamentID, userID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.entrants[pairKey{tournamentID, userID}]
+	return ok, nil
+}
+
+// ListTournamentAwards returns a tournament's awards by rank
+func (s *MemoryStore) ListTournamentAwards(tournamentID uint) ([]models.TournamentAward, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	awards := []models.TournamentAward{}
+	for _, award := range s.awards {
+		if award.TournamentID == tournamentID {
+			awards = append(awards, award)
+		}
+	}
+	sort.Slice(awards, func(i, j int) bool {
+		if awards[i].Rank != awards[j].Rank {
+			return awards[i].Rank < awards[j].Rank
+		}
+		return awards[i].UserID < awards[j].UserID
+	})
+	return awards, nil
+}
+
+// CreateTournament creates a tournament over existing markets
+func (s *MemoryStore) CreateTournament(input *models.TournamentRequest, creatorID uint) (*models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marketIDs := input.UniqueMarketIDs()
+	sort.Slice(marketIDs, func(i, j int) bool { return marketIDs[i] < marketIDs[j] })
+	markets := make([]models.Market, 0, len(marketIDs))
+	for _, id := range marketIDs {
+		market, ok := s.markets[id]
+		if !ok {
+			return nil, services.ErrUnknownTournamentMarkets
+		}
+		markets = append(markets, market)
+	}
+
+	rule := input.ScoringRule
+	if rule == "" {
+		rule = models.ScoringConfidence
+	}
+
+	now := time.Now()
+	tournament := models.Tournament{
+		ID:             uint(s.nextID()),
+		Name:           input.Name,
+		Description:    input.Description,
+		CreatorID:      creatorID,
+		StartDate:      input.StartDate,
+		EndDate:        input.EndDate,
+		RequiresSignup: input.RequiresSignup,
+		ScoringRule:    rule,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	for i, prize := range input.Prizes {
+		tournament.Prizes = append(tournament.Prizes, models.TournamentPrize{TournamentID: tournament.ID, Rank: i + 1, Description: prize})
+	}
+	s.tournaments[tournament.ID] = tournament
+	s.tournamentMarkets[tournament.ID] = marketIDs
+
+	tournament.Markets = markets
+	return &tournament, nil
+}
+
+// JoinTournament signs a user up for a tournament
+func (s *MemoryStore) JoinTournament(tournament *models.Tournament, userID uint, now time.Time) error {
+	if !tournament.RequiresSignup {
+		return services.ErrSignupNotRequired
+	}
+	if tournament.Status(now) == models.TournamentFinished {
+		return services.ErrTournamentFinished
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pairKey{tournament.ID, userID}
+	if _, ok := s.entrants[key]; !ok {
+		s.entrants[key] = models.TournamentEntrant{TournamentID: tournament.ID, UserID: userID, JoinedAt: now}
+	}
+	return nil
+}
+
+// TournamentStandings ranks a tournament's forecasters by the forecasts they made during it
+func (s *MemoryStore) TournamentStandings(tournament *models.Tournament) ([]models.LeaderboardEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inTournament := make(map[uint]bool)
+	for _, id := range s.tournamentMarkets[tournament.ID] {
+		inTournament[id] = true
+	}
+
+	// The last revision of each forecast made while the tournament ran
+	latest := make(map[uint]models.ForecastRevision)
+	for _, revision := range s.revisions {
+		if revision.CreatedAt.Before(tournament.StartDate) || revision.CreatedAt.After(tournament.EndDate) {
+			continue
+		}
+		if last, ok := latest[revision.ForecastID]; !ok || !olderThan(revision.CreatedAt, revision.ID, last.CreatedAt, last.ID) {
+			latest[revision.ForecastID] = revision
+		}
+	}
+
+	var forecasts []services.TournamentForecast
+	for _, revision := range latest {
+		market, forecast := s.markets[revision.MarketID], s.forecasts[revision.ForecastID]
+		if revision.Withdrawn || !inTournament[revision.MarketID] || !forecast.IsActive() {
+			continue
+		}
+		if market.Status != models.MarketResolved || market.Outcome == nil {
+			continue
+		}
+		if _, joined := s.entrants[pairKey{tournament.ID, revision.UserID}]; tournament.RequiresSignup && !joined {
+			continue
+		}
+		forecasts = append(forecasts, services.TournamentForecast{
+			UserID:     revision.UserID,
+			Prediction: revision.Prediction,
+			Confidence: revision.Confidence,
+			Outcome:    *market.Outcome,
+		})
+	}
+
+	entries := services.RankTournament(tournament.ScoringRule, forecasts)
+	for i := range entries {
+		entries[i].Username = s.users[entries[i].UserID].Username
+	}
+	return entries, nil
+}
+
+// ScheduleDailyChallenge makes an open market the challenge of a day
+func (s *MemoryStore) ScheduleDailyChallenge(input *models.DailyChallengeRequest, creatorID uint) (*models.DailyChallenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	market, ok := s.markets[input.MarketID]
+	if !ok || market.Status != models.MarketOpen {
+		return nil, services.ErrChallengeMarket
+	}
+	if _, ok := s.challenges[input.Day]; ok {
+		return nil, services.ErrChallengeScheduled
+	}
+
+	challenge := models.DailyChallenge{
+		ID:        uint(s.nextID()),
+		Day:       input.Day,
+		MarketID:  market.ID,
+		Note:      input.Note,
+		CreatorID: creatorID,
+		CreatedAt: time.Now(),
+	}
+	s.challenges[challenge.Day] = challenge
+
+	challenge.Market = market
+	return &challenge, nil
+}
+
+// DailyChallengeFor returns the challenge of a day, or nil
+func (s *MemoryStore) DailyChallengeFor(day string) (*models.DailyChallenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.challenges[day]
+	if !ok {
+		return nil, nil
+	}
+	challenge.Market = s.markets[challenge.MarketID]
+	return &challenge, nil
+}
+
+// ListWebhooks returns a user's webhooks, newest first
+func (s *MemoryStore) ListWebhooks(userID uint) ([]models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return !olderThan(webhooks[i].CreatedAt, webhooks[i].ID, webhooks[j].CreatedAt, webhooks[j].ID)
+	})
+	return webhooks, nil
+}
+
+// CreateWebhook stores a new webhook
+func (s *MemoryStore) CreateWebhook(webhook *models.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	webhook.ID = uint(s.nextID())
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = now
+	}
+	webhook.UpdatedAt = now
+	s.webhooks[webhook.ID] = *webhook
+	return nil
+}
+
+// GetWebhook returns the webhook with the ID
+func (s *MemoryStore) GetWebhook(id uint) (*models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return nil, utils.ErrNotFound
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook deletes a webhook with its delivery log
+func (s *MemoryStore) DeleteWebhook(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.WebhookID != id {
+			continue
+		}
+		for attemptID, attempt := range s.attempts {
+			if attempt.DeliveryID == deliveryID {
+				delete(s.attempts, attemptID)
+			}
+		}
+		delete(s.deliveries, deliveryID)
+	}
+	delete(s.webhooks, id)
+	return nil
+}
+
+// ListWebhookDeliveries returns a page of a webhook's deliveries, newest first
+func (s *MemoryStore) ListWebhookDeliveries(webhookID uint, status models.WebhookDeliveryStatus, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return !olderThan(deliveries[i].CreatedAt, deliveries[i].ID, deliveries[j].CreatedAt, deliveries[j].ID)
+	})
+	return paginate(deliveries, page, limit), int64(len(deliveries)), nil
+}
+
+// GetWebhookDelivery returns one of a webhook's deliveries
+func (s *MemoryStore) GetWebhookDelivery(webhookID, id uint) (*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok || delivery.WebhookID != webhookID {
+		return nil, utils.ErrNotFound
+	}
+	return &delivery, nil
+}
+
+// ListWebhookAttempts returns a delivery's attempts, oldest first
+func (s *MemoryStore) ListWebhookAttempts(deliveryID uint) ([]models.WebhookAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := []models.WebhookAttempt{}
+	for _, attempt := range s.attempts {
+		if attempt.DeliveryID == deliveryID {
+			attempts = append(attempts, attempt)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		return olderThan(attempts[i].CreatedAt, attempts[i].ID, attempts[j].CreatedAt, attempts[j].ID)
+	})
+	return attempts, nil
+}
+
+// ReplayWebhookDelivery queues a delivery to be sent again from its first attempt
+func (s *MemoryStore) ReplayWebhookDelivery(delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return utils.ErrNotFound
+	}
+	now := time.Now()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.Error = ""
+	delivery.NextAttemptAt = &now
+	delivery.UpdatedAt = now
+	s.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// DispatchWebhookEvent queues deliveries of an event to the webhooks subscribed to it
+func (s *MemoryStore) DispatchWebhookEvent(event models.WebhookEvent, ownerID uint, data interface{}) {
+	now := time.Now()
+	payload, err := services.EncodeWebhookPayload(event, data, now)
+	if err != nil {
+		log.Printf("Failed to encode %s webhook payload: %v", event, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, webhook := range s.webhooks {
+		if !webhook.Active || !(webhook.Global || webhook.UserID == ownerID) || !webhook.Events.Contains(event) {
+			continue
+		}
+
+		delivery := models.WebhookDelivery{
+			ID:            uint(s.nextID()),
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		s.deliveries[delivery.ID] = delivery
+	}
+}
+
+// createNotification stores a notification unless its type is off, pushes it to the user's
+// streams and prunes their oldest notifications beyond the retention limit. Callers hold the lock.
+func (s *MemoryStore) createNotification(notification *models.Notification) error {
+	if s.channelFor(notification.UserID, notification.Type) == models.ChannelOff {
+		return nil
+	}
+
+	notification.ID = s.nextID()
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	s.notifications[notification.ID] = *notification
+	services.PublishNotification(notification)
+
+	if keep := services.NotificationRetentionLimit; keep > 0 {
+		var mine []models.Notification
+		for _, n := range s.notifications {
+			if n.UserID == notification.UserID {
+				mine = append(mine, n)
+			}
+		}
+		if len(mine) > keep {
+			sortNewestFirst(mine)
+			for _, n := range mine[keep:] {
+				delete(s.notifications, n.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// channelFor returns the user's channel for a notification type. Callers hold the lock.
+func (s *MemoryStore) channelFor(userID int, t models.NotificationType) models.NotificationChannel {
+	if channel, ok := s.preferences[userID][t]; ok {
+		return channel
+	}
+	return models.DefaultNotificationChannel(t)
+}
+
+// setChannel stores a user's channel for a notification type. Callers hold the lock.
+func (s *MemoryStore) setChannel(userID int, t models.NotificationType, channel models.NotificationChannel) {
+	if s.preferences[userID] == nil {
+		s.preferences[userID] = map[models.NotificationType]models.NotificationChannel{}
+	}
+	s.preferences[userID][t] = channel
+}
+
+// setDigestFrequency changes how often a user is emailed a digest. Callers hold the lock.
+func (s *MemoryStore) setDigestFrequency(userID int, frequency models.DigestFrequency) {
+	if user, ok := s.users[uint(userID)]; ok {
+		user.DigestFrequency = frequency
+		s.users[user.ID] = user
+	}
+}
+
+// userByUsername returns the user with the username. Callers hold the lock.
+func (s *MemoryStore) userByUsername(username string) (models.User, bool) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+// userStats returns a user's stats, all zero if they have none yet. Callers hold the lock.
+func (s *MemoryStore) userStats(userID uint) models.UserStats {
+	if stats, ok := s.stats[userID]; ok {
+		return stats
+	}
+	return models.UserStats{UserID: userID}
+}
+
+// applyScoreRecord adds a resolved forecast's score to its forecaster's stats. Callers hold the lock.
+func (s *MemoryStore) applyScoreRecord(record models.ScoreRecord) {
+	stats := s.userStats(record.UserID)
+	stats.ResolvedPredictions++
+	stats.Score += record.Score
+	if record.Correct {
+		stats.CorrectPredictions++
+		stats.CurrentStreak++
+		if stats.CurrentStreak > stats.LongestStreak {
+			stats.LongestStreak = stats.CurrentStreak
+		}
+	} else {
+		stats.CurrentStreak = 0
+	}
+	stats.UpdatedAt = time.Now()
+	s.stats[record.UserID] = stats
+}
+
+// calibrationSamples returns a user's forecasts on resolved markets matching filter. Callers hold the lock.
+func (s *MemoryStore) calibrationSamples(userID uint, filter models.CalibrationFilter) []models.CalibrationSample {
+	samples := []models.CalibrationSample{}
+	for _, forecast := range s.forecasts {
+		market := s.markets[forecast.MarketID]
+		if forecast.UserID != userID || !forecast.IsActive() || market.Status != models.MarketResolved || market.Outcome == nil {
+			continue
+		}
+		if filter.Category != "" && market.Category != filter.Category {
+			continue
+		}
+		if (!filter.From.IsZero() && forecast.CreatedAt.Before(filter.From)) || (!filter.To.IsZero() && forecast.CreatedAt.After(filter.To)) {
+			continue
+		}
+		samples = append(samples, models.CalibrationSample{Probability: forecast.Probability(), Outcome: *market.Outcome})
+	}
+	return samples
+}
+
+// hasAchievement reports whether the user earned the achievement. Callers hold the lock.
+func (s *MemoryStore) hasAchievement(userID uint, key models.AchievementKey) bool {
+	for _, achievement := range s.achievements[userID] {
+		if achievement.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// userForecast returns the user's forecast on the market, withdrawn or not. Callers hold the lock.
+func (s *MemoryStore) userForecast(marketID, userID uint) (models.Forecast, bool) {
+	for _, forecast := range s.forecasts {
+		if forecast.MarketID == marketID && forecast.UserID == userID {
+			return forecast, true
+		}
+	}
+	return models.Forecast{}, false
+}
+
+// marketForecasts returns a market's active forecasts by ID. Callers hold the lock.
+func (s *MemoryStore) marketForecasts(marketID uint) []models.Forecast {
+	forecasts := []models.Forecast{}
+	for _, forecast := range s.forecasts {
+		if forecast.MarketID == marketID && forecast.IsActive() {
+			forecasts = append(forecasts, forecast)
+		}
+	}
+	sort.Slice(forecasts, func(i, j int) bool { return forecasts[i].ID < forecasts[j].ID })
+	return forecasts
+}
+
+// forecastsVisible reports whether the user may see others' forecasts on the market, as
+// services.ForecastsVisible does. Callers hold the lock.
+func (s *MemoryStore) forecastsVisible(market models.Market, userID uint, now time.Time) bool {
+	if !market.HidesForecasts(services.HideForecastsByDefault) || market.IsClosed(now) {
+		return true
+	}
+	forecast, predicted := s.userForecast(market.ID, userID)
+	return predicted && forecast.IsActive()
+}
+
+// storeComment stores a comment without its author. Callers hold the lock.
+func (s *MemoryStore) storeComment(comment models.Comment) {
+	comment.User = models.User{}
+	comment.Replies = nil
+	s.comments[comment.ID] = comment
+}
+
+// teamMembers returns a team's members in the order they joined. Callers hold the lock.
+func (s *MemoryStore) teamMembers(teamID uint) []models.TeamMember {
+	members := []models.TeamMember{}
+	for key, member := range s.members {
+		if key.a == teamID {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return olderThan(members[i].JoinedAt, members[i].UserID, members[j].JoinedAt, members[j].UserID)
+	})
+	return members
+}
+
+// teamScores returns the member's score records resolved while they were on the team. Callers hold the lock.
+func (s *MemoryStore) teamScores(member models.TeamMember) []models.ScoreRecord {
+	var records []models.ScoreRecord
+	for _, record := range s.scores {
+		if record.UserID == member.UserID && !record.ResolvedAt.Before(member.JoinedAt) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// matchingPredictions returns the predictions matching filter with their author and result
+// filled in. Callers hold the lock.
+func (s *MemoryStore) matchingPredictions(filter models.PredictionFilter) []models.Prediction {
+	now := time.Now()
+	matches := []models.Prediction{}
+	for _, prediction := range s.predictions {
+		s.decoratePrediction(&prediction, now)
+		if filter.UserID > 0 && prediction.UserID != filter.UserID {
+			continue
+		}
+		if filter.Category != "" && prediction.Category != filter.Category {
+			continue
+		}
+		if filter.Status != "" && prediction.Status != filter.Status {
+			continue
+		}
+		matches = append(matches, prediction)
+	}
+	return matches
+}
+
+// decoratePrediction fills in a prediction's author, outcome and status. Callers hold the lock.
+func (s *MemoryStore) decoratePrediction(prediction *models.Prediction, now time.Time) {
+	prediction.UserName = s.users[uint(prediction.UserID)].Username
+	if result, ok := s.results[prediction.ID]; ok {
+		outcome := result.Outcome
+		prediction.Outcome = &outcome
+	}
+	prediction.Status = prediction.StatusAt(now)
+}
+
+// applyUserVote fills in the viewer's active vote on a prediction. Callers hold the lock.
+func (s *MemoryStore) applyUserVote(prediction *models.Prediction, viewerID int) {
+	if viewerID <= 0 {
+		return
+	}
+	if vote, ok := s.votes[voteKey{viewerID, prediction.ID}]; ok && vote.RemovedAt == nil {
+		value := vote.Value
+		prediction.UserVote = &value
+	}
+}
+
+// adjustCounts changes the prediction's agree or disagree counter by delta
+func adjustCounts(prediction *models.Prediction, agree bool, delta int) {
+	if agree {
+		prediction.AgreeCount += delta
+	} else {
+		prediction.DisagreeCount += delta
+	}
+}
+
+// meanProbability returns the forecasts' mean probability that their market resolves true, or 0
+func meanProbability(forecasts []models.Forecast) float64 {
+	if len(forecasts) == 0 {
+		return 0
+	}
+	var sum float64
+	for i := range forecasts {
+		sum += forecasts[i].Probability()
+	}
+	return sum / float64(len(forecasts))
+}
+
+// roundScore rounds a total to six decimals, so floating point noise cannot split ties
+func roundScore(score float64) float64 {
+	return math.Round(score*1e6) / 1e6
+}
+
+// sortNewestFirst orders notifications newest first
+func sortNewestFirst(notifications []models.Notification) {
+	sort.Slice(notifications, func(i, j int) bool {
+		return !olderThan(notifications[i].CreatedAt, uint(notifications[i].ID), notifications[j].CreatedAt, uint(notifications[j].ID))
+	})
+}
+
+// olderThan reports whether the record at (createdAt, id) sorts after (otherAt, otherID), newest first
+func olderThan(createdAt time.Time, id uint, otherAt time.Time, otherID uint) bool {
+	if !createdAt.Equal(otherAt) {
+		return createdAt.Before(otherAt)
+	}
+	return id < otherID
+}
+
+// paginate returns the page of items, counting pages from 1
+func paginate[T any](items []T, page, limit int) []T {
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
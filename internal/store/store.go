@@ -0,0 +1,297 @@
+// Package store defines the storage the API handlers depend on, so they can run against
+// Postgres in production and against memory in tests. Interfaces only use models types;
+// how a backend computes its results is up to it.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+)
+
+// ErrUsernameTaken is returned when creating or renaming a user to a username already in use.
+// A taken email is reported as utils.ErrDuplicate.
+var ErrUsernameTaken = errors.New("username is already taken")
+
+// UserStore persists user accounts
+type UserStore interface {
+	// CreateUser stores a new user and sets its ID
+	CreateUser(user *models.User) error
+	// GetUser returns utils.ErrNotFound if no user has the ID
+	GetUser(id uint) (*models.User, error)
+	// GetUserByEmail returns utils.ErrNotFound if no user has the email
+	GetUserByEmail(email string) (*models.User, error)
+	// UpdateUser saves changes to a user's profile, returning ErrUsernameTaken if another
+	// user has its username
+	UpdateUser(user *models.User) error
+}
+
+// FollowStore persists who follows whom
+type FollowStore interface {
+	// FollowUser makes follower follow followee; following someone twice is a no-op
+	FollowUser(followerID, followeeID uint) error
+	// UnfollowUser returns utils.ErrNotFound if follower was not following followee
+	UnfollowUser(followerID, followeeID uint) error
+	GetFollowCounts(userID uint) (models.FollowCounts, error)
+	IsFollowing(followerID, followeeID uint) (bool, error)
+	// ListFollowers returns a page of the user's followers, most recent first, and their number
+	ListFollowers(userID uint, page, limit int) ([]models.User, int64, error)
+	// ListFollowing returns a page of the users the user follows, most recent first, and their number
+	ListFollowing(userID uint, page, limit int) ([]models.User, int64, error)
+}
+
+// ActivityStore persists the activity shown in followers' feeds
+type ActivityStore interface {
+	// RecordActivity adds an entry to the actor's followers' feeds. Feeds are derived data,
+	// so failures are logged rather than returned.
+	RecordActivity(activity models.Activity)
+	// GetFeed returns a page of activity by the users viewer follows, newest first, and the
+	// cursor of the next page. Forecasts still hidden from the viewer are left out.
+	GetFeed(viewerID uint, cursor string, limit int) ([]models.Activity, string, error)
+}
+
+// MarketFilter narrows a listing of markets
+type MarketFilter struct {
+	Status   models.MarketStatus
+	Category string
+	Trending bool // order by hot score instead of newest first
+}
+
+// MarketStore persists markets and their history
+type MarketStore interface {
+	// CreateMarket stores a new market and sets its ID
+	CreateMarket(market *models.Market) error
+	// GetMarket returns the market with its creator, or utils.ErrNotFound
+	GetMarket(id uint) (*models.Market, error)
+	// ListMarkets returns a page of markets matching filter with their creators, and the number of matches
+	ListMarkets(filter MarketFilter, page, limit int) ([]models.Market, int64, error)
+	// UpdateMarket saves changes to an existing market
+	UpdateMarket(market *models.Market) error
+	// ResolveMarket resolves the market with outcome at resolvedAt and scores its active
+	// forecasts, adding each score to its forecaster's total and stats, all or nothing.
+	// It returns the score records, or services.ErrMarketAlreadyResolved if the market was resolved first.
+	ResolveMarket(market *models.Market, outcome bool, resolvedAt time.Time) ([]models.ScoreRecord, error)
+	// BumpMarketTrending adds weight to the market's hot score; failures are logged
+	BumpMarketTrending(marketID uint, weight float64)
+	// RecordMarketSnapshot stores the market's current aggregate forecast and pushes it to its subscribers
+	RecordMarketSnapshot(marketID uint) (*models.MarketSnapshot, error)
+	// GetMarketTimeseries returns the market's snapshots in [from, to] downsampled to resolution;
+	// zero times leave the range open
+	GetMarketTimeseries(marketID uint, from, to time.Time, resolution time.Duration) ([]models.TimeseriesPoint, error)
+}
+
+// ForecastStore persists forecasts on markets
+type ForecastStore interface {
+	// GetForecast returns utils.ErrNotFound if no forecast has the ID
+	GetForecast(id uint) (*models.Forecast, error)
+	// GetUserForecast returns the user's forecast on the market, withdrawn or not, or utils.ErrNotFound
+	GetUserForecast(marketID, userID uint) (*models.Forecast, error)
+	// SaveForecast creates or updates a forecast, recording the change in its history and the
+	// forecaster's stats
+	SaveForecast(forecast *models.Forecast) error
+	// ListMarketForecasts returns a page of the market's active forecasts with their users,
+	// newest first, and their number
+	ListMarketForecasts(marketID uint, page, limit int) ([]models.Forecast, int64, error)
+	// ForecastsVisible reports whether the user may see other people's forecasts on the market
+	ForecastsVisible(market *models.Market, userID uint) (bool, error)
+	// RecentForecasts returns the user's latest active forecasts with their markets, leaving out
+	// those still hidden from viewer
+	RecentForecasts(userID, viewerID uint, limit int) ([]models.Forecast, error)
+}
+
+// PredictionStore persists social prediction posts
+type PredictionStore interface {
+	// CreatePrediction stores a new prediction and sets its ID
+	CreatePrediction(prediction *models.Prediction) error
+	// GetPredictions returns a page of predictions, newest first, with userID's votes filled in
+	GetPredictions(userID int, page int, limit int) ([]models.Prediction, error)
+	// FindPredictions returns a page of the predictions matching filter, with viewerID's votes filled in
+	FindPredictions(filter models.PredictionFilter, viewerID, page, limit int) ([]models.Prediction, error)
+	// CountPredictions returns how many predictions match filter
+	CountPredictions(filter models.PredictionFilter) (int64, error)
+	// GetPrediction returns the prediction with its result and viewerID's vote, or utils.ErrNotFound
+	GetPrediction(id, viewerID int) (*models.Prediction, error)
+	// UpdatePrediction saves changes to an existing prediction
+	UpdatePrediction(prediction *models.Prediction) error
+	// DeletePrediction deletes a prediction with its votes and result
+	DeletePrediction(id int) error
+	// ResolvePrediction stores the prediction's result and notifies the users who voted on it
+	ResolvePrediction(prediction *models.Prediction, result *models.Result) error
+}
+
+// VoteStore persists votes on predictions
+type VoteStore interface {
+	// VotePrediction records the vote, replacing the user's earlier vote on the prediction,
+	// and keeps the prediction's vote counts in step. It returns utils.ErrNotFound if the
+	// prediction does not exist.
+	VotePrediction(vote *models.Vote) error
+	// RemoveVote withdraws the user's vote and returns the prediction with its updated counts,
+	// or utils.ErrNotFound if the user has no vote on it
+	RemoveVote(userID, predictionID int) (*models.Prediction, error)
+}
+
+// CommentStore persists market discussions
+type CommentStore interface {
+	// ListMarketComments returns a page of the market's threads, newest first, with their
+	// replies oldest first and every author filled in, and the number of threads
+	ListMarketComments(marketID uint, page, limit int) ([]models.Comment, int64, error)
+	// GetComment returns the comment with its author, or utils.ErrNotFound
+	GetComment(id uint) (*models.Comment, error)
+	// CreateComment stores a new comment and sets its ID
+	CreateComment(comment *models.Comment) error
+	// UpdateComment saves changes to an existing comment
+	UpdateComment(comment *models.Comment) error
+	// NotifyMentions notifies the users mentioned in the comment, skipping the author and the
+	// usernames in alreadyNotified
+	NotifyMentions(author models.User, market models.Market, comment models.Comment, alreadyNotified []string) error
+}
+
+// NotificationStore persists users' notifications and how they want to receive them
+type NotificationStore interface {
+	// CreateNotification stores a notification unless the user has turned its type off
+	CreateNotification(notification *models.Notification) error
+	// ListNotifications returns a page of notifications, newest first, and the cursor of the next page
+	ListNotifications(userID int, unreadOnly bool, cursor string, limit int) ([]models.Notification, string, error)
+	CountUnreadNotifications(userID int) (int64, error)
+	// MarkNotificationRead returns utils.ErrNotFound unless the notification belongs to the user
+	MarkNotificationRead(userID, id int) error
+	// MarkAllNotificationsRead returns how many notifications changed
+	MarkAllNotificationsRead(userID int) (int64, error)
+	// DeleteNotification returns utils.ErrNotFound unless the notification belongs to the user
+	DeleteNotification(userID, id int) error
+	// NotificationPreferences returns the user's channel for every notification type
+	NotificationPreferences(userID int) (map[models.NotificationType]models.NotificationChannel, error)
+	// UpdateNotificationPreferences saves the channels and digest frequency in input
+	UpdateNotificationPreferences(userID int, input models.NotificationPreferencesRequest) error
+	// Unsubscribe turns off the emails an unsubscribe link's scope names
+	Unsubscribe(userID int, scope string) error
+}
+
+// StatsStore computes users' track records
+type StatsStore interface {
+	// GetUserStats returns the user's stats, all zero if they have none yet
+	GetUserStats(userID uint) (models.UserStats, error)
+	// LoadCalibrationSamples returns the user's forecasts on resolved markets matching filter
+	LoadCalibrationSamples(userID uint, filter models.CalibrationFilter) ([]models.CalibrationSample, error)
+	// CompareForecasters compares two users over the markets they both have active forecasts
+	// on, leaving out markets whose forecasts are still hidden from viewer
+	CompareForecasters(userID, otherID, viewerID uint) (models.HeadToHead, error)
+	// GetLeaderboard returns a page of the leaderboard built from filter, the number of ranked
+	// users and viewer's entry, which is nil when they are not ranked
+	GetLeaderboard(filter models.LeaderboardFilter, page, limit int, viewerID uint) ([]models.LeaderboardEntry, int64, *models.LeaderboardEntry, error)
+}
+
+// AchievementStore persists the achievements users have earned
+type AchievementStore interface {
+	// UserAchievements returns the user's achievements, earliest first
+	UserAchievements(userID uint) ([]models.EarnedAchievement, error)
+	// CheckAchievements awards the users any achievements the event earned them and notifies
+	// them. Achievements are a side effect, so failures are logged rather than returned.
+	CheckAchievements(event models.AchievementEvent, userIDs ...uint)
+}
+
+// TeamStore persists teams, their members and invitations
+type TeamStore interface {
+	// ListTeams returns a page of teams by name and their number
+	ListTeams(page, limit int) ([]models.Team, int64, error)
+	// GetTeam returns utils.ErrNotFound if no team has the ID
+	GetTeam(id uint) (*models.Team, error)
+	// ListTeamMembers returns the team's members with their users, in the order they joined
+	ListTeamMembers(teamID uint) ([]models.TeamMember, error)
+	// CreateTeam creates a team owned by its creator
+	CreateTeam(input *models.TeamRequest, creatorID uint) (*models.Team, error)
+	// TeamRoleOf returns the user's role in the team, or "" if they are not a member
+	TeamRoleOf(teamID, userID uint) (models.TeamRole, error)
+	// InviteToTeam invites the user to the team and notifies them
+	InviteToTeam(team *models.Team, inviterID, userID uint) (*models.TeamInvite, error)
+	// ListTeamInvites returns the user's pending invitations with their teams, newest first
+	ListTeamInvites(userID uint) ([]models.TeamInvite, error)
+	// RespondToTeamInvite accepts or declines one of the user's pending invitations
+	RespondToTeamInvite(inviteID, userID uint, accept bool) (*models.TeamInvite, error)
+	// RemoveTeamMember removes a member other than the owner
+	RemoveTeamMember(teamID, userID uint) error
+	// SetTeamRole changes a member's role; making them owner demotes the current owner to admin
+	SetTeamRole(teamID, ownerID, userID uint, role models.TeamRole) error
+	GetTeamStats(teamID uint) (models.TeamStats, error)
+	// GetTeamForecast averages the team members' active forecasts on the market
+	GetTeamForecast(teamID, marketID uint) (models.TeamForecast, error)
+	// GetTeamLeaderboard returns a page of teams ranked by their members' scores and their number
+	GetTeamLeaderboard(ranking models.TeamRanking, page, limit int) ([]models.TeamEntry, int64, error)
+}
+
+// TournamentStore persists tournaments and their entrants
+type TournamentStore interface {
+	// ListTournaments returns a page of the tournaments with status at now, latest first, and
+	// their number; an empty status matches every tournament
+	ListTournaments(status models.TournamentStatus, now time.Time, page, limit int) ([]models.Tournament, int64, error)
+	// GetTournament returns the tournament with its markets and prizes, or utils.ErrNotFound
+	GetTournament(id uint) (*models.Tournament, error)
+	CountTournamentEntrants(tournamentID uint) (int64, error)
+	HasJoinedTournament(tournamentID, userID uint) (bool, error)
+	// ListTournamentAwards returns the tournament's awards by rank
+	ListTournamentAwards(tournamentID uint) ([]models.TournamentAward, error)
+	// CreateTournament creates a tournament over existing markets
+	CreateTournament(input *models.TournamentRequest, creatorID uint) (*models.Tournament, error)
+	// JoinTournament signs the user up for a tournament that requires it
+	JoinTournament(tournament *models.Tournament, userID uint, now time.Time) error
+	// TournamentStandings ranks the tournament's forecasters by the forecasts they made during it
+	TournamentStandings(tournament *models.Tournament) ([]models.LeaderboardEntry, error)
+}
+
+// ChallengeStore persists the daily challenges
+type ChallengeStore interface {
+	// ScheduleDailyChallenge makes an open market the challenge of a day
+	ScheduleDailyChallenge(input *models.DailyChallengeRequest, creatorID uint) (*models.DailyChallenge, error)
+	// DailyChallengeFor returns the challenge of a YYYY-MM-DD day, or nil if there is none
+	DailyChallengeFor(day string) (*models.DailyChallenge, error)
+}
+
+// WebhookStore persists webhooks and their deliveries
+type WebhookStore interface {
+	// ListWebhooks returns the user's webhooks, newest first
+	ListWebhooks(userID uint) ([]models.Webhook, error)
+	// CreateWebhook stores a new webhook and sets its ID
+	CreateWebhook(webhook *models.Webhook) error
+	// GetWebhook returns utils.ErrNotFound if no webhook has the ID
+	GetWebhook(id uint) (*models.Webhook, error)
+	// DeleteWebhook deletes a webhook with its deliveries
+	DeleteWebhook(id uint) error
+	// ListWebhookDeliveries returns a page of the webhook's deliveries, newest first, and their
+	// number; an empty status matches every delivery
+	ListWebhookDeliveries(webhookID uint, status models.WebhookDeliveryStatus, page, limit int) ([]models.WebhookDelivery, int64, error)
+	// GetWebhookDelivery returns utils.ErrNotFound unless the delivery belongs to the webhook
+	GetWebhookDelivery(webhookID, id uint) (*models.WebhookDelivery, error)
+	// ListWebhookAttempts returns the delivery's attempts, oldest first
+	ListWebhookAttempts(deliveryID uint) ([]models.WebhookAttempt, error)
+	// ReplayWebhookDelivery queues the delivery to be sent again
+	ReplayWebhookDelivery(delivery *models.WebhookDelivery) error
+	// DispatchWebhookEvent queues a delivery of the event to every active webhook subscribed to
+	// it that is global or belongs to ownerID. Webhooks are a side effect, so failures are
+	// logged rather than returned.
+	DispatchWebhookEvent(event models.WebhookEvent, ownerID uint, data interface{})
+}
+
+// Store is a complete storage backend
+type Store interface {
+	UserStore
+	FollowStore
+	ActivityStore
+	MarketStore
+	ForecastStore
+	PredictionStore
+	VoteStore
+	CommentStore
+	NotificationStore
+	StatsStore
+	AchievementStore
+	TeamStore
+	TournamentStore
+	ChallengeStore
+	WebhookStore
+}
+
+var (
+	_ Store = (*GormStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
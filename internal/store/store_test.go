@@ -0,0 +1,292 @@
+// store_test.go
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/domolitom/reThink/internal/models"
+	"github.com/domolitom/reThink/internal/services"
+	"github.com/domolitom/reThink/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreUsers(t *testing.T) {
+	s := NewMemoryStore()
+
+	user := &models.User{Name: "Ada", Username: "ada", Email: "ada@example.com"}
+	assert.NoError(t, s.CreateUser(user))
+	assert.NotZero(t, user.ID)
+
+	found, err := s.GetUserByEmail("ada@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, err = s.GetUserByEmail("bob@example.com")
+	assert.ErrorIs(t, err, utils.ErrNotFound)
+
+	assert.ErrorIs(t, s.CreateUser(&models.User{Username: "ada2", Email: "ada@example.com"}), utils.ErrDuplicate)
+	assert.ErrorIs(t, s.CreateUser(&models.User{Username: "ada", Email: "other@example.com"}), ErrUsernameTaken)
+}
+
+func TestMemoryStorePredictionsAndVotes(t *testing.T) {
+	s := NewMemoryStore()
+	author := &models.User{Username: "author", Email: "author@example.com"}
+	assert.NoError(t, s.CreateUser(author))
+
+	now := time.Now()
+	older := &models.Prediction{UserID: int(author.ID), Title: "Older", Category: "Finance", EndDate: now.Add(-time.Hour), CreatedAt: now.Add(-2 * time.Hour)}
+	newer := &models.Prediction{UserID: int(author.ID), Title: "Newer", Category: "Tech", EndDate: now.Add(time.Hour), CreatedAt: now}
+	assert.NoError(t, s.CreatePrediction(older))
+	assert.NoError(t, s.CreatePrediction(newer))
+
+	// Agreeing and then disagreeing moves the vote between the counters
+	vote := &models.Vote{UserID: 7, PredictionID: newer.ID, Value: true}
+	assert.NoError(t, s.VotePrediction(vote))
+	assert.NoError(t, s.VotePrediction(&models.Vote{UserID: 7, PredictionID: newer.ID, Value: false}))
+	assert.ErrorIs(t, s.VotePrediction(&models.Vote{UserID: 7, PredictionID: 999, Value: true}), utils.ErrNotFound)
+
+	predictions, err := s.GetPredictions(7, 1, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, predictions, 2) {
+		assert.Equal(t, "Newer", predictions[0].Title)
+		assert.Equal(t, 0, predictions[0].AgreeCount)
+		assert.Equal(t, 1, predictions[0].DisagreeCount)
+		assert.Equal(t, "author", predictions[0].UserName)
+		if assert.NotNil(t, predictions[0].UserVote) {
+			assert.False(t, *predictions[0].UserVote)
+		}
+		assert.Nil(t, predictions[1].UserVote)
+	}
+
+	ended, err := s.FindPredictions(models.PredictionFilter{Status: models.PredictionEnded}, 0, 1, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, ended, 1) {
+		assert.Equal(t, "Older", ended[0].Title)
+	}
+	total, err := s.CountPredictions(models.PredictionFilter{Status: models.PredictionEnded})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	page, err := s.FindPredictions(models.PredictionFilter{}, 0, 2, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, page, 1) {
+		assert.Equal(t, "Older", page[0].Title)
+	}
+
+	// Removing a vote and voting again restores it without notifying the author twice
+	removed, err := s.RemoveVote(7, newer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed.DisagreeCount)
+	_, err = s.RemoveVote(7, newer.ID)
+	assert.ErrorIs(t, err, utils.ErrNotFound)
+	assert.NoError(t, s.VotePrediction(&models.Vote{UserID: 7, PredictionID: newer.ID, Value: true}))
+
+	found, err := s.GetPrediction(newer.ID, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, found.AgreeCount)
+	unread, err := s.CountUnreadNotifications(int(author.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), unread)
+
+	// Resolving a prediction moves it to the resolved status
+	assert.NoError(t, s.ResolvePrediction(found, &models.Result{Outcome: true, EvidenceURL: "https://example.com"}))
+	resolved, err := s.CountPredictions(models.PredictionFilter{Status: models.PredictionResolved})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), resolved)
+}
+
+func TestMemoryStoreMarkets(t *testing.T) {
+	s := NewMemoryStore()
+	creator := &models.User{Username: "creator", Email: "creator@example.com"}
+	assert.NoError(t, s.CreateUser(creator))
+
+	market := &models.Market{Title: "Will it rain?", Category: "Weather", CreatorID: creator.ID, HotScore: 1}
+	assert.NoError(t, s.CreateMarket(market))
+	assert.NoError(t, s.CreateMarket(&models.Market{Title: "Will it snow?", Category: "Weather", CreatorID: creator.ID, HotScore: 5}))
+	assert.NoError(t, s.CreateMarket(&models.Market{Title: "Who wins?", Category: "Sports", CreatorID: creator.ID}))
+
+	found, err := s.GetMarket(market.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MarketOpen, found.Status)
+	assert.Equal(t, "creator", found.Creator.Username)
+
+	_, err = s.GetMarket(999)
+	assert.ErrorIs(t, err, utils.ErrNotFound)
+
+	markets, total, err := s.ListMarkets(MarketFilter{Category: "Weather", Trending: true}, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	if assert.Len(t, markets, 2) {
+		assert.Equal(t, "Will it snow?", markets[0].Title)
+	}
+
+	found.Title = "Will it rain tomorrow?"
+	assert.NoError(t, s.UpdateMarket(found))
+	found, _ = s.GetMarket(market.ID)
+	assert.Equal(t, "Will it rain tomorrow?", found.Title)
+	assert.ErrorIs(t, s.UpdateMarket(&models.Market{ID: 999}), utils.ErrNotFound)
+}
+
+func TestMemoryStoreForecastsAndResolution(t *testing.T) {
+	s := NewMemoryStore()
+	creator := &models.User{Username: "creator", Email: "creator@example.com"}
+	right := &models.User{Username: "right", Email: "right@example.com"}
+	wrong := &models.User{Username: "wrong", Email: "wrong@example.com"}
+	for _, user := range []*models.User{creator, right, wrong} {
+		assert.NoError(t, s.CreateUser(user))
+	}
+
+	market := &models.Market{Title: "Will it rain?", CreatorID: creator.ID}
+	assert.NoError(t, s.CreateMarket(market))
+	assert.NoError(t, s.SaveForecast(&models.Forecast{MarketID: market.ID, UserID: right.ID, Prediction: true, Confidence: 80}))
+	withdrawn := &models.Forecast{MarketID: market.ID, UserID: wrong.ID, Prediction: false, Confidence: 60}
+	assert.NoError(t, s.SaveForecast(withdrawn))
+
+	stats, err := s.GetUserStats(right.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalPredictions)
+	assert.Equal(t, 1, stats.DayStreak)
+
+	// Withdrawn forecasts are neither listed nor scored
+	now := time.Now()
+	withdrawn.WithdrawnAt = &now
+	assert.NoError(t, s.SaveForecast(withdrawn))
+	forecasts, total, err := s.ListMarketForecasts(market.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	if assert.Len(t, forecasts, 1) {
+		assert.Equal(t, "right", forecasts[0].User.Username)
+	}
+	found, err := s.GetUserForecast(market.ID, wrong.ID)
+	assert.NoError(t, err)
+	assert.False(t, found.IsActive())
+
+	// Withdrawing a forecast on a market that hides forecasts hides the crowd again
+	hide := true
+	hidden := &models.Market{Title: "Will it snow?", CreatorID: creator.ID, HideForecasts: &hide, CloseDate: now.AddDate(0, 0, 1)}
+	assert.NoError(t, s.CreateMarket(hidden))
+	own := &models.Forecast{MarketID: hidden.ID, UserID: wrong.ID, Prediction: true, Confidence: 50}
+	assert.NoError(t, s.SaveForecast(own))
+	visible, err := s.ForecastsVisible(hidden, wrong.ID)
+	assert.NoError(t, err)
+	assert.True(t, visible)
+	own.WithdrawnAt = &now
+	assert.NoError(t, s.SaveForecast(own))
+	visible, err = s.ForecastsVisible(hidden, wrong.ID)
+	assert.NoError(t, err)
+	assert.False(t, visible)
+
+	records, err := s.ResolveMarket(market, true, now)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, right.ID, records[0].UserID)
+		assert.InDelta(t, 1.6, records[0].Score, 1e-9)
+	}
+
+	// A second resolution, racing the first with a stale copy of the market, changes nothing
+	stale := *market
+	stale.Status = models.MarketOpen
+	_, err = s.ResolveMarket(&stale, false, now)
+	assert.ErrorIs(t, err, services.ErrMarketAlreadyResolved)
+
+	user, err := s.GetUser(right.ID)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.6, user.PredictionScore, 1e-9)
+	stats, _ = s.GetUserStats(right.ID)
+	assert.Equal(t, 1, stats.CorrectPredictions)
+
+	entries, total, mine, err := s.GetLeaderboard(models.LeaderboardFilter{}, 1, 10, right.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	if assert.Len(t, entries, 1) && assert.NotNil(t, mine) {
+		assert.Equal(t, "right", entries[0].Username)
+		assert.Equal(t, 1, mine.Rank)
+	}
+}
+
+func TestMemoryStoreTeams(t *testing.T) {
+	s := NewMemoryStore()
+	owner := &models.User{Username: "owner", Email: "owner@example.com"}
+	member := &models.User{Username: "member", Email: "member@example.com"}
+	assert.NoError(t, s.CreateUser(owner))
+	assert.NoError(t, s.CreateUser(member))
+
+	team, err := s.CreateTeam(&models.TeamRequest{Name: "Oracles"}, owner.ID)
+	assert.NoError(t, err)
+	_, err = s.CreateTeam(&models.TeamRequest{Name: "oracles"}, member.ID)
+	assert.ErrorIs(t, err, services.ErrTeamNameTaken)
+
+	invite, err := s.InviteToTeam(team, owner.ID, member.ID)
+	assert.NoError(t, err)
+	invites, err := s.ListTeamInvites(member.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, invites, 1) {
+		assert.Equal(t, "Oracles", invites[0].Team.Name)
+	}
+
+	_, err = s.RespondToTeamInvite(invite.ID, owner.ID, true)
+	assert.ErrorIs(t, err, utils.ErrNotFound)
+	_, err = s.RespondToTeamInvite(invite.ID, member.ID, true)
+	assert.NoError(t, err)
+	_, err = s.InviteToTeam(team, owner.ID, member.ID)
+	assert.ErrorIs(t, err, services.ErrAlreadyTeamMember)
+
+	// Handing over ownership demotes the owner, who can then be removed
+	assert.NoError(t, s.SetTeamRole(team.ID, owner.ID, member.ID, models.TeamOwner))
+	role, err := s.TeamRoleOf(team.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TeamAdmin, role)
+	assert.ErrorIs(t, s.RemoveTeamMember(team.ID, member.ID), services.ErrTeamOwnerCannotLeave)
+	assert.NoError(t, s.RemoveTeamMember(team.ID, owner.ID))
+
+	members, err := s.ListTeamMembers(team.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "member", members[0].User.Username)
+	}
+}
+
+func TestMemoryStoreNotifications(t *testing.T) {
+	s := NewMemoryStore()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.CreateNotification(&models.Notification{UserID: 1, Type: models.NotificationVote, CreatedAt: start.Add(time.Duration(i) * time.Minute)}))
+	}
+	other := &models.Notification{UserID: 2, Type: models.NotificationVote}
+	assert.NoError(t, s.CreateNotification(other))
+
+	// Pages follow the cursor, newest first
+	first, next, err := s.ListNotifications(1, false, "", 2)
+	assert.NoError(t, err)
+	assert.Len(t, first, 2)
+	assert.NotEmpty(t, next)
+	assert.True(t, first[0].CreatedAt.After(first[1].CreatedAt))
+
+	rest, next, err := s.ListNotifications(1, false, next, 2)
+	assert.NoError(t, err)
+	assert.Len(t, rest, 1)
+	assert.Empty(t, next)
+
+	_, _, err = s.ListNotifications(1, false, "not a cursor", 2)
+	assert.ErrorIs(t, err, utils.ErrInvalidCursor)
+
+	// Users can only touch their own notifications
+	assert.ErrorIs(t, s.MarkNotificationRead(1, other.ID), utils.ErrNotFound)
+	assert.ErrorIs(t, s.DeleteNotification(1, other.ID), utils.ErrNotFound)
+
+	assert.NoError(t, s.MarkNotificationRead(1, first[0].ID))
+	unread, err := s.CountUnreadNotifications(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), unread)
+
+	updated, err := s.MarkAllNotificationsRead(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+
+	assert.NoError(t, s.DeleteNotification(1, first[0].ID))
+	remaining, _, err := s.ListNotifications(1, false, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 2)
+}
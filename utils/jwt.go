@@ -56,27 +56,74 @@ func GenerateToken(userID int) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// VerifyToken validates a JWT token and returns the user ID
+// VerifyToken validates a JWT token and returns the user ID. Stream tickets are rejected.
 func VerifyToken(tokenString string) (int, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	if len(claims.Audience) > 0 {
+		return 0, ErrInvalidToken
+	}
+	return claims.UserID, nil
+}
+
+// streamTicketAudience marks tokens that can only open the event stream
+const streamTicketAudience = "stream"
+
+// StreamTicketTTL is how long a stream ticket can be used to open a stream
+const StreamTicketTTL = time.Minute
+
+// GenerateStreamTicket creates a short-lived token that only authenticates opening the event stream.
+// It goes in the URL of an EventSource, which cannot send headers, so a ticket that leaks into a
+// log expires within a minute and cannot be used on the rest of the API.
+func GenerateStreamTicket(userID int) (string, time.Time, error) {
+	expirationTime := time.Now().Add(StreamTicketTTL)
+	claims := &JWTClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{streamTicketAudience},
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(jwtSecret)
+	return signed, expirationTime, err
+}
+
+// VerifyStreamTicket validates a stream ticket and returns the user ID. Session tokens are rejected.
+func VerifyStreamTicket(ticket string) (int, error) {
+	claims, err := parseClaims(ticket, jwt.WithAudience(streamTicketAudience))
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// parseClaims validates a signed token and returns its claims
+func parseClaims(tokenString string, options ...jwt.ParserOption) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&JWTClaims{},
 		func(token *jwt.Token) (interface{}, error) {
 			return jwtSecret, nil
 		},
+		options...,
 	)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return 0, ErrExpiredToken
+			return nil, ErrExpiredToken
 		}
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(*JWTClaims)
 	if !ok || !token.Valid {
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
-	return claims.UserID, nil
+	return claims, nil
 }
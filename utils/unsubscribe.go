@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// unsubscribeKey derives a separate key from the JWT secret so unsubscribe tokens cannot be used as logins
+var unsubscribeKey = func() []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("unsubscribe"))
+	return mac.Sum(nil)
+}()
+
+// SignUnsubscribeToken creates a token for a one-click unsubscribe link.
+// The scope names what to unsubscribe from, such as a notification type.
+// Tokens do not expire, so links in old emails keep working.
+func SignUnsubscribeToken(userID int, scope string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", userID, scope)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(unsubscribeSignature(payload))
+}
+
+// VerifyUnsubscribeToken checks a token made by SignUnsubscribeToken and returns its user ID and scope
+func VerifyUnsubscribeToken(token string) (int, string, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return 0, "", ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, unsubscribeSignature(payload)) {
+		return 0, "", ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return 0, "", ErrInvalidToken
+	}
+	id, scope, found := strings.Cut(string(raw), ":")
+	if !found {
+		return 0, "", ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, "", ErrInvalidToken
+	}
+
+	return userID, scope, nil
+}
+
+func unsubscribeSignature(payload string) []byte {
+	mac := hmac.New(sha256.New, unsubscribeKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
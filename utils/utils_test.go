@@ -2,7 +2,9 @@
 package utils
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -53,6 +55,25 @@ func TestJWTTokenGeneration(t *testing.T) {
 	// assert.Contains(t, err.Error(), "expired")
 }
 
+func TestStreamTicket(t *testing.T) {
+	ticket, expiresAt, err := GenerateStreamTicket(123)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(StreamTicketTTL), expiresAt, time.Second)
+
+	userID, err := VerifyStreamTicket(ticket)
+	assert.NoError(t, err)
+	assert.Equal(t, 123, userID)
+
+	// Tickets and session tokens are not interchangeable
+	_, err = VerifyToken(ticket)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	token, err := GenerateToken(123)
+	assert.NoError(t, err)
+	_, err = VerifyStreamTicket(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
 func TestValidateEmail(t *testing.T) {
 	tests := []struct {
 		email    string
@@ -159,3 +180,35 @@ func TestParsePaginationParams(t *testing.T) {
 		})
 	}
 }
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2025, 3, 14, 15, 9, 26, 535897932, time.UTC)
+
+	cursor := EncodeCursor(created, 42)
+	decodedTime, decodedID, err := DecodeCursor(cursor)
+	assert.NoError(t, err)
+	assert.True(t, created.Equal(decodedTime))
+	assert.Equal(t, uint(42), decodedID)
+
+	for _, invalid := range []string{"", "not base64!", EncodeCursor(created, 1)[:4]} {
+		_, _, err := DecodeCursor(invalid)
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	token := SignUnsubscribeToken(7, "mention")
+
+	userID, scope, err := VerifyUnsubscribeToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+	assert.Equal(t, "mention", scope)
+
+	// Changing the payload invalidates the signature
+	other := SignUnsubscribeToken(8, "mention")
+	forged := other[:strings.Index(other, ".")] + token[strings.Index(token, "."):]
+	for _, invalid := range []string{"", "no-signature", forged, token + "x"} {
+		_, _, err := VerifyUnsubscribeToken(invalid)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+}
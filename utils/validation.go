@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"encoding/base64"
 	"errors"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Common errors
@@ -46,3 +49,35 @@ func ParsePaginationParams(pageStr, limitStr string) (int, int) {
 
 	return page, limit
 }
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor builds an opaque keyset pagination cursor from a row's timestamp and ID
+func EncodeCursor(t time.Time, id uint) string {
+	raw := strconv.FormatInt(t.UnixNano(), 10) + ":" + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor
+func DecodeCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	i, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	return time.Unix(0, n), uint(i), nil
+}